@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"policy-match/internal/config"
+	"policy-match/internal/repository"
+	"policy-match/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const apiKeyUsage = "usage: policy-match apikey [create <name> [request_quota] [token_quota] [workspace_id] | list | revoke <id>]"
+
+// runAPIKey implements the apikey subcommand, which manages service keys
+// without going through the API, e.g. to create the first one. Keys
+// created here are admin keys.
+func runAPIKey(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", apiKeyUsage)
+	}
+
+	repo, err := repository.NewRepository(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		if len(args) < 2 {
+			return fmt.Errorf("missing key name\n%s", apiKeyUsage)
+		}
+		var requestQuota int
+		var tokenQuota int64
+		if len(args) > 2 {
+			requestQuota, err = strconv.Atoi(args[2])
+			if err != nil || requestQuota < 0 {
+				return fmt.Errorf("invalid request quota %q\n%s", args[2], apiKeyUsage)
+			}
+		}
+		if len(args) > 3 {
+			tokenQuota, err = strconv.ParseInt(args[3], 10, 64)
+			if err != nil || tokenQuota < 0 {
+				return fmt.Errorf("invalid token quota %q\n%s", args[3], apiKeyUsage)
+			}
+		}
+
+		workspaceID := repository.DefaultWorkspaceID
+		if len(args) > 4 {
+			workspaceID, err = uuid.Parse(args[4])
+			if err != nil {
+				return fmt.Errorf("invalid workspace id %q\n%s", args[4], apiKeyUsage)
+			}
+		}
+		if _, err := repo.GetWorkspaceByID(ctx, workspaceID); err != nil {
+			return fmt.Errorf("workspace %s: %w", workspaceID, err)
+		}
+
+		key, plaintext, err := service.NewAPIKey(args[1], repository.RoleAdmin, requestQuota, tokenQuota)
+		if err != nil {
+			return err
+		}
+		if err := repo.CreateAPIKey(repository.WithWorkspace(ctx, workspaceID), key); err != nil {
+			return err
+		}
+		fmt.Printf("%s\t%s\n", key.ID, plaintext)
+	case "list":
+		keys, err := repo.GetAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			status := "active"
+			if k.RevokedAt != nil {
+				status = "revoked " + k.RevokedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%s\t%s\t%s…\t%s\t%s\n", k.ID, k.Name, k.Prefix, k.Role, status)
+		}
+	case "revoke":
+		if len(args) < 2 {
+			return fmt.Errorf("missing key id\n%s", apiKeyUsage)
+		}
+		id, err := uuid.Parse(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid key id %q\n%s", args[1], apiKeyUsage)
+		}
+		if err := repo.RevokeAPIKey(ctx, id, time.Now().UTC()); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown apikey command %q\n%s", args[0], apiKeyUsage)
+	}
+	return nil
+}
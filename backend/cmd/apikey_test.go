@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"policy-match/internal/config"
+	"policy-match/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newAPIKeyTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	return &config.Config{
+		DBDriver:      repository.DriverSQLite,
+		DBURL:         filepath.Join(t.TempDir(), "policy-match.db"),
+		DBAutoMigrate: true,
+	}
+}
+
+// captureAPIKey runs the apikey subcommand and returns what it printed.
+func captureAPIKey(t *testing.T, cfg *config.Config, args ...string) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	runErr := runAPIKey(cfg, args)
+	os.Stdout = stdout
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	return string(out), runErr
+}
+
+func TestAPIKeyCommand(t *testing.T) {
+	cfg := newAPIKeyTestConfig(t)
+
+	out, err := captureAPIKey(t, cfg, "create", "ci", "100", "5000")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	id, plaintext, ok := strings.Cut(strings.TrimSpace(out), "\t")
+	if !ok || !strings.HasPrefix(plaintext, "pm_") {
+		t.Fatalf("create printed %q, want the key id and plaintext", out)
+	}
+
+	repo, err := repository.NewRepository(cfg)
+	if err != nil {
+		t.Fatalf("newRepository: %v", err)
+	}
+	ctx := repository.WithWorkspace(context.Background(), repository.DefaultWorkspaceID)
+	keys, err := repo.GetAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("getAPIKeys: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("stored %d keys, want 1", len(keys))
+	}
+	key := keys[0]
+	if key.ID.String() != id || key.Role != repository.RoleAdmin || key.RequestQuota != 100 || key.TokenQuota != 5000 {
+		t.Errorf("key = %+v, want an admin key with the given quotas", key)
+	}
+	if key.KeyHash == "" || strings.Contains(key.KeyHash, plaintext) || !strings.HasPrefix(plaintext, key.Prefix) {
+		t.Errorf("key stores hash %q and prefix %q, want a hash and the plaintext's prefix", key.KeyHash, key.Prefix)
+	}
+
+	out, err = captureAPIKey(t, cfg, "list")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if !strings.Contains(out, id) || !strings.Contains(out, "active") || strings.Contains(out, plaintext) {
+		t.Errorf("list printed %q, want the active key without its plaintext", out)
+	}
+
+	if _, err := captureAPIKey(t, cfg, "revoke", id); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	out, err = captureAPIKey(t, cfg, "list")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if !strings.Contains(out, "revoked") {
+		t.Errorf("list printed %q, want the key revoked", out)
+	}
+
+	if _, err := captureAPIKey(t, cfg, "revoke", uuid.NewString()); err == nil {
+		t.Error("revoking an unknown key succeeded")
+	}
+}
+
+func TestAPIKeyCommandWorkspace(t *testing.T) {
+	cfg := newAPIKeyTestConfig(t)
+
+	if _, err := captureAPIKey(t, cfg, "create", "ci", "0", "0", uuid.NewString()); err == nil {
+		t.Fatal("created a key in a workspace that does not exist")
+	}
+
+	repo, err := repository.NewRepository(cfg)
+	if err != nil {
+		t.Fatalf("newRepository: %v", err)
+	}
+	ws := &repository.Workspace{BaseModel: repository.BaseModel{ID: uuid.New()}, Name: "acme"}
+	if err := repo.CreateWorkspace(context.Background(), ws); err != nil {
+		t.Fatalf("createWorkspace: %v", err)
+	}
+	if _, err := captureAPIKey(t, cfg, "create", "ci", "0", "0", ws.ID.String()); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	keys, err := repo.GetAPIKeys(repository.WithWorkspace(context.Background(), ws.ID))
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("workspace keys = %d, %v, want 1", len(keys), err)
+	}
+	keys, err = repo.GetAPIKeys(repository.WithWorkspace(context.Background(), repository.DefaultWorkspaceID))
+	if err != nil || len(keys) != 0 {
+		t.Errorf("default workspace keys = %d, %v, want 0", len(keys), err)
+	}
+}
+
+func TestAPIKeyCommandInvalidArgs(t *testing.T) {
+	cfg := newAPIKeyTestConfig(t)
+
+	for _, args := range [][]string{
+		{},
+		{"create"},
+		{"create", "ci", "-1"},
+		{"create", "ci", "many"},
+		{"create", "ci", "0", "-5"},
+		{"create", "ci", "0", "0", "not-a-uuid"},
+		{"revoke"},
+		{"revoke", "not-a-uuid"},
+		{"rotate"},
+	} {
+		if _, err := captureAPIKey(t, cfg, args...); err == nil || !strings.Contains(err.Error(), "usage:") {
+			t.Errorf("apikey %v: err = %v, want a usage error", args, err)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
 	"policy-match/internal/config"
 	"policy-match/internal/utils"
+	"syscall"
 
 	"github.com/rs/zerolog/log"
 )
@@ -13,11 +17,37 @@ func main() {
 		log.Error().Msg("error loading config: " + err.Error())
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if cfg == nil {
+			log.Fatal().Msg("cannot migrate without a valid config")
+		}
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatal().Msg("error migrating database: " + err.Error())
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if cfg == nil {
+			log.Fatal().Msg("cannot manage api keys without a valid config")
+		}
+		if err := runAPIKey(cfg, os.Args[2:]); err != nil {
+			log.Fatal().Msg("error managing api keys: " + err.Error())
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	utils.Init()
-	server := NewServer(cfg)
+	server, err := NewServer(ctx, cfg)
+	if err != nil {
+		log.Fatal().Msg("error creating server: " + err.Error())
+	}
 	log.Info().Msg("Starting server...")
 
-	if err := server.Run(); err != nil {
+	if err := server.Run(ctx); err != nil {
 		log.Error().Msg("error running server: " + err.Error())
 	}
 }
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"net/http"
 	"os"
+	"policy-match/internal/blob"
 	"policy-match/internal/client/llm"
+	"policy-match/internal/client/oidc"
 	"policy-match/internal/client/tika"
 	"policy-match/internal/config"
 	"policy-match/internal/handler"
@@ -11,17 +15,24 @@ import (
 	"policy-match/internal/repository"
 	"policy-match/internal/service"
 	"policy-match/internal/utils"
+	"policy-match/internal/worker"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
 
+const shutdownTimeout = 30 * time.Second
+
 type Server struct {
 	router *gin.Engine
+	pool   *worker.Pool
 }
 
-func NewServer(cfg *config.Config) *Server {
+// NewServer builds the router and starts the job workers, which run until
+// ctx is cancelled.
+func NewServer(ctx context.Context, cfg *config.Config) (*Server, error) {
 	r := gin.New()
 
 	r.Use(middleware.LocaleMiddleware(utils.Bundle))
@@ -31,23 +42,72 @@ func NewServer(cfg *config.Config) *Server {
 	r.Use(cors.Default())
 
 	r.Use(logger.Init())
+	r.Use(middleware.ErrorHandler(utils.Bundle))
 
-	repository := repository.NewRepository(cfg.DBURL)
-	llmClient := llm.NewLLMClient(cfg, repository)
+	repository, err := repository.NewRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := llm.NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	embedder, err := llm.NewEmbedder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	llmClient := llm.NewLLMClient(cfg, repository, provider, embedder)
 	tikaClient := tika.NewTikaClient(cfg)
-	chatService := service.NewService(cfg, llmClient, tikaClient, repository)
+	blobStore, err := blob.NewStore(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	pool := worker.NewPool(cfg, repository)
+	chatService := service.NewService(cfg, llmClient, tikaClient, repository, blobStore, pool)
+	pool.Start(ctx, chatService)
+	go chatService.ExpireAPIKeys(ctx)
 	h := handler.NewHandler(chatService)
 
-	handler.RegisterRoutes(r, h)
+	var auth []gin.HandlerFunc
+	if cfg.APIAuthEnabled {
+		if cfg.OIDCIssuer != "" {
+			auth = append(auth, middleware.OIDCAuth(utils.Bundle, oidc.NewVerifier(cfg), chatService))
+		}
+		auth = append(auth, middleware.APIKeyAuth(utils.Bundle, chatService))
+	} else {
+		log.Warn().Msg("API_AUTH_ENABLED is false, the API is public and every caller is an admin of the default workspace")
+		auth = append(auth, middleware.Workspace(utils.Bundle, chatService))
+	}
+	handler.RegisterRoutes(r, h, auth...)
 
-	return &Server{router: r}
+	return &Server{router: r, pool: pool}, nil
 }
 
-func (s *Server) Run() error {
+// Run serves until ctx is cancelled, then stops taking requests, lets
+// in-flight ones finish and waits for the workers to return.
+func (s *Server) Run(ctx context.Context) error {
 	port := os.Getenv("PORT")
 	if port == "" {
 		log.Info().Msg("PORT is not set, using default port 8080")
 		port = "8080"
 	}
-	return s.router.Run(":" + port)
+
+	srv := &http.Server{Addr: ":" + port, Handler: s.router}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info().Msg("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	s.pool.Wait()
+	return err
 }
@@ -0,0 +1,76 @@
+// Package apperr defines the errors the service and repository layers
+// return for conditions a caller can act on. Each carries a Kind, which
+// decides the HTTP status, and a Code, which is both the machine-readable
+// code in error responses and the locale message ID.
+package apperr
+
+import (
+	"errors"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindNotFound        Kind = "not_found"
+	KindInvalidInput    Kind = "invalid_input"
+	KindConflict        Kind = "conflict"
+	KindUpstreamFailure Kind = "upstream_failure"
+	KindRateLimited     Kind = "rate_limited"
+)
+
+// CodeInternal is the code of every error that is not an *Error.
+const CodeInternal = "an_error_occurred_while_processing_your_request"
+
+type Error struct {
+	Kind Kind
+	Code string
+	// RetryAfter is set on rate-limited errors when the wait is known.
+	RetryAfter time.Duration
+	// Err is the cause. It is logged, never sent to clients.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return string(e.Kind) + ": " + e.Code
+	}
+	return string(e.Kind) + ": " + e.Code + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(code string, err error) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Err: err}
+}
+
+func InvalidInput(code string, err error) *Error {
+	return &Error{Kind: KindInvalidInput, Code: code, Err: err}
+}
+
+func Conflict(code string, err error) *Error {
+	return &Error{Kind: KindConflict, Code: code, Err: err}
+}
+
+func UpstreamFailure(code string, err error) *Error {
+	return &Error{Kind: KindUpstreamFailure, Code: code, Err: err}
+}
+
+func RateLimited(code string, retryAfter time.Duration, err error) *Error {
+	return &Error{Kind: KindRateLimited, Code: code, RetryAfter: retryAfter, Err: err}
+}
+
+// As returns the outermost *Error in err's chain.
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
+
+// Is reports whether err is an *Error of kind.
+func Is(err error, kind Kind) bool {
+	appErr, ok := As(err)
+	return ok && appErr.Kind == kind
+}
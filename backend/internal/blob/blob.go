@@ -0,0 +1,73 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"policy-match/internal/config"
+	"regexp"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Object describes a stored blob. Key is the hex SHA-256 of the content, so
+// identical uploads share one object.
+type Object struct {
+	Key  string
+	Size int64
+}
+
+// Store persists uploaded files by content address.
+type Store interface {
+	Put(ctx context.Context, r io.Reader) (Object, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func NewStore(ctx context.Context, cfg *config.Config) (Store, error) {
+	switch cfg.BlobDriver {
+	case "", DriverLocal:
+		return NewLocalStore(cfg.BlobLocalDir)
+	case DriverS3:
+		return NewS3Store(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("newStore :: unsupported driver: %s", cfg.BlobDriver)
+	}
+}
+
+var keyPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func validKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// spool copies r into a temporary file while hashing it, so the content
+// address is known before the blob is written to its final location.
+func spool(r io.Reader) (*os.File, Object, error) {
+	tmp, err := os.CreateTemp("", "blob-*")
+	if err != nil {
+		return nil, Object{}, err
+	}
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, Object{}, err
+	}
+
+	return tmp, Object{Key: hex.EncodeToString(h.Sum(nil)), Size: size}, nil
+}
@@ -0,0 +1,232 @@
+package blob
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"policy-match/internal/config"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStore runs the behavior every Store must share.
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	content := []byte("Staff must wear badges.")
+	sum := sha256.Sum256(content)
+
+	obj, err := store.Put(ctx, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if obj.Key != hex.EncodeToString(sum[:]) || obj.Size != int64(len(content)) {
+		t.Fatalf("object = %+v, want the content's hash and size", obj)
+	}
+
+	// Identical content maps to the same object.
+	again, err := store.Put(ctx, bytes.NewReader(content))
+	if err != nil || again != obj {
+		t.Fatalf("second put = %+v, %v, want %+v", again, err, obj)
+	}
+
+	rc, err := store.Get(ctx, obj.Key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("get = %q, %v, want %q", got, err, content)
+	}
+
+	missing := strings.Repeat("0", 64)
+	if _, err := store.Get(ctx, missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get missing key: %v, want ErrNotFound", err)
+	}
+	for _, key := range []string{"", "../../etc/passwd", strings.Repeat("Z", 64)} {
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("get invalid key %q: %v, want ErrNotFound", key, err)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("delete invalid key %q: %v, want ErrNotFound", key, err)
+		}
+	}
+
+	if err := store.Delete(ctx, obj.Key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Get(ctx, obj.Key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get deleted key: %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, obj.Key); err != nil {
+		t.Errorf("delete twice: %v, want nil", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("newLocalStore: %v", err)
+	}
+	testStore(t, store)
+
+	obj, err := store.Put(context.Background(), strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, obj.Key[:2], obj.Key[2:4], obj.Key)); err != nil {
+		t.Errorf("blob not stored under its fan-out path: %v", err)
+	}
+	parts, _ := filepath.Glob(filepath.Join(dir, obj.Key[:2], obj.Key[2:4], ".part-*"))
+	if len(parts) != 0 {
+		t.Errorf("partial files left behind: %v", parts)
+	}
+}
+
+// fakeS3 is the subset of the S3 API the store uses, kept in memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]bool
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T) *httptest.Server {
+	t.Helper()
+	s := &fakeS3{buckets: map[string]bool{}, objects: map[string][]byte{}}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, object, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if object == "" {
+		switch r.Method {
+		case http.MethodHead:
+			if !s.buckets[bucket] {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPut:
+			s.buckets[bucket] = true
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+		return
+	}
+
+	content, ok := s.objects[r.URL.Path]
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+			}
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		if r.Method == http.MethodGet {
+			http.ServeContent(w, r, object, time.Time{}, bytes.NewReader(content))
+		}
+	case http.MethodPut:
+		body, err := readS3Body(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = body
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// readS3Body reads a plain body, or one sent with aws-chunked encoding as
+// clients do over plain HTTP.
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+	var body []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return body, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		body = append(body, chunk[:size]...)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	srv := newFakeS3(t)
+	u, _ := url.Parse(srv.URL)
+	store, err := NewS3Store(context.Background(), &config.Config{
+		S3Endpoint:  u.Host,
+		S3Bucket:    "policy-match",
+		S3Prefix:    "blobs/",
+		S3Region:    "us-east-1",
+		S3AccessKey: "access",
+		S3SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("newS3Store: %v", err)
+	}
+	testStore(t, store)
+}
+
+// TestS3StoreMinIO runs against a real MinIO, such as the one in
+// docker-compose.yml, when S3_TEST_ENDPOINT is set.
+func TestS3StoreMinIO(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT is not set")
+	}
+	store, err := NewS3Store(context.Background(), &config.Config{
+		S3Endpoint:  endpoint,
+		S3Bucket:    "policy-match-test",
+		S3Prefix:    "blobs/",
+		S3Region:    "us-east-1",
+		S3AccessKey: os.Getenv("S3_TEST_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_TEST_SECRET_KEY"),
+	})
+	if err != nil {
+		t.Fatalf("newS3Store: %v", err)
+	}
+	testStore(t, store)
+}
@@ -0,0 +1,89 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore keeps blobs on the local filesystem under root/ab/cd/<key>.
+type LocalStore struct {
+	root string
+}
+
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("newLocalStore :: create root: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, r io.Reader) (Object, error) {
+	tmp, obj, err := spool(r)
+	if err != nil {
+		return Object{}, fmt.Errorf("local :: spool: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	dst := s.path(obj.Key)
+	if _, err := os.Stat(dst); err == nil {
+		return obj, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return Object{}, fmt.Errorf("local :: create dir: %w", err)
+	}
+
+	// Write next to the destination and rename so readers never
+	// observe a partially written blob.
+	part, err := os.CreateTemp(filepath.Dir(dst), ".part-*")
+	if err != nil {
+		return Object{}, fmt.Errorf("local :: create file: %w", err)
+	}
+	defer os.Remove(part.Name())
+
+	_, err = io.Copy(part, tmp)
+	if closeErr := part.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("local :: write file: %w", err)
+	}
+	if err := os.Rename(part.Name(), dst); err != nil {
+		return Object{}, fmt.Errorf("local :: rename file: %w", err)
+	}
+	return obj, nil
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("local :: open file: %w", err)
+	}
+	return f, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrNotFound
+	}
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.root, key[:2], key[2:4], key)
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken is a rough, model-agnostic estimate used to size chunks
+// without shipping a tokenizer.
+const charsPerToken = 4
+
+// Chunk is a contiguous slice of an extracted document. Offset is the byte
+// offset of Text within the full document.
+type Chunk struct {
+	Index  int
+	Offset int
+	Text   string
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+// separators are tried in order: page breaks, sections, lines, words.
+var separators = []string{"\f", "\n\n", "\n", " "}
+
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// SplitDocument splits text into chunks of at most maxTokens estimated
+// tokens, cutting at the coarsest boundary that fits. Chunks are contiguous
+// so offsets can be mapped back to the full text.
+func SplitDocument(text string, maxTokens int) []Chunk {
+	maxChars := maxTokens * charsPerToken
+	if maxChars <= 0 || len(text) <= maxChars {
+		return appendChunk(nil, text, 0, len(text))
+	}
+
+	var chunks []Chunk
+	start, end := 0, 0
+	for _, seg := range segments(text, 0, maxChars, 0) {
+		if seg.end-start > maxChars && end > start {
+			chunks = appendChunk(chunks, text, start, end)
+			start = end
+		}
+		end = seg.end
+	}
+	return appendChunk(chunks, text, start, end)
+}
+
+func segments(text string, offset int, maxChars int, level int) []span {
+	if len(text) <= maxChars {
+		return []span{{start: offset, end: offset + len(text)}}
+	}
+
+	var out []span
+	if level == len(separators) {
+		for i := 0; i < len(text); {
+			j := min(i+maxChars, len(text))
+			for j < len(text) && j > i+1 && !utf8.RuneStart(text[j]) {
+				j--
+			}
+			out = append(out, span{start: offset + i, end: offset + j})
+			i = j
+		}
+		return out
+	}
+
+	sep := separators[level]
+	for pos := 0; pos < len(text); {
+		next := len(text)
+		if i := strings.Index(text[pos:], sep); i >= 0 {
+			next = pos + i + len(sep)
+		}
+		out = append(out, segments(text[pos:next], offset+pos, maxChars, level+1)...)
+		pos = next
+	}
+	return out
+}
+
+func appendChunk(chunks []Chunk, text string, start int, end int) []Chunk {
+	if strings.TrimSpace(text[start:end]) == "" {
+		return chunks
+	}
+	return append(chunks, Chunk{
+		Index:  len(chunks),
+		Offset: start,
+		Text:   text[start:end],
+	})
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitDocument(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		want      []string
+	}{
+		{"empty", "", 10, nil},
+		{"only whitespace", " \n\n ", 10, nil},
+		{"single chunk", "Staff must wear badges.", 10, []string{"Staff must wear badges."}},
+		{"no limit", "Staff must wear badges.", 0, []string{"Staff must wear badges."}},
+		{"cuts at sections", "aaaa aa\n\nbbbb bb", 3, []string{"aaaa aa\n\n", "bbbb bb"}},
+		{"cuts at page breaks first", "aaa\n\nbb\fcccc", 2, []string{"aaa\n\nbb\f", "cccc"}},
+		{"cuts at words", "aaa bbb ccc", 2, []string{"aaa bbb ", "ccc"}},
+		{"cuts long words", "abcdefghij", 1, []string{"abcd", "efgh", "ij"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitDocument(tt.text, tt.maxTokens)
+			var got []string
+			for i, c := range chunks {
+				if c.Index != i {
+					t.Errorf("chunk %d has index %d", i, c.Index)
+				}
+				got = append(got, c.Text)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitDocumentBoundaries(t *testing.T) {
+	text := strings.Repeat("Staff must wear badges at all times.\n", 20) + "\n\n" + strings.Repeat("Visitors sign in. ", 30)
+	const maxTokens = 25
+	chunks := SplitDocument(text, maxTokens)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+
+	// Chunks neither overlap nor leave gaps, so offsets map back into text.
+	next := 0
+	for _, c := range chunks {
+		if c.Offset != next {
+			t.Fatalf("chunk %d starts at %d, want %d", c.Index, c.Offset, next)
+		}
+		if text[c.Offset:c.Offset+len(c.Text)] != c.Text {
+			t.Fatalf("chunk %d does not match the text at its offset", c.Index)
+		}
+		if len(c.Text) > maxTokens*charsPerToken {
+			t.Errorf("chunk %d is %d bytes, over the limit", c.Index, len(c.Text))
+		}
+		next = c.Offset + len(c.Text)
+	}
+	if next != len(text) {
+		t.Errorf("chunks end at %d, want %d", next, len(text))
+	}
+}
+
+func TestSplitDocumentMultiByte(t *testing.T) {
+	// Neither text has a separator, so chunks are cut inside the run of
+	// runes and must not split one.
+	for _, text := range []string{strings.Repeat("é", 25), strings.Repeat("سياسة", 7), strings.Repeat("a€", 9)} {
+		chunks := SplitDocument(text, 2)
+		var b strings.Builder
+		for _, c := range chunks {
+			if !utf8.ValidString(c.Text) {
+				t.Errorf("chunk %d of %q is not valid UTF-8: %q", c.Index, text, c.Text)
+			}
+			if len(c.Text) > 2*charsPerToken {
+				t.Errorf("chunk %d of %q is %d bytes, over the limit", c.Index, text, len(c.Text))
+			}
+			b.WriteString(c.Text)
+		}
+		if b.String() != text {
+			t.Errorf("chunks of %q do not add back up to it", text)
+		}
+	}
+}
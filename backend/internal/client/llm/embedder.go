@@ -0,0 +1,30 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"policy-match/internal/config"
+)
+
+const (
+	EmbedderLocal  = "local"
+	EmbedderOpenAI = "openai"
+)
+
+// Embedder turns texts into vectors for rule retrieval. Vectors are only
+// compared with vectors of the same Model.
+type Embedder interface {
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+func NewEmbedder(cfg *config.Config) (Embedder, error) {
+	switch cfg.EmbeddingProvider {
+	case "", EmbedderLocal:
+		return NewLocalEmbedder(cfg.EmbeddingDimensions), nil
+	case EmbedderOpenAI:
+		return NewOpenAIEmbedder(cfg), nil
+	default:
+		return nil, fmt.Errorf("newEmbedder :: unsupported provider: %s", cfg.EmbeddingProvider)
+	}
+}
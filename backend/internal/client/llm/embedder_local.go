@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// LocalEmbedder is a deterministic bag-of-words embedder that needs no
+// external service. Words and word pairs are hashed into a fixed number of
+// dimensions, so texts sharing vocabulary end up close together.
+type LocalEmbedder struct {
+	dimensions int
+}
+
+func NewLocalEmbedder(dimensions int) *LocalEmbedder {
+	return &LocalEmbedder{dimensions: max(dimensions, 1)}
+}
+
+func (e *LocalEmbedder) Model() string {
+	return "local-hash-" + strconv.Itoa(e.dimensions)
+}
+
+func (e *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *LocalEmbedder) embed(text string) []float32 {
+	counts := map[string]int{}
+	prev := ""
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if len(word) < 3 || stopWords[word] {
+			prev = ""
+			continue
+		}
+		word = stem(word)
+		counts[word]++
+		if prev != "" {
+			counts[prev+" "+word]++
+		}
+		prev = word
+	}
+
+	vector := make([]float32, e.dimensions)
+	for term, n := range counts {
+		h := fnv.New64a()
+		h.Write([]byte(term))
+		sum := h.Sum64()
+		// The sign bit spreads collisions out instead of piling them up.
+		weight := float32(1 + math.Log(float64(n)))
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vector[sum%uint64(e.dimensions)] += weight
+	}
+	return normalize(vector)
+}
+
+// stem strips a few common English suffixes so "employees" and "employee"
+// share a dimension.
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "all": true, "any": true,
+	"not": true, "shall": true, "must": true, "should": true, "may": true,
+	"with": true, "this": true, "that": true, "from": true, "have": true,
+	"has": true, "been": true, "will": true, "its": true, "their": true,
+	"such": true, "each": true, "other": true, "than": true, "into": true,
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	n := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= n
+	}
+	return v
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"policy-match/internal/config"
+	"strings"
+)
+
+// embeddingBatchSize is the number of inputs sent per embeddings request.
+const embeddingBatchSize = 96
+
+// OpenAIEmbedder talks to any OpenAI-compatible embeddings endpoint.
+type OpenAIEmbedder struct {
+	baseURL    string
+	apiKey     string
+	authHeader string
+	model      string
+	client     *http.Client
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func NewOpenAIEmbedder(cfg *config.Config) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		baseURL:    strings.TrimSuffix(cfg.EmbeddingBaseURL, "/"),
+		apiKey:     cfg.EmbeddingAPIKey,
+		authHeader: cfg.LLMAuthHeader,
+		model:      cfg.EmbeddingModel,
+		client:     newHTTPClient(cfg),
+	}
+}
+
+func (e *OpenAIEmbedder) Model() string {
+	return e.model
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		batch := texts[start:min(start+embeddingBatchSize, len(texts))]
+		out, err := e.embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, out...)
+	}
+	return vectors, nil
+}
+
+func (e *OpenAIEmbedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	payload, err := json.Marshal(embeddingRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("embeddings :: error marshalling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("embeddings :: error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		setAuth(req, e.authHeader, e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings :: error calling API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError("embeddings", resp, body)
+	}
+
+	var er embeddingResponse
+	if err := json.Unmarshal(body, &er); err != nil {
+		return nil, fmt.Errorf("embeddings :: error decoding response: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range er.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings :: unexpected index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("embeddings :: no embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
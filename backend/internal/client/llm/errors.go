@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrRateLimited means the provider answered 429. The API maps it to
+	// 429 too.
+	ErrRateLimited = errors.New("llm provider rate limited the request")
+	// ErrProviderUnavailable covers 5xx answers, network failures and an
+	// open circuit breaker. The API maps it to 503.
+	ErrProviderUnavailable = errors.New("llm provider is unavailable")
+	// ErrInvalidOutput means the model's reply still failed its JSON schema
+	// after repair and every re-prompt.
+	ErrInvalidOutput = errors.New("llm output does not match the schema")
+	// ErrInvalidResponse means the provider answered 200 with a body that
+	// is not a chat completion or carries no choices.
+	ErrInvalidResponse = errors.New("llm provider returned an invalid response")
+)
+
+// StatusError is a non-2xx answer from a provider. It matches
+// ErrRateLimited or ErrProviderUnavailable with errors.Is where that
+// applies.
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	// RetryAfter is the provider's Retry-After hint, 0 without one.
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s :: API error [%d]: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrProviderUnavailable:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+func newStatusError(provider string, resp *http.Response, body []byte) *StatusError {
+	return &StatusError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Body:       string(body),
+	}
+}
+
+// RetryAfter returns the provider's Retry-After hint carried by err.
+func RetryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP date.
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"policy-match/internal/repository"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// resolveRuleResults links model verdicts to stored rules and locates each
+// evidence quote in the chunk. Offsets are left as byte offsets into the
+// full document; toRuneOffsets converts them once chunks are merged.
+func resolveRuleResults(policyRules []repository.Rule, chunk Chunk, results []RuleResult) {
+	byRuleID := make(map[string]repository.Rule, len(policyRules))
+	for _, rule := range policyRules {
+		byRuleID[rule.RuleID] = rule
+	}
+
+	for i := range results {
+		res := &results[i]
+		res.RuleID = strings.Trim(strings.TrimSpace(res.RuleID), "[]")
+		if rule, ok := byRuleID[res.RuleID]; ok {
+			res.RuleUUID = rule.ID
+		}
+		res.Confidence = min(max(res.Confidence, 0), 1)
+		res.EvidenceStart, res.EvidenceEnd = -1, -1
+		if start, end, ok := locateEvidence(chunk.Text, res.Evidence); ok {
+			res.EvidenceStart = chunk.Offset + start
+			res.EvidenceEnd = chunk.Offset + end
+		}
+	}
+}
+
+// locateEvidence finds quote in text, first verbatim and then ignoring case
+// and whitespace differences. It returns byte offsets into text.
+func locateEvidence(text string, quote string) (int, int, bool) {
+	quote = strings.TrimSpace(quote)
+	if quote == "" {
+		return 0, 0, false
+	}
+	if i := strings.Index(text, quote); i >= 0 {
+		return i, i + len(quote), true
+	}
+
+	normText, starts, ends := normalizeWithIndex(text)
+	normQuote, _, _ := normalizeWithIndex(quote)
+	if normQuote == "" {
+		return 0, 0, false
+	}
+	i := strings.Index(normText, normQuote)
+	if i < 0 {
+		return 0, 0, false
+	}
+	return starts[i], ends[i+len(normQuote)-1], true
+}
+
+// normalizeWithIndex lowercases s and collapses whitespace runs to a single
+// space. For every byte of the result it records the byte range of the
+// source rune it came from.
+func normalizeWithIndex(s string) (string, []int, []int) {
+	var b strings.Builder
+	starts := make([]int, 0, len(s))
+	ends := make([]int, 0, len(s))
+	pendingSpace := false
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			pendingSpace = b.Len() > 0
+			continue
+		}
+		if pendingSpace {
+			b.WriteByte(' ')
+			starts = append(starts, i)
+			ends = append(ends, i)
+			pendingSpace = false
+		}
+		lr := unicode.ToLower(r)
+		n, _ := b.WriteRune(lr)
+		for range n {
+			starts = append(starts, i)
+			ends = append(ends, i+utf8.RuneLen(r))
+		}
+	}
+	return b.String(), starts, ends
+}
+
+// toRuneOffsets converts byte offsets into character offsets so clients can
+// slice the text without knowing its encoding.
+func toRuneOffsets(text string, results []RuleResult) {
+	for i := range results {
+		res := &results[i]
+		if res.EvidenceStart < 0 || res.EvidenceEnd > len(text) {
+			res.EvidenceStart, res.EvidenceEnd = -1, -1
+			continue
+		}
+		start, end := res.EvidenceStart, res.EvidenceEnd
+		res.EvidenceStart = utf8.RuneCountInString(text[:start])
+		res.EvidenceEnd = res.EvidenceStart + utf8.RuneCountInString(text[start:end])
+	}
+}
@@ -0,0 +1,70 @@
+package llm
+
+import "testing"
+
+func TestLocateEvidence(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		quote string
+		want  string
+		ok    bool
+	}{
+		{"verbatim", "Staff must wear badges.", "wear badges", "wear badges", true},
+		{"not found", "Staff must wear badges.", "visitors sign in", "", false},
+		{"empty quote", "Staff must wear badges.", "  ", "", false},
+		{"verbatim repeat beats an earlier case-insensitive match", "Wear badges. Then wear badges again.", "wear badges", "wear badges", true},
+		{"case and whitespace", "Staff  must\n\twear Badges.", "must wear badges", "must\n\twear Badges", true},
+		{"surrounding whitespace", "Staff must wear badges.", "  wear badges\n", "wear badges", true},
+		{"non-ascii", "يجب على الموظفين ارتداء   الشارات.", "ارتداء الشارات", "ارتداء   الشارات", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := locateEvidence(tt.text, tt.quote)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && tt.text[start:end] != tt.want {
+				t.Errorf("located %q, want %q", tt.text[start:end], tt.want)
+			}
+		})
+	}
+
+	// A verbatim repeat is found at its first occurrence.
+	if start, _, _ := locateEvidence("Wear badges. Wear badges.", "Wear badges"); start != 0 {
+		t.Errorf("repeated quote found at %d, want 0", start)
+	}
+}
+
+func TestResolveRuleResultsOffsets(t *testing.T) {
+	text := "Überblick.\nAlle Mitarbeiter müssen Ausweise tragen."
+	chunk := Chunk{Offset: len("Überblick.\n"), Text: "Alle Mitarbeiter müssen Ausweise tragen."}
+	results := []RuleResult{
+		{RuleID: "1", Evidence: "müssen Ausweise"},
+		{RuleID: "2", Evidence: "not in the text"},
+	}
+	resolveRuleResults(nil, chunk, results)
+	if got := text[results[0].EvidenceStart:results[0].EvidenceEnd]; got != "müssen Ausweise" {
+		t.Fatalf("byte offsets locate %q", got)
+	}
+
+	toRuneOffsets(text, results)
+	runes := []rune(text)
+	if got := string(runes[results[0].EvidenceStart:results[0].EvidenceEnd]); got != "müssen Ausweise" {
+		t.Errorf("rune offsets %d-%d locate %q", results[0].EvidenceStart, results[0].EvidenceEnd, got)
+	}
+	if results[0].EvidenceStart == len("Überblick.\nAlle Mitarbeiter ") {
+		t.Errorf("offsets are still byte offsets")
+	}
+	if results[1].EvidenceStart != -1 || results[1].EvidenceEnd != -1 {
+		t.Errorf("missing quote offsets = %d-%d, want -1", results[1].EvidenceStart, results[1].EvidenceEnd)
+	}
+}
+
+func TestToRuneOffsetsOutOfRange(t *testing.T) {
+	results := []RuleResult{{EvidenceStart: 2, EvidenceEnd: 40}}
+	toRuneOffsets("short", results)
+	if results[0].EvidenceStart != -1 || results[0].EvidenceEnd != -1 {
+		t.Errorf("offsets = %d-%d, want -1", results[0].EvidenceStart, results[0].EvidenceEnd)
+	}
+}
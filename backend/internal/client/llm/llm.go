@@ -5,13 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"policy-match/internal/config"
 	"policy-match/internal/dto"
 	"policy-match/internal/repository"
+	"slices"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -24,13 +26,26 @@ const (
 	Document:
 	<full document text>
 
-	Your task is to compare the Document against the Policy and output four fields:
-	- is_compliant_with_policy  (boolean)
-	- compliance_percentage      (number between 0 and 100)
-	- violations                 (array of strings; each violated rule)
-	- violation_percentage       (number between 0 and 100)
+	Each policy rule is prefixed with its identifier in square brackets, e.g. "- [3.1] ...". A rule that
+	ends with "(applies ...)" only applies under that condition; its verdict is not_applicable when the
+	Document falls outside it.
 
-	The system will enforce the JSON schema for your response, so focus solely on accurately assessing compliance and identifying violations.
+	Your task is to compare the Document against the Policy and output:
+	- is_human_review_required  (boolean; true when the document is ambiguous or unreadable)
+	- results                   (one entry per rule) with:
+	  - rule_id     the identifier of the rule exactly as given
+	  - verdict     pass, fail, not_applicable or uncertain
+	  - confidence  number between 0 and 1
+	  - evidence    a verbatim quote copied character for character from the Document that supports the verdict, or "" if none
+	  - rationale   one or two sentences explaining the verdict
+
+	Long documents are sent in parts, labelled "Document (part i of n)". Judge only the text you are given:
+	fail a rule when the excerpt contradicts it, and do not treat content that may appear in another part
+	as missing.
+
+	Do not compute an overall score; the system derives it from your per-rule verdicts.
+
+	The system will enforce the JSON schema for your response, so focus solely on accurately assessing each rule.
 	`
 
 	EXTRACT_RULES_SYSTEM_PROMPT = `
@@ -38,11 +53,27 @@ const (
 	Input comes exactly as:
 
 	Policy:
-	<full policy text>
+
+	[Part 1] <headings the part sits under, outermost first>
+	<policy text>
+
+	[Part 2] ...
+
+	Long policies are sent in sections, labelled "Policy (section i of n)". Extract only the rules in
+	the text you are given.
 
 	Your task:
 	• Identify each numbered clause or bullet as a “rule.”
 	• Extract its identifier and full wording.
+	• Give the number of the part it appears in.
+	• Rate its severity: "critical" for legal, safety or security obligations whose breach is serious on
+	  its own, "minor" for formalities and housekeeping, "major" otherwise.
+	• Name its topic category in two or three lowercase words, e.g. "access control", "data retention".
+	• Classify its obligation: "must", "must-not", "should" (recommended) or "may" (permitted).
+	• If it is a sub-clause, give the rule_id of the clause it is nested under as parent_rule_id;
+	  otherwise leave it empty.
+	• State the conditions under which it applies, e.g. "only to remote employees", as applicability;
+	  leave it empty if it always applies.
 	• Normalize spacing, preserve numbering, drop boilerplate.
 
 	IMPORTANT:
@@ -51,22 +82,62 @@ const (
 )
 
 type LLMClient struct {
-	cfg  *config.Config
-	repo *repository.Repository
+	cfg      *config.Config
+	repo     repository.Repository
+	provider Provider
+	embedder Embedder
 }
 
-func NewLLMClient(cfg *config.Config, repo *repository.Repository) *LLMClient {
-	return &LLMClient{cfg: cfg, repo: repo}
+func NewLLMClient(cfg *config.Config, repo repository.Repository, provider Provider, embedder Embedder) *LLMClient {
+	return &LLMClient{cfg: cfg, repo: repo, provider: provider, embedder: embedder}
 }
 
 func (l *LLMClient) CheckCompliance(ctx context.Context, policyRules []repository.Rule, documentContent string) (*CheckComplianceResponse, error) {
+	chunks := SplitDocument(documentContent, l.cfg.LLMChunkTokens)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("checkCompliance :: document has no extractable text")
+	}
+
+	chunkRules := l.selectRules(ctx, policyRules, chunks)
+
+	results := make([]*CheckComplianceResponse, len(chunks))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(l.cfg.LLMConcurrency)
+	for i, chunk := range chunks {
+		g.Go(func() error {
+			res, err := l.checkComplianceChunk(gctx, chunkRules[i], chunk, len(chunks))
+			if err != nil {
+				return fmt.Errorf("chunk %d/%d: %w", chunk.Index+1, len(chunks), err)
+			}
+			results[i] = res
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("checkCompliance :: %w", err)
+	}
+
+	merged := mergeComplianceResponses(policyRules, chunkRules, results)
+	toRuneOffsets(documentContent, merged.Results)
+	return merged, nil
+}
+
+func (l *LLMClient) checkComplianceChunk(ctx context.Context, policyRules []repository.Rule, chunk Chunk, total int) (*CheckComplianceResponse, error) {
 	var sysBuf bytes.Buffer
 	sysBuf.WriteString("Policy:\n")
 	for _, rule := range policyRules {
-		sysBuf.WriteString("- " + rule.RuleText + "\n")
+		sysBuf.WriteString("- [" + rule.RuleID + "] " + rule.RuleText)
+		if rule.Applicability != "" {
+			sysBuf.WriteString(" (applies " + rule.Applicability + ")")
+		}
+		sysBuf.WriteString("\n")
 	}
-	sysBuf.WriteString("Document:\n")
-	sysBuf.WriteString("- " + documentContent + "\n")
+	if total > 1 {
+		sysBuf.WriteString(fmt.Sprintf("Document (part %d of %d):\n", chunk.Index+1, total))
+	} else {
+		sysBuf.WriteString("Document:\n")
+	}
+	sysBuf.WriteString("- " + chunk.Text + "\n")
 
 	msgs := []MessageRequest{
 		{Role: SystemRole, Content: CHAT_SYSTEM_PROMPT},
@@ -76,11 +147,10 @@ func (l *LLMClient) CheckCompliance(ctx context.Context, policyRules []repositor
 	reqBody := ChatRequest{
 		Messages:            msgs,
 		Temperature:         0,
-		MaxCompletionTokens: 1024,
+		MaxCompletionTokens: complianceCompletionTokens(len(policyRules)),
 		TopP:                1.0,
 		Stream:              false,
 		Stop:                []string{"ERROR"},
-		Model:               l.cfg.LLMModel,
 		ResponseFormat: ResponseFormat{
 			Type: "json_schema",
 			JsonSchema: JsonSchema{
@@ -88,19 +158,22 @@ func (l *LLMClient) CheckCompliance(ctx context.Context, policyRules []repositor
 				Schema: ParametersRequest{
 					Type: "object",
 					Properties: map[string]any{
-						"is_compliant": map[string]any{
-							"type":        "boolean",
-							"description": "Whether the document is compliant with the policy",
-						},
-						"compliance_percentage": map[string]any{
-							"type":        "number",
-							"description": "The compliance percentage with the policy",
-						},
-						"violations": map[string]any{
+						"results": map[string]any{
 							"type":        "array",
-							"description": "The violated rules of the policy",
+							"description": "One verdict per policy rule",
 							"items": map[string]any{
-								"type": "string",
+								"type": "object",
+								"properties": map[string]any{
+									"rule_id": map[string]any{"type": "string"},
+									"verdict": map[string]any{
+										"type": "string",
+										"enum": []string{string(VerdictPass), string(VerdictFail), string(VerdictNotApplicable), string(VerdictUncertain)},
+									},
+									"confidence": map[string]any{"type": "number"},
+									"evidence":   map[string]any{"type": "string"},
+									"rationale":  map[string]any{"type": "string"},
+								},
+								"required": []string{"rule_id", "verdict", "confidence", "evidence", "rationale"},
 							},
 						},
 						"is_human_review_required": map[string]any{
@@ -108,41 +181,72 @@ func (l *LLMClient) CheckCompliance(ctx context.Context, policyRules []repositor
 							"description": "Whether the document requires human review",
 						},
 					},
-					Required: []string{"is_compliant", "compliance_percentage", "violations", "violation_percentage"},
+					Required: []string{"results", "is_human_review_required"},
 				},
 			},
 		},
 	}
 
-	payload, err := json.Marshal(reqBody)
+	var checkComplianceResponse CheckComplianceResponse
+	path, err := l.chatStructured(ctx, reqBody, &checkComplianceResponse)
 	if err != nil {
-		return nil, fmt.Errorf("checkCompliance :: error marshalling chat request: %w", err)
+		return nil, fmt.Errorf("checkComplianceChunk :: error calling %s API: %w", l.provider.Name(), err)
 	}
+	checkComplianceResponse.OutputPath = path
+	resolveRuleResults(policyRules, chunk, checkComplianceResponse.Results)
 
-	apiKey := ""
-	if key, ok := ctx.Value(dto.UserAPIKeyContext).(string); ok {
-		apiKey = key
-	}
+	return &checkComplianceResponse, nil
+}
 
-	resp, err := CallGroqAPIWithKey(ctx, l.cfg, payload, apiKey)
-	if err != nil {
-		return nil, fmt.Errorf("checkCompliance :: error calling groq API: %w", err)
-	}
+// complianceCompletionTokens leaves room for one verdict with evidence and
+// rationale per rule.
+func complianceCompletionTokens(rules int) int {
+	return min(max(1024, 256+160*rules), 8192)
+}
 
-	log.Info().Msg("checkCompliance :: groq LLM response: " + resp)
+// ExtractRules splits the policy into sections at its headings, extracts
+// the rules of each section in parallel and merges them in document order.
+// Each rule carries the breadcrumb of headings it was found under.
+func (l *LLMClient) ExtractRules(ctx context.Context, policyContent string) (*ExtractRulesResponse, error) {
+	sections := SplitPolicy(policyContent, l.cfg.LLMSectionTokens)
+	if len(sections) == 0 {
+		return &ExtractRulesResponse{Rules: []Rule{}, OutputPath: OutputClean}, nil
+	}
 
-	var checkComplianceResponse CheckComplianceResponse
-	if err := json.Unmarshal([]byte(resp), &checkComplianceResponse); err != nil {
-		return nil, fmt.Errorf("checkCompliance :: error unmarshalling check compliance response: %w", err)
+	results := make([]*ExtractRulesResponse, len(sections))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(l.cfg.LLMConcurrency)
+	for i, section := range sections {
+		g.Go(func() error {
+			res, err := l.extractSectionRules(gctx, section, len(sections))
+			if err != nil {
+				return fmt.Errorf("section %d/%d: %w", section.Index+1, len(sections), err)
+			}
+			results[i] = res
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("extractRules :: %w", err)
 	}
 
-	return &checkComplianceResponse, nil
+	return mergeExtractedRules(results), nil
 }
 
-func (l *LLMClient) ExtractRules(ctx context.Context, policyContent string) ([]Rule, error) {
+func (l *LLMClient) extractSectionRules(ctx context.Context, section Section, total int) (*ExtractRulesResponse, error) {
 	var sysBuf bytes.Buffer
-	sysBuf.WriteString("Policy:\n")
-	sysBuf.WriteString("- " + policyContent + "\n")
+	if total > 1 {
+		sysBuf.WriteString(fmt.Sprintf("Policy (section %d of %d):\n", section.Index+1, total))
+	} else {
+		sysBuf.WriteString("Policy:\n")
+	}
+	for i, part := range section.Parts {
+		sysBuf.WriteString(fmt.Sprintf("\n[Part %d]", i+1))
+		if len(part.Breadcrumb) > 0 {
+			sysBuf.WriteString(" " + strings.Join(part.Breadcrumb, " > "))
+		}
+		sysBuf.WriteString("\n" + part.Text + "\n")
+	}
 
 	msgs := []MessageRequest{
 		{Role: SystemRole, Content: EXTRACT_RULES_SYSTEM_PROMPT},
@@ -156,7 +260,6 @@ func (l *LLMClient) ExtractRules(ctx context.Context, policyContent string) ([]R
 		TopP:                1.0,
 		Stream:              false,
 		Stop:                []string{"ERROR"},
-		Model:               l.cfg.LLMModel,
 		ResponseFormat: ResponseFormat{
 			Type: "json_schema",
 			JsonSchema: JsonSchema{
@@ -172,8 +275,23 @@ func (l *LLMClient) ExtractRules(ctx context.Context, policyContent string) ([]R
 								"properties": map[string]any{
 									"rule_id":   map[string]any{"type": "string"},
 									"rule_text": map[string]any{"type": "string"},
+									"part": map[string]any{
+										"type":        "integer",
+										"description": "The number of the part the rule appears in",
+									},
+									"severity": map[string]any{
+										"type": "string",
+										"enum": []string{"critical", "major", "minor"},
+									},
+									"category": map[string]any{"type": "string"},
+									"obligation": map[string]any{
+										"type": "string",
+										"enum": []string{"must", "must-not", "should", "may"},
+									},
+									"parent_rule_id": map[string]any{"type": "string"},
+									"applicability":  map[string]any{"type": "string"},
 								},
-								"required": []string{"rule_id", "rule_text"},
+								"required": []string{"rule_id", "rule_text", "part", "severity", "category", "obligation", "parent_rule_id", "applicability"},
 							},
 						},
 					},
@@ -183,70 +301,90 @@ func (l *LLMClient) ExtractRules(ctx context.Context, policyContent string) ([]R
 		},
 	}
 
-	payload, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("extractRules :: error marshalling chat request: %w", err)
-	}
-
-	apiKey := ""
-	if key, ok := ctx.Value(dto.UserAPIKeyContext).(string); ok {
-		apiKey = key
-	}
-
-	resp, err := CallGroqAPIWithKey(ctx, l.cfg, payload, apiKey)
+	var extractRulesResponse ExtractRulesResponse
+	path, err := l.chatStructured(ctx, reqBody, &extractRulesResponse)
 	if err != nil {
-		return nil, fmt.Errorf("extractRules :: error calling groq API: %w", err)
+		return nil, fmt.Errorf("extractSectionRules :: error calling %s API: %w", l.provider.Name(), err)
 	}
-
-	raw := []byte(resp)
-
-	if !bytes.HasSuffix(raw, []byte("}")) || !bytes.Contains(raw, []byte("]")) {
-		raw = append(raw, []byte("]}")...)
+	extractRulesResponse.OutputPath = path
+	for i := range extractRulesResponse.Rules {
+		rule := &extractRulesResponse.Rules[i]
+		rule.Section = slices.Clone(section.breadcrumbFor(rule.Part))
 	}
 
-	var extractRulesResponse ExtractRulesResponse
-	if err := json.Unmarshal([]byte(raw), &extractRulesResponse); err != nil {
-		return nil, fmt.Errorf("extractRules :: error unmarshalling extract rules response: %w", err)
-	}
-
-	return extractRulesResponse.Rules, nil
+	return &extractRulesResponse, nil
 }
 
-func CallGroqAPIWithKey(ctx context.Context, cfg *config.Config, payload []byte, apiKey string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://demo-proxy.groqcloud.dev/openai/v1/chat/completions", bytes.NewReader(payload))
+// chatStructured sends req and decodes the reply into out. The reply is
+// validated against the request's JSON schema; one that does not parse is
+// repaired, and one that still fails is sent back to the model with the
+// error, up to cfg.LLMOutputRetries times.
+func (l *LLMClient) chatStructured(ctx context.Context, req ChatRequest, out any) (OutputPath, error) {
+	schema, err := schemaOf(req)
 	if err != nil {
-		return "", fmt.Errorf("callGroqAPIWithKey :: error creating chat request: %w", err)
+		return "", fmt.Errorf("chatStructured :: schema: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Origin", cfg.Origin)
-
-	if apiKey != "" {
-		log.Info().Msg("callGroqAPIWithKey :: using API key")
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+	messages := slices.Clip(req.Messages)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := l.chat(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		log.Debug().Str("schema", req.ResponseFormat.JsonSchema.Name).Msg("chatStructured :: LLM response: " + resp)
+
+		raw, repaired, verr := decodeStructured(resp, schema)
+		if verr == nil {
+			if err := json.Unmarshal(raw, out); err != nil {
+				return "", fmt.Errorf("chatStructured :: unmarshal: %w", err)
+			}
+			path := OutputClean
+			switch {
+			case attempt > 0:
+				path = OutputRetried
+			case repaired:
+				path = OutputRepaired
+			}
+			if path != OutputClean {
+				log.Info().Str("output_path", string(path)).Int("attempts", attempt+1).Msg("chatStructured :: recovered invalid output")
+			}
+			return path, nil
+		}
+
+		if attempt >= l.cfg.LLMOutputRetries {
+			return "", fmt.Errorf("chatStructured :: %w after %d attempts: %w", ErrInvalidOutput, attempt+1, verr)
+		}
+		log.Warn().Err(verr).Int("attempt", attempt+1).Msg("chatStructured :: invalid output, asking again")
+
+		// Each retry sees only the latest rejected reply, so the prompt
+		// does not grow with every attempt.
+		req.Messages = append(messages,
+			MessageRequest{Role: AssistantRole, Content: resp},
+			MessageRequest{Role: UserRole, Content: fmt.Sprintf(outputRetryPrompt, verr)},
+		)
 	}
+}
 
-	resp, err := http.DefaultClient.Do(req)
+func (l *LLMClient) chat(ctx context.Context, req ChatRequest) (string, error) {
+	resp, err := l.provider.Chat(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("callGroqAPIWithKey :: error calling chat API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return "", fmt.Errorf("RATE_LIMIT: %s", string(body))
+		return "", err
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("callGroqAPIWithKey :: chat API error [%d]: %s", resp.StatusCode, string(body))
+	l.recordUsage(ctx, resp.Usage)
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("chat :: %s: %w", l.provider.Name(), ErrInvalidResponse)
 	}
+	return resp.Choices[0].Message.Content, nil
+}
 
-	var cr ChatResponse
-	if err := json.Unmarshal(body, &cr); err != nil {
-		return "", fmt.Errorf("callGroqAPIWithKey :: error decoding chat response: %w", err)
+// recordUsage charges the tokens to the service API key the request was
+// made with, if any.
+func (l *LLMClient) recordUsage(ctx context.Context, usage Usage) {
+	id, ok := ctx.Value(dto.APIKeyIDContext).(uuid.UUID)
+	if !ok || usage.TotalTokens == 0 {
+		return
 	}
-	if len(cr.Choices) == 0 {
-		return "", fmt.Errorf("callGroqAPIWithKey :: error no choices in chat response")
+	if err := l.repo.AddAPIKeyTokens(ctx, id, usage.TotalTokens); err != nil {
+		log.Warn().Err(err).Msg("recordUsage :: addAPIKeyTokens")
 	}
-	return cr.Choices[0].Message.Content, nil
 }
@@ -1,10 +1,13 @@
 package llm
 
+import "github.com/google/uuid"
+
 type Role string
 
 const (
-	UserRole   Role = "user"
-	SystemRole Role = "system"
+	UserRole      Role = "user"
+	SystemRole    Role = "system"
+	AssistantRole Role = "assistant"
 )
 
 type MessageRequest struct {
@@ -57,22 +60,73 @@ type ChatChoice struct {
 	Message MessageRequest `json:"message"`
 }
 
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
 type ChatResponse struct {
 	Choices []ChatChoice `json:"choices"`
+	Usage   Usage        `json:"usage"`
 }
 
 type Rule struct {
 	RuleID   string `json:"rule_id"`
 	RuleText string `json:"rule_text"`
+	// Part is the 1-based part of the section the model found the rule
+	// in. Section is the heading breadcrumb of that part.
+	Part    int      `json:"part"`
+	Section []string `json:"-"`
+
+	Severity      string `json:"severity"`
+	Category      string `json:"category"`
+	Obligation    string `json:"obligation"`
+	ParentRuleID  string `json:"parent_rule_id"`
+	Applicability string `json:"applicability"`
 }
 
 type ExtractRulesResponse struct {
 	Rules []Rule `json:"rules"`
+
+	OutputPath OutputPath `json:"-"`
+}
+
+type Verdict string
+
+const (
+	VerdictPass          Verdict = "pass"
+	VerdictFail          Verdict = "fail"
+	VerdictNotApplicable Verdict = "not_applicable"
+	VerdictUncertain     Verdict = "uncertain"
+	// VerdictNotAddressed is never returned by the model: it marks rules
+	// that were not sent because no chunk was relevant to them.
+	VerdictNotAddressed Verdict = "not_addressed"
+)
+
+// RuleResult is the verdict for a single policy rule. EvidenceStart and
+// EvidenceEnd are character offsets into the extracted document text, or -1
+// when the quote could not be located.
+type RuleResult struct {
+	RuleUUID      uuid.UUID `json:"rule_uuid"`
+	RuleID        string    `json:"rule_id"`
+	Verdict       Verdict   `json:"verdict"`
+	Confidence    float64   `json:"confidence"`
+	Evidence      string    `json:"evidence"`
+	EvidenceStart int       `json:"evidence_start"`
+	EvidenceEnd   int       `json:"evidence_end"`
+	Rationale     string    `json:"rationale"`
 }
 
+// CheckComplianceResponse carries the model's per-rule verdicts. The score
+// fields are computed by the service, never taken from the model.
 type CheckComplianceResponse struct {
-	IsCompliant           bool     `json:"is_compliant"`
-	CompliancePercentage  int      `json:"compliance_percentage"`
-	Violations            []string `json:"violations"`
-	IsHumanReviewRequired bool     `json:"is_human_review_required"`
+	IsCompliant           bool         `json:"is_compliant"`
+	CompliancePercentage  int          `json:"compliance_percentage"`
+	ViolationPercentage   int          `json:"violation_percentage"`
+	Violations            []string     `json:"violations"`
+	IsHumanReviewRequired bool         `json:"is_human_review_required"`
+	Results               []RuleResult `json:"results"`
+
+	OutputPath OutputPath `json:"-"`
 }
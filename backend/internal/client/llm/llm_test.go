@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"policy-match/internal/config"
+	"policy-match/internal/repository"
+	"strings"
+	"testing"
+)
+
+func newTestClient(provider Provider) *LLMClient {
+	return NewLLMClient(&config.Config{
+		LLMChunkTokens:   1000,
+		LLMSectionTokens: 1000,
+		LLMConcurrency:   2,
+		LLMOutputRetries: 1,
+	}, nil, provider, nil)
+}
+
+func TestFakeProviderZeroValue(t *testing.T) {
+	fake := NewFakeProvider(nil)
+	res, err := newTestClient(fake).ExtractRules(context.Background(), "Staff must wear badges.")
+	if err != nil {
+		t.Fatalf("extractRules: %v", err)
+	}
+	if len(res.Rules) != 0 || res.OutputPath != OutputClean {
+		t.Errorf("response = %+v, want no rules on the clean path", res)
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+}
+
+func TestExtractRules(t *testing.T) {
+	fake := NewFakeProvider(func(req ChatRequest) (string, error) {
+		if !strings.Contains(req.Messages[1].Content, "[Part 1] Part I") {
+			t.Errorf("prompt does not name the part: %q", req.Messages[1].Content)
+		}
+		return `{"rules": [{"rule_id": "1", "rule_text": "Staff must wear badges.", "part": 1, "severity": "critical",
+			"category": "Physical", "obligation": "must", "parent_rule_id": "", "applicability": ""}]}`, nil
+	})
+	res, err := newTestClient(fake).ExtractRules(context.Background(), "Part I\nStaff must wear badges.\n")
+	if err != nil {
+		t.Fatalf("extractRules: %v", err)
+	}
+	if len(res.Rules) != 1 || res.Rules[0].Severity != "critical" || res.Rules[0].Section[0] != "Part I" {
+		t.Errorf("rules = %+v", res.Rules)
+	}
+}
+
+func TestChatStructuredRetries(t *testing.T) {
+	replies := []string{
+		`{"rules": [{"rule_id": "1"`,
+		`{"rules": []}`,
+	}
+	fake := NewFakeProvider(func(req ChatRequest) (string, error) {
+		reply := replies[0]
+		replies = replies[1:]
+		return reply, nil
+	})
+	res, err := newTestClient(fake).ExtractRules(context.Background(), "Staff must wear badges.")
+	if err != nil {
+		t.Fatalf("extractRules: %v", err)
+	}
+	if res.OutputPath != OutputRetried {
+		t.Errorf("output path = %s, want %s", res.OutputPath, OutputRetried)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	retry := requests[1].Messages
+	if len(retry) != 4 || retry[2].Role != AssistantRole || !strings.Contains(retry[3].Content, "rejected") {
+		t.Errorf("retry messages = %+v, want the rejected reply and the error", retry)
+	}
+}
+
+func TestChatStructuredGivesUp(t *testing.T) {
+	fake := NewFakeProvider(func(ChatRequest) (string, error) {
+		return "I cannot answer that.", nil
+	})
+	_, err := newTestClient(fake).ExtractRules(context.Background(), "Staff must wear badges.")
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Fatalf("err = %v, want ErrInvalidOutput", err)
+	}
+	if n := len(fake.Requests()); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+}
+
+func TestCheckCompliance(t *testing.T) {
+	rules := []repository.Rule{
+		{RuleID: "1", RuleText: "Staff must wear badges.", Severity: repository.SeverityMajor},
+		{RuleID: "2", RuleText: "Visitors must sign in.", Severity: repository.SeverityMajor},
+	}
+	fake := NewFakeProvider(func(ChatRequest) (string, error) {
+		return `{"results": [{"rule_id": "1", "verdict": "pass", "confidence": 0.9, "evidence": "wear badges", "rationale": "stated"}],
+			"is_human_review_required": false}`, nil
+	})
+	res, err := newTestClient(fake).CheckCompliance(context.Background(), rules, "All staff wear badges on site.")
+	if err != nil {
+		t.Fatalf("checkCompliance: %v", err)
+	}
+	if len(res.Results) != 2 {
+		t.Fatalf("results = %+v, want one per rule", res.Results)
+	}
+	verdicts := map[string]Verdict{}
+	for _, r := range res.Results {
+		verdicts[r.RuleID] = r.Verdict
+	}
+	if verdicts["1"] != VerdictPass || verdicts["2"] != VerdictUncertain {
+		t.Errorf("verdicts = %v, want 1 passed and the skipped 2 uncertain", verdicts)
+	}
+}
+
+func TestFakeProviderError(t *testing.T) {
+	fake := NewFakeProvider(func(ChatRequest) (string, error) {
+		return "", ErrProviderUnavailable
+	})
+	_, err := newTestClient(fake).ExtractRules(context.Background(), "Staff must wear badges.")
+	if !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+// emptyProvider answers every request without choices.
+type emptyProvider struct{}
+
+func (emptyProvider) Name() string { return "empty" }
+
+func (emptyProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	return &ChatResponse{}, nil
+}
+
+func TestChatWithoutChoices(t *testing.T) {
+	_, err := newTestClient(emptyProvider{}).ExtractRules(context.Background(), "Staff must wear badges.")
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("err = %v, want ErrInvalidResponse", err)
+	}
+}
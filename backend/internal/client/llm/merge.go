@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"policy-match/internal/repository"
+	"strings"
+	"unicode"
+)
+
+// verdictRank orders verdicts when the same rule is judged in several
+// chunks: a failure anywhere wins, then a pass, then uncertainty.
+var verdictRank = map[Verdict]int{
+	VerdictFail:          3,
+	VerdictPass:          2,
+	VerdictUncertain:     1,
+	VerdictNotApplicable: 0,
+}
+
+// mergeComplianceResponses folds per-chunk findings into one verdict per
+// policy rule. Rules the model skipped are reported as uncertain, and rules
+// that were sent with no chunk as not addressed. Each chunk is told not to
+// fail a rule for what another part may contain, so a required rule left
+// uncertain without evidence by every chunk of a multi-part document fails
+// and is flagged for review.
+func mergeComplianceResponses(policyRules []repository.Rule, chunkRules [][]repository.Rule, results []*CheckComplianceResponse) *CheckComplianceResponse {
+	merged := &CheckComplianceResponse{OutputPath: OutputClean}
+
+	sent := map[string]bool{}
+	for _, rules := range chunkRules {
+		for _, rule := range rules {
+			sent[rule.RuleID] = true
+		}
+	}
+
+	best := map[string]RuleResult{}
+	found := map[string]bool{}
+	for _, res := range results {
+		merged.IsHumanReviewRequired = merged.IsHumanReviewRequired || res.IsHumanReviewRequired
+		merged.OutputPath = worseOutputPath(merged.OutputPath, res.OutputPath)
+
+		for _, rr := range res.Results {
+			if _, ok := verdictRank[rr.Verdict]; !ok {
+				rr.Verdict = VerdictUncertain
+			}
+			if rr.Verdict != VerdictUncertain || rr.Evidence != "" {
+				found[rr.RuleID] = true
+			}
+			cur, ok := best[rr.RuleID]
+			if !ok || verdictRank[rr.Verdict] > verdictRank[cur.Verdict] ||
+				(rr.Verdict == cur.Verdict && rr.Confidence > cur.Confidence) {
+				best[rr.RuleID] = rr
+			}
+		}
+	}
+
+	merged.Results = make([]RuleResult, 0, len(policyRules))
+	for _, rule := range policyRules {
+		rr, ok := best[rule.RuleID]
+		if !ok && !sent[rule.RuleID] {
+			rr = RuleResult{
+				RuleUUID:      rule.ID,
+				RuleID:        rule.RuleID,
+				Verdict:       VerdictNotAddressed,
+				EvidenceStart: -1,
+				EvidenceEnd:   -1,
+				Rationale:     "No part of the document was relevant to this rule.",
+			}
+		} else if !ok {
+			rr = RuleResult{
+				RuleUUID:      rule.ID,
+				RuleID:        rule.RuleID,
+				Verdict:       VerdictUncertain,
+				EvidenceStart: -1,
+				EvidenceEnd:   -1,
+				Rationale:     "The model returned no verdict for this rule.",
+			}
+		} else if len(results) > 1 && !found[rule.RuleID] && rule.IsRequired() {
+			rr.Verdict = VerdictFail
+			rr.Rationale = "No part of the document addresses this required rule."
+			merged.IsHumanReviewRequired = true
+		}
+		merged.Results = append(merged.Results, rr)
+	}
+
+	return merged
+}
+
+// mergeExtractedRules joins per-section rules in document order. A rule
+// extracted twice, as happens where split sections overlap, is kept the
+// first time it appears. Rule IDs that repeat under different headings are
+// qualified with their breadcrumb, so they do not depend on which section
+// came first.
+func mergeExtractedRules(results []*ExtractRulesResponse) *ExtractRulesResponse {
+	merged := &ExtractRulesResponse{Rules: []Rule{}, OutputPath: OutputClean}
+
+	seen := map[string]bool{}
+	for _, res := range results {
+		merged.OutputPath = worseOutputPath(merged.OutputPath, res.OutputPath)
+		for _, rule := range res.Rules {
+			key := strings.Join(strings.FieldsFunc(strings.ToLower(rule.RuleText), func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+			}), " ")
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged.Rules = append(merged.Rules, rule)
+		}
+	}
+	qualifyRuleIDs(merged.Rules)
+
+	return merged
+}
+
+// qualifyRuleIDs prefixes a rule ID found under more than one breadcrumb,
+// such as clause "1" of both Part I and Part II, with the breadcrumb of
+// each rule. Parent IDs pointing at such a rule under the same breadcrumb
+// are qualified the same way.
+func qualifyRuleIDs(rules []Rule) {
+	breadcrumbs := map[string]map[string]bool{}
+	for _, rule := range rules {
+		if breadcrumbs[rule.RuleID] == nil {
+			breadcrumbs[rule.RuleID] = map[string]bool{}
+		}
+		breadcrumbs[rule.RuleID][strings.Join(rule.Section, " > ")] = true
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		crumb := strings.Join(rule.Section, " > ")
+		if crumb == "" {
+			continue
+		}
+		if rule.RuleID != "" && len(breadcrumbs[rule.RuleID]) > 1 {
+			rule.RuleID = crumb + " > " + rule.RuleID
+		}
+		if parent := breadcrumbs[rule.ParentRuleID]; rule.ParentRuleID != "" && len(parent) > 1 && parent[crumb] {
+			rule.ParentRuleID = crumb + " > " + rule.ParentRuleID
+		}
+	}
+}
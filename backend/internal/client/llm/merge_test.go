@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeExtractedRules(t *testing.T) {
+	partOne := &ExtractRulesResponse{OutputPath: OutputClean, Rules: []Rule{
+		{RuleID: "1", RuleText: "Staff must wear badges.", Section: []string{"Part I"}},
+		{RuleID: "1.1", RuleText: "Badges must show a photo.", Section: []string{"Part I"}, ParentRuleID: "1"},
+		{RuleID: "2", RuleText: "Visitors must sign in.", Section: []string{"Part I"}},
+	}}
+	partTwo := &ExtractRulesResponse{OutputPath: OutputRepaired, Rules: []Rule{
+		// Repeated where the sections overlap.
+		{RuleID: "2", RuleText: "Visitors must sign in.", Section: []string{"Part I"}},
+		{RuleID: "1", RuleText: "Contractors must sign an NDA.", Section: []string{"Part II"}},
+		{RuleID: "1.1", RuleText: "The NDA must be renewed yearly.", Section: []string{"Part II"}, ParentRuleID: "1"},
+		{RuleID: "3", RuleText: "Laptops must be encrypted.", Section: []string{"Part II"}},
+	}}
+
+	ids := func(merged *ExtractRulesResponse) map[string]string {
+		out := map[string]string{}
+		for _, rule := range merged.Rules {
+			out[rule.RuleText] = rule.RuleID + " < " + rule.ParentRuleID
+		}
+		return out
+	}
+
+	merged := mergeExtractedRules([]*ExtractRulesResponse{partOne, partTwo})
+	if merged.OutputPath != OutputRepaired {
+		t.Errorf("output path = %s, want %s", merged.OutputPath, OutputRepaired)
+	}
+	var order []string
+	for _, rule := range merged.Rules {
+		order = append(order, rule.RuleID)
+	}
+	want := []string{"Part I > 1", "Part I > 1.1", "2", "Part II > 1", "Part II > 1.1", "3"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("rule IDs = %q, want %q", order, want)
+	}
+	got := ids(merged)
+	if got["The NDA must be renewed yearly."] != "Part II > 1.1 < Part II > 1" {
+		t.Errorf("nested rule = %q, want its parent under the same part", got["The NDA must be renewed yearly."])
+	}
+
+	// The IDs do not depend on which section was merged first.
+	reversed := ids(mergeExtractedRules([]*ExtractRulesResponse{partTwo, partOne}))
+	for text, id := range got {
+		if reversed[text] != id {
+			t.Errorf("%q is %q in reverse order, want %q", text, reversed[text], id)
+		}
+	}
+}
+
+func TestMergeExtractedRulesEmpty(t *testing.T) {
+	merged := mergeExtractedRules(nil)
+	if merged.Rules == nil || len(merged.Rules) != 0 || merged.OutputPath != OutputClean {
+		t.Errorf("merged = %+v, want no rules and a clean path", merged)
+	}
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"policy-match/internal/config"
+	"policy-match/internal/dto"
+)
+
+const (
+	ProviderGroq   = "groq"
+	ProviderOpenAI = "openai"
+	ProviderFake   = "fake"
+)
+
+// Provider sends a chat completion request to an LLM backend and returns
+// the raw completion.
+type Provider interface {
+	Name() string
+	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+}
+
+func NewProvider(cfg *config.Config) (Provider, error) {
+	switch cfg.LLMProvider {
+	case "", ProviderGroq:
+		return NewResilientProvider(cfg, NewGroqProvider(cfg)), nil
+	case ProviderOpenAI:
+		return NewResilientProvider(cfg, NewOpenAIProvider(cfg)), nil
+	case ProviderFake:
+		return NewFakeProvider(nil), nil
+	default:
+		return nil, fmt.Errorf("newProvider :: unsupported provider: %s", cfg.LLMProvider)
+	}
+}
+
+// userAPIKey returns the caller-supplied key forwarded through the request
+// context, if any.
+func userAPIKey(ctx context.Context) string {
+	if key, ok := ctx.Value(dto.UserAPIKeyContext).(string); ok {
+		return key
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// FakeProvider is an in-process provider for tests and local runs. Without
+// a handler it answers every request with the zero value of the requested
+// JSON schema, so callers always receive a well-formed response.
+type FakeProvider struct {
+	mu       sync.Mutex
+	handler  func(ChatRequest) (string, error)
+	requests []ChatRequest
+}
+
+func NewFakeProvider(handler func(ChatRequest) (string, error)) *FakeProvider {
+	return &FakeProvider{handler: handler}
+}
+
+func (p *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+func (p *FakeProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	handler := p.handler
+	p.mu.Unlock()
+
+	var content string
+	if handler != nil {
+		var err error
+		content, err = handler(req)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		raw, err := json.Marshal(zeroValue(req.ResponseFormat.JsonSchema.Schema))
+		if err != nil {
+			return nil, fmt.Errorf("fake :: error marshalling response: %w", err)
+		}
+		content = string(raw)
+	}
+
+	return &ChatResponse{
+		Choices: []ChatChoice{
+			{Message: MessageRequest{Role: AssistantRole, Content: content}},
+		},
+	}, nil
+}
+
+// Requests returns every request the provider has received so far.
+func (p *FakeProvider) Requests() []ChatRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]ChatRequest(nil), p.requests...)
+}
+
+func zeroValue(schema any) any {
+	switch s := schema.(type) {
+	case ParametersRequest:
+		return zeroValue(map[string]any{"type": s.Type, "properties": s.Properties})
+	case map[string]any:
+		switch s["type"] {
+		case "object":
+			out := map[string]any{}
+			props, _ := s["properties"].(map[string]any)
+			for name, prop := range props {
+				out[name] = zeroValue(prop)
+			}
+			return out
+		case "array":
+			return []any{}
+		case "string":
+			if enum, ok := s["enum"].([]string); ok && len(enum) > 0 {
+				return enum[0]
+			}
+			return ""
+		case "number", "integer":
+			return 0
+		case "boolean":
+			return false
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package llm
+
+import (
+	"policy-match/internal/config"
+)
+
+const GroqBaseURL = "https://api.groq.com/openai/v1"
+
+// NewGroqProvider returns an OpenAI-compatible provider preconfigured for
+// Groq. LLM_BASE_URL may still override the endpoint, e.g. to use the demo
+// proxy.
+func NewGroqProvider(cfg *config.Config) *OpenAIProvider {
+	p := NewOpenAIProvider(cfg)
+	p.name = ProviderGroq
+	if p.baseURL == "" {
+		p.baseURL = GroqBaseURL
+	}
+	return p
+}
@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"policy-match/internal/config"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider talks to any OpenAI-compatible chat completions endpoint.
+type OpenAIProvider struct {
+	name       string
+	baseURL    string
+	apiKey     string
+	authHeader string
+	origin     string
+	model      string
+	client     *http.Client
+}
+
+func NewOpenAIProvider(cfg *config.Config) *OpenAIProvider {
+	return &OpenAIProvider{
+		name:       ProviderOpenAI,
+		baseURL:    strings.TrimSuffix(cfg.LLMBaseURL, "/"),
+		apiKey:     cfg.LLMAPIKey,
+		authHeader: cfg.LLMAuthHeader,
+		origin:     cfg.Origin,
+		model:      cfg.LLMModel,
+		client:     newHTTPClient(cfg),
+	}
+}
+
+// newHTTPClient bounds each attempt by LLMTimeout and connecting by
+// LLMConnectTimeout, so a dead endpoint fails fast enough to be retried.
+func newHTTPClient(cfg *config.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: cfg.LLMConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = cfg.LLMConnectTimeout
+	return &http.Client{Timeout: cfg.LLMTimeout, Transport: transport}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return p.name
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, chatReq ChatRequest) (*ChatResponse, error) {
+	if chatReq.Model == "" {
+		chatReq.Model = p.model
+	}
+
+	payload, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s :: error marshalling chat request: %w", p.name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("%s :: error creating chat request: %w", p.name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.origin != "" {
+		req.Header.Set("Origin", p.origin)
+	}
+
+	apiKey := p.apiKey
+	if key := userAPIKey(ctx); key != "" {
+		apiKey = key
+	}
+	if apiKey != "" {
+		setAuth(req, p.authHeader, apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s :: error calling chat API: %w", p.name, err)
+		}
+		return nil, fmt.Errorf("%s :: error calling chat API: %w: %w", p.name, ErrProviderUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s :: error reading chat response: %w", p.name, err)
+		}
+		return nil, fmt.Errorf("%s :: error reading chat response: %w: %w", p.name, ErrProviderUnavailable, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(p.name, resp, body)
+	}
+
+	var cr ChatResponse
+	if err := json.Unmarshal(body, &cr); err != nil {
+		return nil, fmt.Errorf("%s :: error decoding chat response: %w: %w", p.name, ErrInvalidResponse, err)
+	}
+	if len(cr.Choices) == 0 {
+		return nil, fmt.Errorf("%s :: error no choices in chat response: %w", p.name, ErrInvalidResponse)
+	}
+	return &cr, nil
+}
+
+// setAuth sends apiKey as a bearer token, or raw in a custom header such as
+// api-key.
+func setAuth(req *http.Request, header string, apiKey string) {
+	if strings.EqualFold(header, "Authorization") {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		return
+	}
+	req.Header.Set(header, apiKey)
+}
@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/config"
+	"policy-match/internal/dto"
+	"testing"
+	"time"
+)
+
+func TestOpenAIProvider(t *testing.T) {
+	var got struct {
+		path   string
+		auth   string
+		origin string
+		model  string
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		got.path, got.auth, got.origin, got.model = r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("Origin"), req.Model
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "{}"}}], "usage": {"total_tokens": 12}}`))
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider(&config.Config{
+		LLMBaseURL:    srv.URL + "/v1/",
+		LLMAPIKey:     "server-key",
+		LLMAuthHeader: "Authorization",
+		LLMModel:      "test-model",
+		Origin:        "https://app.example.com",
+		LLMTimeout:    time.Second,
+	})
+	resp, err := p.Chat(context.Background(), ChatRequest{})
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "{}" || resp.Usage.TotalTokens != 12 {
+		t.Errorf("response = %+v", resp)
+	}
+	if got.path != "/v1/chat/completions" || got.auth != "Bearer server-key" || got.origin != "https://app.example.com" || got.model != "test-model" {
+		t.Errorf("request = %+v", got)
+	}
+
+	ctx := context.WithValue(context.Background(), dto.UserAPIKeyContext, "user-key")
+	if _, err := p.Chat(ctx, ChatRequest{Model: "other-model"}); err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if got.auth != "Bearer user-key" || got.model != "other-model" {
+		t.Errorf("request = %+v, want the caller's key and model", got)
+	}
+}
+
+func TestSetAuth(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	setAuth(req, "api-key", "secret")
+	if req.Header.Get("api-key") != "secret" || req.Header.Get("Authorization") != "" {
+		t.Errorf("headers = %v, want the raw key in api-key", req.Header)
+	}
+}
+
+func TestOpenAIProviderErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     string
+		want       error
+		retryAfter time.Duration
+	}{
+		{"rate limited", http.StatusTooManyRequests, "7", ErrRateLimited, 7 * time.Second},
+		{"unavailable", http.StatusServiceUnavailable, "", ErrProviderUnavailable, 0},
+		{"bad gateway", http.StatusBadGateway, "", ErrProviderUnavailable, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("Retry-After", tt.header)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			p := NewOpenAIProvider(&config.Config{LLMBaseURL: srv.URL, LLMTimeout: time.Second})
+			_, err := p.Chat(context.Background(), ChatRequest{})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if got := RetryAfter(err); got != tt.retryAfter {
+				t.Errorf("RetryAfter = %s, want %s", got, tt.retryAfter)
+			}
+		})
+	}
+
+	for name, body := range map[string]string{
+		"no choices": `{"choices": []}`,
+		"not json":   `<html>bad gateway</html>`,
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		p := NewOpenAIProvider(&config.Config{LLMBaseURL: srv.URL, LLMTimeout: time.Second})
+		if _, err := p.Chat(context.Background(), ChatRequest{}); !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("%s: err = %v, want ErrInvalidResponse", name, err)
+		}
+		srv.Close()
+	}
+
+	p := NewOpenAIProvider(&config.Config{LLMBaseURL: "http://127.0.0.1:1", LLMTimeout: time.Second, LLMConnectTimeout: time.Second})
+	if _, err := p.Chat(context.Background(), ChatRequest{}); !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("unreachable endpoint err = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := map[string]string{
+		"":             ProviderGroq,
+		ProviderGroq:   ProviderGroq,
+		ProviderOpenAI: ProviderOpenAI,
+		ProviderFake:   ProviderFake,
+	}
+	for name, want := range tests {
+		p, err := NewProvider(&config.Config{LLMProvider: name})
+		if err != nil {
+			t.Fatalf("newProvider(%q): %v", name, err)
+		}
+		if p.Name() != want {
+			t.Errorf("newProvider(%q).Name() = %q, want %q", name, p.Name(), want)
+		}
+	}
+	if _, err := NewProvider(&config.Config{LLMProvider: "unknown"}); err == nil {
+		t.Error("newProvider(unknown) succeeded, want an error")
+	}
+}
@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"policy-match/internal/config"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// errCircuitOpen is returned without calling the provider while the
+// breaker is open.
+var errCircuitOpen = fmt.Errorf("circuit open: %w", ErrProviderUnavailable)
+
+// ResilientProvider retries rate-limited and failed calls with jittered
+// exponential backoff and stops calling a provider that keeps failing.
+type ResilientProvider struct {
+	next      Provider
+	retries   int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	breaker   *breaker
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewResilientProvider(cfg *config.Config, next Provider) *ResilientProvider {
+	return &ResilientProvider{
+		next:      next,
+		retries:   cfg.LLMMaxRetries,
+		baseDelay: cfg.LLMRetryBaseDelay,
+		maxDelay:  cfg.LLMRetryMaxDelay,
+		breaker:   &breaker{threshold: cfg.LLMBreakerThreshold, cooldown: cfg.LLMBreakerCooldown},
+		sleep:     sleep,
+	}
+}
+
+func (p *ResilientProvider) Name() string {
+	return p.next.Name()
+}
+
+// Chat retries on ErrRateLimited and ErrProviderUnavailable. A Retry-After
+// hint replaces the computed delay; one longer than the maximum delay
+// ends the retries, since waiting that long would hold up the caller.
+func (p *ResilientProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := p.attempt(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if !retryable(err) || attempt >= p.retries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := p.backoff(attempt)
+		if hint := RetryAfter(err); hint > 0 {
+			if hint > p.maxDelay {
+				return nil, err
+			}
+			delay = hint
+		}
+		log.Warn().Err(err).Int("attempt", attempt+1).Dur("delay", delay).Msg(p.Name() + " :: retrying")
+		if err := p.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *ResilientProvider) attempt(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	trial, ok := p.breaker.allow()
+	if !ok {
+		return nil, fmt.Errorf("%s :: %w", p.Name(), errCircuitOpen)
+	}
+	resp, err := p.next.Chat(ctx, req)
+	// A cancelled call says nothing about the provider either way, so it
+	// only gives up the trial.
+	if ctx.Err() != nil {
+		p.breaker.release(trial)
+		return resp, err
+	}
+	// Only outages count against the breaker. A 429 is usually the
+	// caller's own key running out and says nothing about the provider.
+	p.breaker.record(err == nil || !errors.Is(err, ErrProviderUnavailable), trial)
+	return resp, err
+}
+
+// backoff is "full jitter": a random delay up to baseDelay*2^attempt,
+// capped at maxDelay, so clients that failed together spread out.
+func (p *ResilientProvider) backoff(attempt int) time.Duration {
+	ceiling := p.maxDelay
+	if attempt < 32 {
+		ceiling = min(p.baseDelay<<attempt, p.maxDelay)
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+func retryable(err error) bool {
+	if errors.Is(err, errCircuitOpen) {
+		return false
+	}
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrProviderUnavailable)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// breaker opens after threshold consecutive failures. Once cooldown has
+// passed it lets a single trial call through: success closes it, failure
+// opens it for another cooldown. A threshold of 0 disables it.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// allow reports whether a call may go ahead, and whether it is the trial
+// call of a half-open breaker.
+func (b *breaker) allow() (trial bool, ok bool) {
+	if b.threshold == 0 {
+		return false, true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false, true
+	}
+	if b.trial || time.Since(b.openedAt) < b.cooldown {
+		return false, false
+	}
+	b.trial = true
+	return true, true
+}
+
+// release gives up a trial call without recording a result, so the next
+// call after it becomes the trial instead.
+func (b *breaker) release(trial bool) {
+	if !trial {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *breaker) record(success bool, trial bool) {
+	if b.threshold == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+	if success {
+		if b.failures >= b.threshold {
+			log.Info().Msg("llm :: circuit closed")
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures == b.threshold || trial {
+		b.openedAt = time.Now()
+		log.Warn().Dur("cooldown", b.cooldown).Msg("llm :: circuit opened")
+	}
+}
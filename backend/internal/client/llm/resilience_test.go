@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestResilientProvider(next Provider, retries int, threshold int) *ResilientProvider {
+	return &ResilientProvider{
+		next:      next,
+		retries:   retries,
+		baseDelay: time.Millisecond,
+		maxDelay:  time.Second,
+		breaker:   &breaker{threshold: threshold, cooldown: time.Minute},
+		sleep:     func(ctx context.Context, d time.Duration) error { return ctx.Err() },
+	}
+}
+
+// failing answers with the given status codes in turn, then with "{}".
+func failing(codes ...int) *FakeProvider {
+	return NewFakeProvider(func(ChatRequest) (string, error) {
+		if len(codes) == 0 {
+			return "{}", nil
+		}
+		code := codes[0]
+		codes = codes[1:]
+		return "", &StatusError{Provider: ProviderFake, StatusCode: code}
+	})
+}
+
+func TestResilientProviderRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []int
+		retries  int
+		wantErr  error
+		attempts int
+	}{
+		{"succeeds after outages", []int{503, 502}, 3, nil, 3},
+		{"succeeds after rate limit", []int{429}, 3, nil, 2},
+		{"gives up after retries", []int{503, 503, 503}, 2, ErrProviderUnavailable, 3},
+		{"does not retry client errors", []int{400}, 3, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := failing(tt.codes...)
+			p := newTestResilientProvider(fake, tt.retries, 0)
+
+			_, err := p.Chat(context.Background(), ChatRequest{})
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && tt.attempts > 1 && err != nil {
+				t.Errorf("err = %v, want success", err)
+			}
+			if n := len(fake.Requests()); n != tt.attempts {
+				t.Errorf("attempts = %d, want %d", n, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestResilientProviderRetryAfterTooLong(t *testing.T) {
+	fake := NewFakeProvider(func(ChatRequest) (string, error) {
+		return "", &StatusError{Provider: ProviderFake, StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}
+	})
+	p := newTestResilientProvider(fake, 3, 0)
+
+	if _, err := p.Chat(context.Background(), ChatRequest{}); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Errorf("attempts = %d, want 1", n)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	fake := failing(503, 503, 503)
+	p := newTestResilientProvider(fake, 0, 2)
+
+	for range 2 {
+		if _, err := p.Chat(ctx, ChatRequest{}); !errors.Is(err, ErrProviderUnavailable) {
+			t.Fatalf("err = %v, want ErrProviderUnavailable", err)
+		}
+	}
+	if _, err := p.Chat(ctx, ChatRequest{}); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("err = %v, want errCircuitOpen", err)
+	}
+	if n := len(fake.Requests()); n != 2 {
+		t.Fatalf("open breaker let %d calls through, want 2", n)
+	}
+
+	// A failed trial opens the breaker for another cooldown.
+	p.breaker.openedAt = time.Now().Add(-time.Minute)
+	if _, err := p.Chat(ctx, ChatRequest{}); !errors.Is(err, ErrProviderUnavailable) || errors.Is(err, errCircuitOpen) {
+		t.Fatalf("trial err = %v, want a provider failure", err)
+	}
+	if _, err := p.Chat(ctx, ChatRequest{}); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("after failed trial err = %v, want errCircuitOpen", err)
+	}
+
+	// A successful trial closes it.
+	p.breaker.openedAt = time.Now().Add(-time.Minute)
+	if _, err := p.Chat(ctx, ChatRequest{}); err != nil {
+		t.Fatalf("trial err = %v, want success", err)
+	}
+	if _, err := p.Chat(ctx, ChatRequest{}); err != nil {
+		t.Fatalf("after successful trial err = %v, want success", err)
+	}
+}
+
+func TestBreakerIgnoresRateLimits(t *testing.T) {
+	p := newTestResilientProvider(failing(429, 429, 429), 0, 2)
+	for range 3 {
+		if _, err := p.Chat(context.Background(), ChatRequest{}); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("err = %v, want ErrRateLimited", err)
+		}
+	}
+}
+
+func TestBreakerCancelledTrial(t *testing.T) {
+	p := newTestResilientProvider(failing(503, 503, 503), 0, 2)
+	for range 2 {
+		p.Chat(context.Background(), ChatRequest{})
+	}
+	p.breaker.openedAt = time.Now().Add(-time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Chat(ctx, ChatRequest{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	// The cancelled trial neither closed the breaker nor kept the trial
+	// slot, so the next call is the trial.
+	trial, ok := p.breaker.allow()
+	if !ok || !trial {
+		t.Fatalf("allow() = %v, %v, want a trial call", trial, ok)
+	}
+}
@@ -0,0 +1,147 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"policy-match/internal/repository"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// selectRules picks the rules sent with each chunk. Policies with at most
+// topK rules send every rule; larger ones send the topK rules whose
+// embeddings are closest to the chunk, in policy order. If embedding fails
+// every chunk falls back to the full rule set.
+//
+// Ranking is an exact cosine scan over the policy's rules in process; the
+// stored vectors are opaque bytes with no vector index, which is enough
+// for the rule count of a single policy but not for search across them.
+func (l *LLMClient) selectRules(ctx context.Context, policyRules []repository.Rule, chunks []Chunk) [][]repository.Rule {
+	selected := make([][]repository.Rule, len(chunks))
+	for i := range selected {
+		selected[i] = policyRules
+	}
+
+	topK := l.cfg.RuleTopK
+	if l.embedder == nil || topK <= 0 || len(policyRules) <= topK {
+		return selected
+	}
+
+	ruleVectors, err := l.ruleVectors(ctx, policyRules)
+	if err != nil {
+		log.Warn().Err(err).Msg("selectRules :: sending every rule")
+		return selected
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	chunkVectors, err := l.embedder.Embed(ctx, texts)
+	if err != nil {
+		log.Warn().Err(err).Msg("selectRules :: sending every rule")
+		return selected
+	}
+
+	for i, cv := range chunkVectors {
+		order := make([]int, len(policyRules))
+		scores := make([]float64, len(policyRules))
+		for j := range policyRules {
+			order[j] = j
+			scores[j] = cosine(cv, ruleVectors[j])
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return scores[order[a]] > scores[order[b]]
+		})
+		top := order[:topK]
+		sort.Ints(top)
+
+		rules := make([]repository.Rule, len(top))
+		for k, j := range top {
+			rules[k] = policyRules[j]
+		}
+		selected[i] = rules
+	}
+	return selected
+}
+
+// ruleVectors returns one embedding per rule, reusing stored embeddings
+// whose text is unchanged and storing the ones it computes.
+func (l *LLMClient) ruleVectors(ctx context.Context, policyRules []repository.Rule) ([][]float32, error) {
+	model := l.embedder.Model()
+
+	ids := make([]uuid.UUID, len(policyRules))
+	for i, rule := range policyRules {
+		ids[i] = rule.ID
+	}
+	stored, err := l.repo.GetRuleEmbeddings(ctx, model, ids)
+	if err != nil {
+		return nil, fmt.Errorf("ruleVectors :: getRuleEmbeddings: %w", err)
+	}
+	cached := make(map[uuid.UUID]repository.RuleEmbedding, len(stored))
+	for _, e := range stored {
+		cached[e.RuleID] = e
+	}
+
+	vectors := make([][]float32, len(policyRules))
+	var missing []int
+	var texts []string
+	for i, rule := range policyRules {
+		if e, ok := cached[rule.ID]; ok && e.TextHash == textHash(rule.RuleText) {
+			vectors[i] = e.Vector
+			continue
+		}
+		missing = append(missing, i)
+		texts = append(texts, rule.RuleText)
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	computed, err := l.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("ruleVectors :: embed: %w", err)
+	}
+
+	embeddings := make([]repository.RuleEmbedding, len(missing))
+	for k, i := range missing {
+		vectors[i] = computed[k]
+		embeddings[k] = repository.RuleEmbedding{
+			RuleID:   policyRules[i].ID,
+			Model:    model,
+			TextHash: textHash(policyRules[i].RuleText),
+			Vector:   computed[k],
+		}
+	}
+	// A failed write only costs re-embedding on the next check.
+	if err := l.repo.SaveRuleEmbeddings(ctx, embeddings); err != nil {
+		log.Warn().Err(err).Msg("ruleVectors :: saveRuleEmbeddings")
+	}
+	return vectors, nil
+}
+
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
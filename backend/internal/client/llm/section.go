@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Section is the slice of a policy sent in one rule-extraction request.
+// Its parts are consecutive blocks of the policy, each under its own
+// heading breadcrumb.
+type Section struct {
+	Index int
+	Parts []Part
+}
+
+// Part is policy text under one heading. Breadcrumb lists the headings
+// that contain it, outermost first; it is empty for text before the first
+// heading.
+type Part struct {
+	Breadcrumb []string
+	Text       string
+}
+
+var (
+	markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(\S.*)$`)
+	keywordHeading  = regexp.MustCompile(`(?i)^(part|chapter|article|section|appendix|annex|schedule)\s+([0-9]+[a-z]?|[ivxlc]+|[a-z])\b`)
+	numberedHeading = regexp.MustCompile(`^(\d+(?:\.\d+)*)[.)]?\s+(\S.*)$`)
+)
+
+// headingLevel reports whether line is a section heading and how deep it
+// is: parts and chapters are 1, articles and sections 2, and numbered
+// headings nest below them by the depth of their numbering. ALL CAPS
+// lines count as top-level headings.
+func headingLevel(line string) (int, string, bool) {
+	if m := markdownHeading.FindStringSubmatch(line); m != nil {
+		return len(m[1]), strings.TrimSpace(m[2]), true
+	}
+	if len(line) > 100 {
+		return 0, "", false
+	}
+	if m := keywordHeading.FindStringSubmatch(line); m != nil && !strings.ContainsAny(line[len(line)-1:], ".;,") {
+		switch strings.ToLower(m[1]) {
+		case "part", "chapter":
+			return 1, line, true
+		default:
+			return 2, line, true
+		}
+	}
+	if m := numberedHeading.FindStringSubmatch(line); m != nil && isTitle(m[2]) {
+		return 2 + strings.Count(m[1], "."), line, true
+	}
+	if isAllCaps(line) {
+		return 1, line, true
+	}
+	return 0, "", false
+}
+
+// isTitle tells a short heading such as "Access Control" from a numbered
+// clause, which reads as a sentence.
+func isTitle(text string) bool {
+	words := strings.Fields(text)
+	if len(words) == 0 || len(words) > 8 {
+		return false
+	}
+	first := []rune(words[0])[0]
+	if !unicode.IsUpper(first) && !unicode.IsDigit(first) {
+		return false
+	}
+	return !strings.ContainsAny(text[len(text)-1:], ".;,")
+}
+
+func isAllCaps(line string) bool {
+	letters := 0
+	for _, r := range line {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters >= 3 && len(strings.Fields(line)) <= 10
+}
+
+// SplitPolicy splits policy text at its headings and packs consecutive
+// blocks into sections of at most maxTokens estimated tokens. A block
+// larger than that is split further by SplitDocument and keeps its
+// breadcrumb.
+func SplitPolicy(text string, maxTokens int) []Section {
+	type heading struct {
+		level int
+		title string
+	}
+	var (
+		parts []Part
+		trail []heading
+		block strings.Builder
+	)
+	breadcrumb := func() []string {
+		out := make([]string, len(trail))
+		for i, h := range trail {
+			out[i] = h.title
+		}
+		return out
+	}
+	flush := func() {
+		if strings.TrimSpace(block.String()) == "" {
+			block.Reset()
+			return
+		}
+		crumbs := breadcrumb()
+		for _, chunk := range SplitDocument(block.String(), maxTokens) {
+			parts = append(parts, Part{Breadcrumb: crumbs, Text: strings.TrimSpace(chunk.Text)})
+		}
+		block.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if level, title, ok := headingLevel(strings.TrimSpace(line)); ok {
+			flush()
+			for len(trail) > 0 && trail[len(trail)-1].level >= level {
+				trail = trail[:len(trail)-1]
+			}
+			trail = append(trail, heading{level: level, title: title})
+		}
+		block.WriteString(line)
+	}
+	flush()
+
+	maxChars := maxTokens * charsPerToken
+	var sections []Section
+	size := 0
+	for _, part := range parts {
+		if len(sections) == 0 || (maxChars > 0 && size+len(part.Text) > maxChars) {
+			sections = append(sections, Section{Index: len(sections)})
+			size = 0
+		}
+		last := &sections[len(sections)-1]
+		last.Parts = append(last.Parts, part)
+		size += len(part.Text)
+	}
+	return sections
+}
+
+// breadcrumbFor returns the breadcrumb of the 1-based part the model
+// attributed a rule to. Out of range, it falls back to the headings every
+// part of the section shares.
+func (s Section) breadcrumbFor(part int) []string {
+	if part >= 1 && part <= len(s.Parts) {
+		return s.Parts[part-1].Breadcrumb
+	}
+	common := s.Parts[0].Breadcrumb
+	for _, p := range s.Parts[1:] {
+		n := 0
+		for n < len(common) && n < len(p.Breadcrumb) && common[n] == p.Breadcrumb[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	return common
+}
@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHeadingLevel(t *testing.T) {
+	tests := []struct {
+		line  string
+		level int
+		title string
+		ok    bool
+	}{
+		{"# Access Policy", 1, "Access Policy", true},
+		{"### Passwords", 3, "Passwords", true},
+		{"Part I", 1, "Part I", true},
+		{"CHAPTER 2 Data Handling", 1, "CHAPTER 2 Data Handling", true},
+		{"Article 5", 2, "Article 5", true},
+		{"Section 3a Retention", 2, "Section 3a Retention", true},
+		{"Section 3 applies to all staff.", 0, "", false},
+		{"1. Scope", 2, "1. Scope", true},
+		{"2.1 Access Control", 3, "2.1 Access Control", true},
+		{"2.1.4) Remote Access", 4, "2.1.4) Remote Access", true},
+		{"1. Employees must lock their screens when away.", 0, "", false},
+		{"1. employees", 0, "", false},
+		{"ACCEPTABLE USE", 1, "ACCEPTABLE USE", true},
+		{"NDA", 1, "NDA", true},
+		{"OK", 0, "", false},
+		{"All staff must wear badges.", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			level, title, ok := headingLevel(tt.line)
+			if level != tt.level || title != tt.title || ok != tt.ok {
+				t.Errorf("headingLevel(%q) = %d, %q, %v, want %d, %q, %v", tt.line, level, title, ok, tt.level, tt.title, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSplitPolicy(t *testing.T) {
+	text := `Acme Security Policy applies to everyone.
+
+Part I
+1. Scope
+Staff must wear badges.
+2. Access Control
+Passwords must be rotated.
+2.1 Remote Access
+VPN must be used.
+Part II
+1. Scope
+Contractors must sign an NDA.
+`
+	sections := SplitPolicy(text, 0)
+	if len(sections) != 1 {
+		t.Fatalf("got %d sections, want 1 without a token limit", len(sections))
+	}
+
+	want := [][]string{
+		{},
+		{"Part I"},
+		{"Part I", "1. Scope"},
+		{"Part I", "2. Access Control"},
+		{"Part I", "2. Access Control", "2.1 Remote Access"},
+		{"Part II"},
+		{"Part II", "1. Scope"},
+	}
+	var got [][]string
+	for _, part := range sections[0].Parts {
+		got = append(got, part.Breadcrumb)
+	}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("breadcrumbs = %q, want %q", got, want)
+	}
+	if text := sections[0].Parts[6].Text; !strings.Contains(text, "NDA") {
+		t.Errorf("last part = %q, want the Part II scope", text)
+	}
+}
+
+func TestSplitPolicyPacksSections(t *testing.T) {
+	var b strings.Builder
+	for _, heading := range []string{"Part I", "Part II", "Part III", "Part IV"} {
+		b.WriteString(heading + "\n" + strings.Repeat("Staff must wear badges. ", 20) + "\n")
+	}
+	sections := SplitPolicy(b.String(), 300)
+	if len(sections) < 2 {
+		t.Fatalf("got %d sections, want the policy split", len(sections))
+	}
+	var parts int
+	for i, section := range sections {
+		if section.Index != i {
+			t.Errorf("section %d has index %d", i, section.Index)
+		}
+		size := 0
+		for _, part := range section.Parts {
+			size += len(part.Text)
+		}
+		if len(section.Parts) > 1 && size > 300*charsPerToken {
+			t.Errorf("section %d holds %d chars, over the limit", i, size)
+		}
+		parts += len(section.Parts)
+	}
+	if parts != 4 {
+		t.Errorf("got %d parts, want 4", parts)
+	}
+}
+
+func TestSplitPolicyEmpty(t *testing.T) {
+	if sections := SplitPolicy("  \n\n", 100); len(sections) != 0 {
+		t.Errorf("got %d sections for blank text, want 0", len(sections))
+	}
+}
+
+func TestBreadcrumbFor(t *testing.T) {
+	section := Section{Parts: []Part{
+		{Breadcrumb: []string{"Part I", "1. Scope"}},
+		{Breadcrumb: []string{"Part I", "2. Access"}},
+	}}
+	if got := section.breadcrumbFor(2); !slices.Equal(got, []string{"Part I", "2. Access"}) {
+		t.Errorf("breadcrumbFor(2) = %q", got)
+	}
+	if got := section.breadcrumbFor(9); !slices.Equal(got, []string{"Part I"}) {
+		t.Errorf("breadcrumbFor(9) = %q, want the shared prefix", got)
+	}
+}
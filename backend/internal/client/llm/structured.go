@@ -0,0 +1,280 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OutputPath records how a structured response was obtained.
+type OutputPath string
+
+const (
+	// OutputClean means the first reply matched the schema as sent.
+	OutputClean OutputPath = "clean"
+	// OutputRepaired means the first reply was wrapped in Markdown fences
+	// or extra text, or was cut off, and matched the schema once the
+	// wrapping was stripped or the open values closed.
+	OutputRepaired OutputPath = "repaired"
+	// OutputRetried means the model had to be asked again.
+	OutputRetried OutputPath = "retried"
+)
+
+var outputPathRank = map[OutputPath]int{
+	OutputClean:    0,
+	OutputRepaired: 1,
+	OutputRetried:  2,
+}
+
+// worseOutputPath returns the less clean of a and b, for results merged
+// from several calls.
+func worseOutputPath(a, b OutputPath) OutputPath {
+	if outputPathRank[b] > outputPathRank[a] {
+		return b
+	}
+	return a
+}
+
+const outputRetryPrompt = `Your previous response was rejected: %s.
+Respond again with only the complete JSON object, following the schema exactly.`
+
+// decodeStructured checks reply against schema and returns the JSON to
+// decode. A reply that does not parse is repaired first; repaired reports
+// whether that was needed.
+func decodeStructured(reply string, schema map[string]any) (raw []byte, repaired bool, err error) {
+	raw = bytes.TrimSpace([]byte(reply))
+
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		fixed, ok := repairJSON(raw)
+		if !ok {
+			return nil, false, fmt.Errorf("response is not valid JSON (%v)", err)
+		}
+		if err := json.Unmarshal(fixed, &value); err != nil {
+			return nil, false, fmt.Errorf("response is not valid JSON (%v)", err)
+		}
+		raw, repaired = fixed, true
+	}
+
+	if err := validateSchema(schema, value, "$"); err != nil {
+		if repaired {
+			return nil, true, fmt.Errorf("response was not valid JSON, and after repairing it %v", err)
+		}
+		return nil, false, err
+	}
+	return raw, repaired, nil
+}
+
+// schemaOf returns the JSON schema sent with req in its decoded form, so
+// replies are validated against exactly what the model was given.
+func schemaOf(req ChatRequest) (map[string]any, error) {
+	b, err := json.Marshal(req.ResponseFormat.JsonSchema.Schema)
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// repairJSON recovers the JSON value in raw when the model wrapped it in
+// Markdown fences or followed it with text, or closes it when the reply
+// was cut off; see closeJSON. The caller still validates the result
+// against the schema, which rejects a value missing required fields.
+func repairJSON(raw []byte) ([]byte, bool) {
+	start := bytes.IndexAny(raw, "{[")
+	if start < 0 {
+		return nil, false
+	}
+	raw = raw[start:]
+
+	var (
+		stack    []byte
+		inString bool
+		escaped  bool
+	)
+	for i, c := range raw {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, c)
+		case '}', ']':
+			open := byte('{')
+			if c == ']' {
+				open = '['
+			}
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				return nil, false
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return raw[:i+1], true
+			}
+		}
+	}
+	return closeJSON(raw, stack, inString, escaped)
+}
+
+// closeJSON closes the strings, arrays and objects left open in a value
+// that was cut off, dropping a trailing comma. It only does so when the
+// cut falls inside a string or right after a complete value, comma or
+// opening bracket: a cut inside a number or literal, or after a key,
+// would have the closed value invent or change data, so it fails and the
+// model is asked again.
+func closeJSON(raw []byte, stack []byte, inString bool, escaped bool) ([]byte, bool) {
+	fixed := bytes.Clone(raw)
+	if inString {
+		if escaped {
+			fixed = fixed[:len(fixed)-1]
+		}
+		fixed = append(fixed, '"')
+	}
+
+	fixed = bytes.TrimRight(fixed, " \t\r\n")
+	switch fixed[len(fixed)-1] {
+	case ',':
+		fixed = fixed[:len(fixed)-1]
+	case '"', '{', '[', '}', ']':
+	default:
+		return nil, false
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			fixed = append(fixed, '}')
+		} else {
+			fixed = append(fixed, ']')
+		}
+	}
+	// A closed key, such as {"a": 1, "b"}, or a cut escape sequence
+	// still does not parse.
+	if !json.Valid(fixed) {
+		return nil, false
+	}
+	return fixed, true
+}
+
+// validateSchema checks value against the subset of JSON Schema the
+// client sends: type, properties, required, additionalProperties, items
+// and enum.
+func validateSchema(schema map[string]any, value any, path string) error {
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return fmt.Errorf("%s must be one of %s, got %s", path, joinJSON(enum), jsonString(value))
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object, got %s", path, jsonType(value))
+		}
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, ok := obj[name.(string)]; !ok {
+					return fmt.Errorf("%s.%s is required", path, name)
+				}
+			}
+		}
+		for _, name := range sortedKeys(obj) {
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s.%s is not allowed", path, name)
+				}
+				continue
+			}
+			if err := validateSchema(prop, obj[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array, got %s", path, jsonType(value))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range arr {
+				if err := validateSchema(items, item, path+"["+strconv.Itoa(i)+"]"); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s must be a string, got %s", path, jsonType(value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s must be a number, got %s", path, jsonType(value))
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s must be an integer, got %s", path, jsonType(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean, got %s", path, jsonType(value))
+		}
+	}
+	return nil
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func jsonString(value any) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+func joinJSON(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = jsonString(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,147 @@
+package llm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+		ok   bool
+	}{
+		{"fenced", "```json\n{\"rules\": []}\n```", `{"rules": []}`, true},
+		{"leading text", `Here you go: {"a": 1}`, `{"a": 1}`, true},
+		{"trailing text", `{"a": "}"} hope this helps`, `{"a": "}"}`, true},
+		{"escaped quote", `{"a": "say \"}\""} done`, `{"a": "say \"}\""}`, true},
+		{"top-level array", "[1, 2] extra", "[1, 2]", true},
+		{"truncated array", `{"rules": [{"rule_id": "1"}, {"rule_id": "2"`, `{"rules": [{"rule_id": "1"}, {"rule_id": "2"}]}`, true},
+		{"truncated object", `{"rules": []`, `{"rules": []}`, true},
+		{"truncated string", `{"a": "unfinished`, `{"a": "unfinished"}`, true},
+		{"truncated escape", `{"a": "say \`, `{"a": "say "}`, true},
+		{"truncated after comma", "```json\n{\"rules\": [{\"rule_id\": \"1\"},\n ", `{"rules": [{"rule_id": "1"}]}`, true},
+		{"truncated after open bracket", `{"rules": [`, `{"rules": []}`, true},
+		{"truncated key", `{"a": 1, "rul`, "", false},
+		{"truncated after key", `{"a": 1, "rules"`, "", false},
+		{"truncated after colon", `{"a": 1, "rules": `, "", false},
+		{"truncated number", `{"a": 0.`, "", false},
+		{"truncated literal", `{"a": tr`, "", false},
+		{"truncated unicode escape", `{"a": "\u00`, "", false},
+		{"mismatched brackets", `{"a": [1}`, "", false},
+		{"no JSON", "sorry, I cannot help", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := repairJSON([]byte(tt.raw))
+			if ok != tt.ok || string(got) != tt.want {
+				t.Errorf("repairJSON() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func testSchema(t *testing.T) map[string]any {
+	t.Helper()
+	var schema map[string]any
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["rules"],
+		"additionalProperties": false,
+		"properties": {
+			"rules": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"required": ["rule_id", "severity"],
+					"properties": {
+						"rule_id": {"type": "string"},
+						"severity": {"type": "string", "enum": ["critical", "major", "minor"]},
+						"weight": {"type": "number"},
+						"page": {"type": "integer"},
+						"optional": {"type": "boolean"}
+					}
+				}
+			}
+		}
+	}`), &schema)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return schema
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := testSchema(t)
+	tests := []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{"valid", `{"rules": [{"rule_id": "1", "severity": "major", "weight": 1.5, "page": 2, "optional": false}]}`, ""},
+		{"empty list", `{"rules": []}`, ""},
+		{"not an object", `[]`, "$ must be an object, got an array"},
+		{"missing required", `{}`, "$.rules is required"},
+		{"additional property", `{"rules": [], "extra": 1}`, "$.extra is not allowed"},
+		{"not an array", `{"rules": {}}`, "$.rules must be an array, got an object"},
+		{"item missing required", `{"rules": [{"rule_id": "1"}]}`, "$.rules[0].severity is required"},
+		{"enum", `{"rules": [{"rule_id": "1", "severity": "high"}]}`, `$.rules[0].severity must be one of "critical", "major", "minor", got "high"`},
+		{"string", `{"rules": [{"rule_id": 1, "severity": "major"}]}`, "$.rules[0].rule_id must be a string, got a number"},
+		{"number", `{"rules": [{"rule_id": "1", "severity": "major", "weight": "1"}]}`, "$.rules[0].weight must be a number, got a string"},
+		{"integer", `{"rules": [{"rule_id": "1", "severity": "major", "page": 1.5}]}`, "$.rules[0].page must be an integer, got a number"},
+		{"boolean", `{"rules": [{"rule_id": "1", "severity": "major", "optional": null}]}`, "$.rules[0].optional must be a boolean, got null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value any
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatalf("value: %v", err)
+			}
+			err := validateSchema(schema, value, "$")
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("validateSchema() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("validateSchema() = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDecodeStructured(t *testing.T) {
+	schema := testSchema(t)
+	tests := []struct {
+		name     string
+		reply    string
+		repaired bool
+		err      string
+	}{
+		{"clean", `{"rules": [{"rule_id": "1", "severity": "major"}]}`, false, ""},
+		{"fenced", "```json\n{\"rules\": [{\"rule_id\": \"1\", \"severity\": \"major\"}]}\n```", true, ""},
+		{"truncated between rules", `{"rules": [{"rule_id": "1", "severity": "major"}, `, true, ""},
+		{"truncated rule", `{"rules": [{"rule_id": "1", "severity": "major"}, {"rule_id": "2"`, true, "after repairing it $.rules[1].severity is required"},
+		{"truncated value", `{"rules": [{"rule_id": "1", "severity": "maj`, true, "after repairing it $.rules[0].severity must be one of"},
+		{"truncated number", `{"rules": [{"rule_id": "1", "severity": "major", "weight": 1`, false, "not valid JSON"},
+		{"invalid", `{"rules": [{"rule_id": "1"}]}`, false, "severity is required"},
+		{"invalid after repair", "```json\n{\"rules\": {}}\n```", true, "after repairing it"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repaired, err := decodeStructured(tt.reply, schema)
+			if repaired != tt.repaired {
+				t.Errorf("repaired = %v, want %v", repaired, tt.repaired)
+			}
+			if tt.err == "" && err != nil {
+				t.Errorf("err = %v, want nil", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
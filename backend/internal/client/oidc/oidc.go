@@ -0,0 +1,193 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"policy-match/internal/config"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken covers every reason to reject a token: bad signature,
+// wrong issuer or audience, expiry, or missing claims.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Identity is what a verified token says about the caller.
+type Identity struct {
+	Subject string
+	Email   string
+	Name    string
+	// WorkspaceID is nil when the token has no workspace claim.
+	WorkspaceID *uuid.UUID
+	Roles       []string
+}
+
+// Verifier validates OIDC access and ID tokens issued by one issuer.
+type Verifier struct {
+	config *config.Config
+	keys   *keySet
+	now    func() time.Time
+}
+
+func NewVerifier(config *config.Config) *Verifier {
+	return &Verifier{
+		config: config,
+		keys: &keySet{
+			issuer: config.OIDCIssuer,
+			url:    config.OIDCJWKSURL,
+			ttl:    config.OIDCJWKSCacheTTL,
+			client: &http.Client{Timeout: 10 * time.Second},
+		},
+		now: time.Now,
+	}
+}
+
+// LooksLikeJWT reports whether token has the three dot-separated parts of
+// a compact JWS, which tells it apart from an API key.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify checks the token's signature against the issuer's JWKS, then its
+// issuer, audience and validity window, allowing OIDCClockSkew either way.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Identity, error) {
+	token, err := parseJWT(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	key, err := v.keys.key(ctx, token.header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("verify :: %w", err)
+	}
+	if key.alg != "" && key.alg != token.header.Alg {
+		return nil, fmt.Errorf("%w: alg %s does not match key", ErrInvalidToken, token.header.Alg)
+	}
+	if err := verifySignature(token.header.Alg, key.key, token.signed, token.signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if err := v.validate(token.claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	identity, err := v.identity(token.claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return identity, nil
+}
+
+func (v *Verifier) validate(claims map[string]any) error {
+	if iss, _ := claims["iss"].(string); iss != v.config.OIDCIssuer {
+		return fmt.Errorf("issuer %q", iss)
+	}
+	if !hasAudience(claims["aud"], v.config.OIDCAudience) {
+		return errors.New("audience does not match")
+	}
+
+	now, skew := v.now(), v.config.OIDCClockSkew
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return errors.New("missing exp")
+	}
+	if now.After(exp.Add(skew)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Before(nbf.Add(-skew)) {
+		return errors.New("token not valid yet")
+	}
+	if iat, ok := numericDate(claims["iat"]); ok && now.Before(iat.Add(-skew)) {
+		return errors.New("token issued in the future")
+	}
+	return nil
+}
+
+// identity maps the configured claims onto an Identity.
+func (v *Verifier) identity(claims map[string]any) (*Identity, error) {
+	identity := &Identity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claim(claims, v.config.OIDCEmailClaim).(string)
+	identity.Name, _ = claim(claims, v.config.OIDCNameClaim).(string)
+	if identity.Email == "" {
+		return nil, fmt.Errorf("missing %s claim", v.config.OIDCEmailClaim)
+	}
+	if v.config.OIDCRequireVerifiedEmail && !emailVerified(claims["email_verified"]) {
+		return nil, errors.New("email is not verified")
+	}
+
+	if raw, ok := claim(claims, v.config.OIDCWorkspaceClaim).(string); ok && raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s claim: %w", v.config.OIDCWorkspaceClaim, err)
+		}
+		identity.WorkspaceID = &id
+	}
+
+	switch roles := claim(claims, v.config.OIDCRolesClaim).(type) {
+	case string:
+		identity.Roles = strings.Fields(roles)
+	case []any:
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				identity.Roles = append(identity.Roles, s)
+			}
+		}
+	}
+	return identity, nil
+}
+
+// claim reads a claim by name; a dotted name walks nested objects.
+func claim(claims map[string]any, name string) any {
+	var v any = claims
+	for _, part := range strings.Split(name, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+// emailVerified accepts the boolean the spec requires and the "true"
+// string some issuers send instead.
+func emailVerified(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+func hasAudience(aud any, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func numericDate(v any) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(f*float64(time.Second))), true
+}
@@ -0,0 +1,376 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/config"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testAudience = "policy-match"
+
+// testIssuer serves discovery and a JWKS for the keys it currently holds,
+// counting JWKS fetches.
+type testIssuer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches atomic.Int32
+}
+
+func newTestIssuer(t *testing.T, kids ...string) *testIssuer {
+	t.Helper()
+	issuer := &testIssuer{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		issuer.addKey(t, kid)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.URL,
+			"jwks_uri": issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.fetches.Add(1)
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		keys := []map[string]string{}
+		for kid, key := range issuer.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func (i *testIssuer) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keys[kid] = key
+	return key
+}
+
+func (i *testIssuer) key(kid string) *rsa.PrivateKey {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.keys[kid]
+}
+
+func (i *testIssuer) verifier() *Verifier {
+	return NewVerifier(&config.Config{
+		OIDCIssuer:               i.URL,
+		OIDCAudience:             testAudience,
+		OIDCClockSkew:            time.Minute,
+		OIDCJWKSCacheTTL:         time.Hour,
+		OIDCEmailClaim:           "email",
+		OIDCNameClaim:            "name",
+		OIDCWorkspaceClaim:       "workspace_id",
+		OIDCRolesClaim:           "roles",
+		OIDCRequireVerifiedEmail: true,
+	})
+}
+
+// claims returns valid claims for the issuer, overridden by extra.
+func (i *testIssuer) claims(extra map[string]any) map[string]any {
+	now := time.Now()
+	claims := map[string]any{
+		"iss":            i.URL,
+		"aud":            testAudience,
+		"sub":            "user-1",
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"name":           "Ada",
+		"roles":          []string{"admin"},
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+func segment(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// sign builds an RS256 token signed with key.
+func sign(t *testing.T, key *rsa.PrivateKey, header map[string]any, claims map[string]any) string {
+	t.Helper()
+	signed := segment(t, header) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerify(t *testing.T) {
+	issuer := newTestIssuer(t, "k1")
+	key := issuer.key("k1")
+	header := map[string]any{"alg": "RS256", "kid": "k1"}
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		token func() string
+		valid bool
+	}{
+		{
+			name:  "valid",
+			token: func() string { return sign(t, key, header, issuer.claims(nil)) },
+			valid: true,
+		},
+		{
+			name: "bad signature",
+			token: func() string {
+				other, _ := rsa.GenerateKey(rand.Reader, 2048)
+				return sign(t, other, header, issuer.claims(nil))
+			},
+		},
+		{
+			name: "tampered claims",
+			token: func() string {
+				parts := strings.Split(sign(t, key, header, issuer.claims(nil)), ".")
+				parts[1] = segment(t, issuer.claims(map[string]any{"roles": []string{"owner"}}))
+				return strings.Join(parts, ".")
+			},
+		},
+		{
+			name: "alg none",
+			token: func() string {
+				return segment(t, map[string]any{"alg": "none", "kid": "k1"}) + "." + segment(t, issuer.claims(nil)) + "."
+			},
+		},
+		{
+			name: "alg does not match key",
+			token: func() string {
+				return sign(t, key, map[string]any{"alg": "PS256", "kid": "k1"}, issuer.claims(nil))
+			},
+		},
+		{
+			name: "alg HS256",
+			token: func() string {
+				return segment(t, map[string]any{"alg": "HS256", "kid": "k1"}) + "." + segment(t, issuer.claims(nil)) + ".c2ln"
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				return sign(t, key, header, issuer.claims(map[string]any{"iss": "https://evil.example.com"}))
+			},
+		},
+		{
+			name:  "wrong audience",
+			token: func() string { return sign(t, key, header, issuer.claims(map[string]any{"aud": "other"})) },
+		},
+		{
+			name: "audience list",
+			token: func() string {
+				return sign(t, key, header, issuer.claims(map[string]any{"aud": []string{"other", testAudience}}))
+			},
+			valid: true,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				return sign(t, key, header, issuer.claims(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()}))
+			},
+		},
+		{
+			name: "expired within skew",
+			token: func() string {
+				return sign(t, key, header, issuer.claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}))
+			},
+			valid: true,
+		},
+		{
+			name:  "missing exp",
+			token: func() string { return sign(t, key, header, issuer.claims(map[string]any{"exp": nil})) },
+		},
+		{
+			name: "not valid yet",
+			token: func() string {
+				return sign(t, key, header, issuer.claims(map[string]any{"nbf": now.Add(2 * time.Minute).Unix()}))
+			},
+		},
+		{
+			name: "not valid yet within skew",
+			token: func() string {
+				return sign(t, key, header, issuer.claims(map[string]any{"nbf": now.Add(30 * time.Second).Unix()}))
+			},
+			valid: true,
+		},
+		{
+			name: "issued in the future",
+			token: func() string {
+				return sign(t, key, header, issuer.claims(map[string]any{"iat": now.Add(2 * time.Minute).Unix()}))
+			},
+		},
+		{
+			name:  "unverified email",
+			token: func() string { return sign(t, key, header, issuer.claims(map[string]any{"email_verified": false})) },
+		},
+		{
+			name:  "missing email_verified",
+			token: func() string { return sign(t, key, header, issuer.claims(map[string]any{"email_verified": nil})) },
+		},
+		{
+			name:  "missing email",
+			token: func() string { return sign(t, key, header, issuer.claims(map[string]any{"email": nil})) },
+		},
+		{
+			name:  "malformed",
+			token: func() string { return "not.a.token" },
+		},
+	}
+
+	verifier := issuer.verifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := verifier.Verify(context.Background(), tt.token())
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("verify: %v", err)
+				}
+				if identity.Email != "ada@example.com" || identity.Subject != "user-1" {
+					t.Errorf("identity = %+v", identity)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("verify returned %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIdentityClaims(t *testing.T) {
+	issuer := newTestIssuer(t, "k1")
+	verifier := issuer.verifier()
+	verifier.config.OIDCRolesClaim = "realm_access.roles"
+
+	token := sign(t, issuer.key("k1"), map[string]any{"alg": "RS256", "kid": "k1"}, issuer.claims(map[string]any{
+		"roles":          nil,
+		"realm_access":   map[string]any{"roles": []string{"offline_access", "reviewer"}},
+		"workspace_id":   "6f1c1b52-8d0e-4a4b-9a57-2b1d5cf2d8b1",
+		"email_verified": "true",
+	}))
+	identity, err := verifier.Verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(identity.Roles) != 2 || identity.Roles[1] != "reviewer" {
+		t.Errorf("roles = %v", identity.Roles)
+	}
+	if identity.WorkspaceID == nil || identity.WorkspaceID.String() != "6f1c1b52-8d0e-4a4b-9a57-2b1d5cf2d8b1" {
+		t.Errorf("workspace = %v", identity.WorkspaceID)
+	}
+}
+
+func TestVerifyKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	issuer := newTestIssuer(t, "k1")
+	verifier := issuer.verifier()
+
+	if _, err := verifier.Verify(ctx, sign(t, issuer.key("k1"), map[string]any{"alg": "RS256", "kid": "k1"}, issuer.claims(nil))); err != nil {
+		t.Fatalf("verify k1: %v", err)
+	}
+	if n := issuer.fetches.Load(); n != 1 {
+		t.Fatalf("fetched JWKS %d times, want 1", n)
+	}
+
+	// A new kid right after a fetch is rejected without refetching, so
+	// made-up kids cannot hammer the issuer.
+	rotated := sign(t, issuer.addKey(t, "k2"), map[string]any{"alg": "RS256", "kid": "k2"}, issuer.claims(nil))
+	if _, err := verifier.Verify(ctx, rotated); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("verify k2 within minRefresh returned %v, want ErrInvalidToken", err)
+	}
+	if n := issuer.fetches.Load(); n != 1 {
+		t.Fatalf("fetched JWKS %d times within minRefresh, want 1", n)
+	}
+
+	verifier.keys.mu.Lock()
+	verifier.keys.fetched = time.Now().Add(-minRefresh)
+	verifier.keys.mu.Unlock()
+
+	if _, err := verifier.Verify(ctx, rotated); err != nil {
+		t.Fatalf("verify k2 after minRefresh: %v", err)
+	}
+	if n := issuer.fetches.Load(); n != 2 {
+		t.Fatalf("fetched JWKS %d times, want 2", n)
+	}
+
+	unknown := sign(t, issuer.key("k1"), map[string]any{"alg": "RS256", "kid": "k3"}, issuer.claims(nil))
+	if _, err := verifier.Verify(ctx, unknown); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("verify unknown kid returned %v, want ErrInvalidToken", err)
+	}
+	if n := issuer.fetches.Load(); n != 2 {
+		t.Fatalf("fetched JWKS %d times for an unknown kid, want 2", n)
+	}
+}
+
+func TestVerifyIssuerUnavailable(t *testing.T) {
+	issuer := newTestIssuer(t, "k1")
+	token := sign(t, issuer.key("k1"), map[string]any{"alg": "RS256", "kid": "k1"}, issuer.claims(nil))
+	verifier := issuer.verifier()
+	issuer.Close()
+
+	_, err := verifier.Verify(context.Background(), token)
+	if err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("verify returned %v, want an upstream error", err)
+	}
+}
+
+func TestLooksLikeJWT(t *testing.T) {
+	tests := map[string]bool{
+		"eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln": true,
+		"pm_0123456789abcdef":                       false,
+		"":                                          false,
+	}
+	for token, want := range tests {
+		if got := LooksLikeJWT(token); got != want {
+			t.Errorf("LooksLikeJWT(%q) = %v, want %v", token, got, want)
+		}
+	}
+}
@@ -2,18 +2,36 @@ package tika
 
 import (
 	"context"
-	"mime/multipart"
+	"io"
 	"net/http"
 	"policy-match/internal/config"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/go-tika/tika"
 )
 
+// languageSampleSize bounds how many bytes of text are sent for language
+// detection; the sample is cut back to the start of a rune.
+const languageSampleSize = 10000
+
 type TikaClient struct {
 	config *config.Config
 	client *tika.Client
 }
 
+// Extraction is the text and the normalized metadata Tika found in a file.
+type Extraction struct {
+	Text       string
+	MimeType   string
+	PageCount  int
+	Author     string
+	AuthoredAt *time.Time
+	Language   string
+}
+
 func NewTikaClient(config *config.Config) *TikaClient {
 	return &TikaClient{
 		config: config,
@@ -21,7 +39,7 @@ func NewTikaClient(config *config.Config) *TikaClient {
 	}
 }
 
-func (t *TikaClient) ExtractText(ctx context.Context, f multipart.File) (string, error) {
+func (t *TikaClient) ExtractText(ctx context.Context, f io.Reader) (string, error) {
 	header := http.Header{}
 	header.Set("Accept", "text/plain")
 	text, err := t.client.ParseWithHeader(ctx, f, header)
@@ -31,19 +49,93 @@ func (t *TikaClient) ExtractText(ctx context.Context, f multipart.File) (string,
 	return text, nil
 }
 
-func (t *TikaClient) ExtractMetaData(ctx context.Context, f multipart.File) (string, error) {
-	f.Seek(0, 0)
-	meta, err := t.client.Meta(ctx, f)
+// Extract parses f once through Tika's recursive metadata endpoint, which
+// returns the text of the file and of any embedded files together with
+// their metadata.
+func (t *TikaClient) Extract(ctx context.Context, f io.Reader) (*Extraction, error) {
+	docs, err := t.client.MetaRecursive(ctx, f)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return &Extraction{}, nil
+	}
+
+	var text strings.Builder
+	for _, doc := range docs {
+		for _, content := range doc["X-TIKA:content"] {
+			text.WriteString(content)
+			text.WriteString("\n")
+		}
+	}
+
+	meta := docs[0]
+	extraction := &Extraction{
+		Text:       text.String(),
+		MimeType:   mimeType(first(meta, "Content-Type")),
+		PageCount:  pageCount(meta),
+		Author:     first(meta, "dc:creator", "meta:author", "Author", "creator"),
+		AuthoredAt: parseDate(first(meta, "dcterms:created", "meta:creation-date", "Creation-Date", "created")),
+		Language:   first(meta, "dc:language", "language"),
 	}
-	return meta, nil
+
+	if extraction.Language == "" && strings.TrimSpace(extraction.Text) != "" {
+		sample := extraction.Text
+		if len(sample) > languageSampleSize {
+			cut := languageSampleSize
+			for cut > 0 && !utf8.RuneStart(sample[cut]) {
+				cut--
+			}
+			sample = sample[:cut]
+		}
+		if lang, err := t.client.LanguageString(ctx, sample); err == nil {
+			extraction.Language = strings.TrimSpace(lang)
+		}
+	}
+
+	return extraction, nil
 }
 
-func (t *TikaClient) DetectMIMEType(ctx context.Context, f multipart.File) (string, error) {
+func (t *TikaClient) DetectMIMEType(ctx context.Context, f io.Reader) (string, error) {
 	mimeType, err := t.client.Detect(ctx, f)
 	if err != nil {
 		return "", err
 	}
 	return mimeType, nil
 }
+
+func first(meta map[string][]string, keys ...string) string {
+	for _, key := range keys {
+		for _, v := range meta[key] {
+			if v = strings.TrimSpace(v); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func mimeType(contentType string) string {
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.TrimSpace(mt)
+}
+
+func pageCount(meta map[string][]string) int {
+	n, _ := strconv.Atoi(first(meta, "xmpTPg:NPages", "meta:page-count", "Page-Count"))
+	return n
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseDate(v string) *time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
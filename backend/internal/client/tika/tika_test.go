@@ -0,0 +1,161 @@
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/config"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// newTestClient answers /rmeta/text with rmeta and /language/string with
+// language, or 500 without one. Language samples are sent to samples.
+func newTestClient(t *testing.T, rmeta string, language string, samples chan<- string) *TikaClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/rmeta/text":
+			w.Write([]byte(rmeta))
+		case "/language/string":
+			if samples != nil {
+				samples <- string(body)
+			}
+			if language == "" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(language + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return NewTikaClient(&config.Config{TikaURL: srv.URL})
+}
+
+func rmeta(t *testing.T, docs ...map[string]any) string {
+	t.Helper()
+	raw, err := json.Marshal(docs)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(raw)
+}
+
+func TestExtract(t *testing.T) {
+	authored := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		docs     []map[string]any
+		language string
+		want     Extraction
+	}{
+		{
+			name: "all fields",
+			docs: []map[string]any{
+				{
+					"Content-Type":    "application/pdf; charset=UTF-8",
+					"X-TIKA:content":  "Staff must wear badges.",
+					"dc:creator":      []string{"", "Ada Lovelace"},
+					"dcterms:created": "2024-03-01T09:30:00Z",
+					"dc:language":     "en",
+					"xmpTPg:NPages":   "4",
+				},
+				{"Content-Type": "image/png", "X-TIKA:content": "Embedded text."},
+			},
+			want: Extraction{
+				Text:       "Staff must wear badges.\nEmbedded text.\n",
+				MimeType:   "application/pdf",
+				PageCount:  4,
+				Author:     "Ada Lovelace",
+				AuthoredAt: &authored,
+				Language:   "en",
+			},
+		},
+		{
+			name: "fallback keys",
+			docs: []map[string]any{{
+				"Content-Type":       "application/msword",
+				"X-TIKA:content":     "Text.",
+				"meta:author":        " Grace Hopper ",
+				"meta:creation-date": "2024-03-01",
+				"meta:page-count":    "2",
+				"language":           "fr",
+			}},
+			want: Extraction{Text: "Text.\n", MimeType: "application/msword", PageCount: 2, Author: "Grace Hopper", AuthoredAt: &day, Language: "fr"},
+		},
+		{
+			name:     "missing fields detect the language",
+			docs:     []map[string]any{{"Content-Type": "text/plain", "X-TIKA:content": "Le personnel doit porter un badge."}},
+			language: "fr",
+			want:     Extraction{Text: "Le personnel doit porter un badge.\n", MimeType: "text/plain", Language: "fr"},
+		},
+		{
+			name: "malformed fields",
+			docs: []map[string]any{{
+				"X-TIKA:content":  "Text.",
+				"dc:creator":      "   ",
+				"dcterms:created": "last Tuesday",
+				"xmpTPg:NPages":   "many",
+			}},
+			want: Extraction{Text: "Text.\n"},
+		},
+		{
+			name: "no text skips language detection",
+			docs: []map[string]any{{"Content-Type": "image/png"}},
+			want: Extraction{MimeType: "image/png"},
+		},
+		{
+			name: "no documents",
+			want: Extraction{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, rmeta(t, tt.docs...), tt.language, nil)
+			got, err := client.Extract(context.Background(), strings.NewReader("file"))
+			if err != nil {
+				t.Fatalf("extract: %v", err)
+			}
+			if got.Text != tt.want.Text || got.MimeType != tt.want.MimeType || got.PageCount != tt.want.PageCount ||
+				got.Author != tt.want.Author || got.Language != tt.want.Language {
+				t.Errorf("extraction = %+v, want %+v", got, tt.want)
+			}
+			if (got.AuthoredAt == nil) != (tt.want.AuthoredAt == nil) || (got.AuthoredAt != nil && !got.AuthoredAt.Equal(*tt.want.AuthoredAt)) {
+				t.Errorf("authored at = %v, want %v", got.AuthoredAt, tt.want.AuthoredAt)
+			}
+		})
+	}
+}
+
+func TestExtractNonStringField(t *testing.T) {
+	client := newTestClient(t, `[{"X-TIKA:content": "Text.", "xmpTPg:NPages": 4}]`, "", nil)
+	if _, err := client.Extract(context.Background(), strings.NewReader("file")); err == nil {
+		t.Error("extract succeeded on a numeric metadata value, want an error")
+	}
+}
+
+func TestExtractLanguageSample(t *testing.T) {
+	samples := make(chan string, 1)
+	text := strings.Repeat("é", languageSampleSize)
+	client := newTestClient(t, rmeta(t, map[string]any{"X-TIKA:content": text}), "fr", samples)
+
+	got, err := client.Extract(context.Background(), strings.NewReader("file"))
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	sample := <-samples
+	if len(sample) > languageSampleSize || !utf8.ValidString(sample) {
+		t.Errorf("sample is %d bytes, valid UTF-8 %v", len(sample), utf8.ValidString(sample))
+	}
+	if got.Language != "fr" {
+		t.Errorf("language = %q, want fr", got.Language)
+	}
+}
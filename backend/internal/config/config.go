@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -14,6 +17,120 @@ type Config struct {
 	DBURL      string
 	TikaURL    string
 	Origin     string
+
+	// DBDriver is "postgres" or "sqlite". For SQLite, DBURL is the
+	// database file path.
+	DBDriver      string
+	DBAutoMigrate bool
+
+	// APIAuthEnabled requires a service API key on every /api/v1 route
+	// except health. Without it every caller is an admin of the default
+	// workspace, and other workspaces cannot be reached.
+	APIAuthEnabled bool
+
+	// OIDC bearer tokens are accepted alongside API keys when OIDCIssuer
+	// is set. OIDCJWKSURL defaults to the issuer's discovery document. The
+	// claim names select the caller's email, workspace and roles; a dotted
+	// name reads a nested claim, e.g. realm_access.roles.
+	OIDCIssuer         string
+	OIDCAudience       string
+	OIDCJWKSURL        string
+	OIDCClockSkew      time.Duration
+	OIDCJWKSCacheTTL   time.Duration
+	OIDCEmailClaim     string
+	OIDCNameClaim      string
+	OIDCWorkspaceClaim string
+	OIDCRolesClaim     string
+	// OIDCRequireVerifiedEmail rejects tokens whose email_verified claim is
+	// not true, since the email is what links a token to a member.
+	OIDCRequireVerifiedEmail bool
+
+	LLMProvider string
+	// LLMBaseURL overrides the provider's endpoint. The groq provider
+	// defaults to https://api.groq.com/openai/v1; earlier releases used the
+	// demo proxy at https://demo-proxy.groqcloud.dev/openai/v1, which has
+	// to be set here now.
+	LLMBaseURL    string
+	LLMAPIKey     string
+	LLMAuthHeader string
+	LLMTimeout    time.Duration
+
+	// LLMTimeout bounds each attempt at a provider call. Failed attempts
+	// are retried up to LLMMaxRetries times with jittered exponential
+	// backoff; after LLMBreakerThreshold consecutive outages calls fail
+	// fast for LLMBreakerCooldown.
+	LLMConnectTimeout   time.Duration
+	LLMMaxRetries       int
+	LLMRetryBaseDelay   time.Duration
+	LLMRetryMaxDelay    time.Duration
+	LLMBreakerThreshold int
+	LLMBreakerCooldown  time.Duration
+
+	// LLMOutputRetries is how many times the model is asked again when
+	// its structured output fails schema validation and cannot be repaired.
+	LLMOutputRetries int
+
+	LLMChunkTokens int
+	LLMConcurrency int
+	// LLMSectionTokens bounds the policy text sent in one rule-extraction
+	// request, so each reply fits in the completion limit; 0 sends the
+	// whole policy at once.
+	LLMSectionTokens int
+
+	// Policies with more than RuleTopK rules only send the RuleTopK rules
+	// most similar to each chunk, ranked by the embedding model; 0 sends
+	// them all. Rule vectors are cached in the database without a vector
+	// index and ranked in process against one policy's rules at a time.
+	EmbeddingProvider   string
+	EmbeddingBaseURL    string
+	EmbeddingAPIKey     string
+	EmbeddingModel      string
+	EmbeddingDimensions int
+	RuleTopK            int
+
+	// Scores weigh each rule by its severity and obligation. A document
+	// passes at CompliancePassThreshold percent unless its policy sets its
+	// own, and verdicts below ReviewConfidence request human review.
+	SeverityWeights         map[string]float64
+	ObligationWeights       map[string]float64
+	CompliancePassThreshold int
+	ReviewConfidence        float64
+
+	// Checks run on JobWorkers background workers, which poll for queued
+	// jobs every JobPollInterval. A claimed job is leased for JobLease at a
+	// time, stopped after JobTimeout and given up after JobMaxAttempts.
+	JobWorkers      int
+	JobPollInterval time.Duration
+	JobLease        time.Duration
+	JobTimeout      time.Duration
+	JobMaxAttempts  int
+	// JobRetryDelay is how long a job that failed on the LLM provider or
+	// Tika waits before its next attempt; it doubles with each attempt.
+	JobRetryDelay time.Duration
+	// JobAPIKeyTTL bounds how long a caller-supplied LLM key is kept in
+	// memory for its queued job; a job still waiting after that runs with
+	// the server key.
+	JobAPIKeyTTL time.Duration
+
+	BatchMaxFiles     int
+	BatchMaxFileBytes int64
+	// BatchMaxZipBytes caps a zip archive as uploaded, and
+	// BatchMaxTotalBytes the content of a whole batch once zips are
+	// expanded.
+	BatchMaxZipBytes   int64
+	BatchMaxTotalBytes int64
+
+	// Uploaded files are stored under BlobLocalDir, or in an S3-compatible
+	// bucket when BlobDriver is "s3".
+	BlobDriver   string
+	BlobLocalDir string
+	S3Endpoint   string
+	S3Bucket     string
+	S3Prefix     string
+	S3Region     string
+	S3AccessKey  string
+	S3SecretKey  string
+	S3UseSSL     bool
 }
 
 func Load() (*Config, error) {
@@ -22,24 +139,33 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("error loading .env file: %w", err)
 	}
 
-	if os.Getenv("DB_HOST") == "" || os.Getenv("DB_PORT") == "" || os.Getenv("DB_USER") == "" || os.Getenv("DB_PASSWORD") == "" || os.Getenv("DB_NAME") == "" {
-		return nil, fmt.Errorf("missing required environment variables")
-	}
+	dbDriver := getEnv("DB_DRIVER", "postgres")
+	var dbURL string
+	switch dbDriver {
+	case "postgres":
+		if os.Getenv("DB_HOST") == "" || os.Getenv("DB_PORT") == "" || os.Getenv("DB_USER") == "" || os.Getenv("DB_PASSWORD") == "" || os.Getenv("DB_NAME") == "" {
+			return nil, fmt.Errorf("missing required environment variables")
+		}
 
-	sslMode := "disable"
-	if os.Getenv("IS_PROD") == "true" {
-		sslMode = "require"
-	}
+		sslMode := "disable"
+		if os.Getenv("IS_PROD") == "true" {
+			sslMode = "require"
+		}
 
-	dbURL := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		sslMode,
-	)
+		dbURL = fmt.Sprintf(
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			os.Getenv("DB_HOST"),
+			os.Getenv("DB_PORT"),
+			os.Getenv("DB_USER"),
+			os.Getenv("DB_PASSWORD"),
+			os.Getenv("DB_NAME"),
+			sslMode,
+		)
+	case "sqlite":
+		dbURL = getEnv("DB_PATH", "data/policy-match.db")
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", dbDriver)
+	}
 
 	origin := os.Getenv("ORIGIN")
 	if origin == "" {
@@ -47,27 +173,338 @@ func Load() (*Config, error) {
 		origin = "http://localhost"
 	}
 
+	llmTimeout, err := getDuration("LLM_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	oidcClockSkew, err := getDuration("OIDC_CLOCK_SKEW", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	oidcJWKSCacheTTL, err := getDuration("OIDC_JWKS_CACHE_TTL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	llmConnectTimeout, err := getDuration("LLM_CONNECT_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	llmMaxRetries, err := getInt("LLM_MAX_RETRIES", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	llmRetryBaseDelay, err := getDuration("LLM_RETRY_BASE_DELAY", 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
+	llmRetryMaxDelay, err := getDuration("LLM_RETRY_MAX_DELAY", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	llmBreakerThreshold, err := getInt("LLM_BREAKER_THRESHOLD", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	llmBreakerCooldown, err := getDuration("LLM_BREAKER_COOLDOWN", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	llmOutputRetries, err := getInt("LLM_OUTPUT_RETRIES", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkTokens, err := getInt("LLM_CHUNK_TOKENS", 4000)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency, err := getInt("LLM_CONCURRENCY", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	sectionTokens, err := getInt("LLM_SECTION_TOKENS", 3000)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddingDimensions, err := getInt("EMBEDDING_DIMENSIONS", 512)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleTopK, err := getInt("RULE_TOP_K", 20)
+	if err != nil {
+		return nil, err
+	}
+
+	severityWeights, err := getWeights("SCORE_SEVERITY_WEIGHTS", "critical=3,major=2,minor=1")
+	if err != nil {
+		return nil, err
+	}
+
+	obligationWeights, err := getWeights("SCORE_OBLIGATION_WEIGHTS", "must=1,must-not=1,should=0.5,may=0.25")
+	if err != nil {
+		return nil, err
+	}
+
+	passThreshold, err := getInt("COMPLIANCE_PASS_THRESHOLD", 80)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewConfidence, err := getFloat("REVIEW_CONFIDENCE_THRESHOLD", 0.6)
+	if err != nil {
+		return nil, err
+	}
+
+	jobWorkers, err := getInt("JOB_WORKERS", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	jobPollInterval, err := getDuration("JOB_POLL_INTERVAL", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	jobLease, err := getDuration("JOB_LEASE", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	jobTimeout, err := getDuration("JOB_TIMEOUT", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	jobMaxAttempts, err := getInt("JOB_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	jobRetryDelay, err := getDuration("JOB_RETRY_DELAY", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	jobAPIKeyTTL, err := getDuration("JOB_API_KEY_TTL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	batchMaxFiles, err := getInt("BATCH_MAX_FILES", 500)
+	if err != nil {
+		return nil, err
+	}
+
+	batchMaxFileMB, err := getInt("BATCH_MAX_FILE_MB", 50)
+	if err != nil {
+		return nil, err
+	}
+
+	batchMaxZipMB, err := getInt("BATCH_MAX_ZIP_MB", 200)
+	if err != nil {
+		return nil, err
+	}
+
+	batchMaxTotalMB, err := getInt("BATCH_MAX_TOTAL_MB", 1024)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
 		GroqAPIKey: os.Getenv("GROQ_API_KEY"),
 		LLMModel:   os.Getenv("LLM_MODEL"),
 		DBURL:      dbURL,
 		TikaURL:    os.Getenv("TIKA_URL"),
 		Origin:     origin,
+
+		DBDriver:      dbDriver,
+		DBAutoMigrate: os.Getenv("DB_AUTO_MIGRATE") != "false",
+
+		APIAuthEnabled: os.Getenv("API_AUTH_ENABLED") != "false",
+
+		OIDCIssuer:         strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+		OIDCAudience:       os.Getenv("OIDC_AUDIENCE"),
+		OIDCJWKSURL:        os.Getenv("OIDC_JWKS_URL"),
+		OIDCClockSkew:      max(oidcClockSkew, 0),
+		OIDCJWKSCacheTTL:   oidcJWKSCacheTTL,
+		OIDCEmailClaim:     getEnv("OIDC_EMAIL_CLAIM", "email"),
+		OIDCNameClaim:      getEnv("OIDC_NAME_CLAIM", "name"),
+		OIDCWorkspaceClaim: getEnv("OIDC_WORKSPACE_CLAIM", "workspace_id"),
+		OIDCRolesClaim:     getEnv("OIDC_ROLES_CLAIM", "roles"),
+
+		OIDCRequireVerifiedEmail: os.Getenv("OIDC_REQUIRE_VERIFIED_EMAIL") != "false",
+
+		LLMProvider:   getEnv("LLM_PROVIDER", "groq"),
+		LLMBaseURL:    os.Getenv("LLM_BASE_URL"),
+		LLMAPIKey:     getEnv("LLM_API_KEY", os.Getenv("GROQ_API_KEY")),
+		LLMAuthHeader: getEnv("LLM_AUTH_HEADER", "Authorization"),
+		LLMTimeout:    llmTimeout,
+
+		LLMConnectTimeout:   llmConnectTimeout,
+		LLMMaxRetries:       max(llmMaxRetries, 0),
+		LLMRetryBaseDelay:   llmRetryBaseDelay,
+		LLMRetryMaxDelay:    max(llmRetryMaxDelay, llmRetryBaseDelay),
+		LLMBreakerThreshold: max(llmBreakerThreshold, 0),
+		LLMBreakerCooldown:  llmBreakerCooldown,
+
+		LLMOutputRetries: max(llmOutputRetries, 0),
+
+		LLMChunkTokens: chunkTokens,
+		LLMConcurrency: max(concurrency, 1),
+
+		LLMSectionTokens: max(sectionTokens, 0),
+
+		EmbeddingProvider:   getEnv("EMBEDDING_PROVIDER", "local"),
+		EmbeddingBaseURL:    getEnv("EMBEDDING_BASE_URL", os.Getenv("LLM_BASE_URL")),
+		EmbeddingAPIKey:     getEnv("EMBEDDING_API_KEY", getEnv("LLM_API_KEY", os.Getenv("GROQ_API_KEY"))),
+		EmbeddingModel:      os.Getenv("EMBEDDING_MODEL"),
+		EmbeddingDimensions: max(embeddingDimensions, 1),
+		RuleTopK:            max(ruleTopK, 0),
+
+		SeverityWeights:         severityWeights,
+		ObligationWeights:       obligationWeights,
+		CompliancePassThreshold: passThreshold,
+		ReviewConfidence:        reviewConfidence,
+
+		JobWorkers:      max(jobWorkers, 1),
+		JobPollInterval: jobPollInterval,
+		JobLease:        jobLease,
+		JobTimeout:      jobTimeout,
+		JobMaxAttempts:  max(jobMaxAttempts, 1),
+		JobRetryDelay:   max(jobRetryDelay, 0),
+		JobAPIKeyTTL:    max(jobAPIKeyTTL, time.Minute),
+
+		BatchMaxFiles:     batchMaxFiles,
+		BatchMaxFileBytes: int64(batchMaxFileMB) << 20,
+
+		BatchMaxZipBytes:   int64(batchMaxZipMB) << 20,
+		BatchMaxTotalBytes: int64(batchMaxTotalMB) << 20,
+
+		BlobDriver:   getEnv("BLOB_DRIVER", "local"),
+		BlobLocalDir: getEnv("BLOB_LOCAL_DIR", "data/blobs"),
+		S3Endpoint:   os.Getenv("S3_ENDPOINT"),
+		S3Bucket:     getEnv("S3_BUCKET", "policy-match"),
+		S3Prefix:     os.Getenv("S3_PREFIX"),
+		S3Region:     os.Getenv("S3_REGION"),
+		S3AccessKey:  os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:  os.Getenv("S3_SECRET_KEY"),
+		S3UseSSL:     os.Getenv("S3_USE_SSL") == "true",
 	}
 
 	missing := make([]string, 0, 3)
-	if cfg.GroqAPIKey == "" {
-		missing = append(missing, "GROQ_API_KEY")
+	switch cfg.LLMProvider {
+	case "groq":
+		if cfg.LLMAPIKey == "" {
+			missing = append(missing, "GROQ_API_KEY")
+		}
+	case "openai":
+		if cfg.LLMBaseURL == "" {
+			missing = append(missing, "LLM_BASE_URL")
+		}
+	case "fake":
+	default:
+		return nil, fmt.Errorf("unsupported LLM_PROVIDER: %s", cfg.LLMProvider)
 	}
-	if cfg.LLMModel == "" {
+	if cfg.LLMModel == "" && cfg.LLMProvider != "fake" {
 		missing = append(missing, "LLM_MODEL")
 	}
+	switch cfg.EmbeddingProvider {
+	case "local":
+	case "openai":
+		if cfg.EmbeddingBaseURL == "" {
+			missing = append(missing, "EMBEDDING_BASE_URL")
+		}
+		if cfg.EmbeddingModel == "" {
+			missing = append(missing, "EMBEDDING_MODEL")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported EMBEDDING_PROVIDER: %s", cfg.EmbeddingProvider)
+	}
 	if cfg.TikaURL == "" {
 		missing = append(missing, "TIKA_URL")
 	}
+	if cfg.OIDCIssuer != "" && cfg.OIDCAudience == "" {
+		missing = append(missing, "OIDC_AUDIENCE")
+	}
+	if cfg.BlobDriver == "s3" && cfg.S3Endpoint == "" {
+		missing = append(missing, "S3_ENDPOINT")
+	}
 	if len(missing) > 0 {
 		return nil, fmt.Errorf("missing required environment variables: %v", missing)
 	}
 
 	return cfg, nil
 }
+
+func getEnv(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+func getFloat(key string, fallback float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// getWeights parses a comma separated list of name=weight pairs.
+func getWeights(key string, fallback string) (map[string]float64, error) {
+	weights := map[string]float64{}
+	for _, pair := range strings.Split(getEnv(key, fallback), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: expected name=weight, got %q", key, pair)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid %s: bad weight for %q", key, name)
+		}
+		weights[strings.TrimSpace(name)] = w
+	}
+	return weights, nil
+}
+
+func getDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
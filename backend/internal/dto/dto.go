@@ -2,17 +2,40 @@ package dto
 
 import "mime/multipart"
 
+// UploadPolicyRequestDTO creates a policy, or with PolicyID uploads a new
+// version of it. Title and category are required for new policies only.
 type UploadPolicyRequestDTO struct {
 	File     *multipart.FileHeader `form:"file"  binding:"required"`
-	Title    string                `form:"title" binding:"required"`
-	Category string                `form:"category" binding:"required"`
+	PolicyID string                `form:"policy_id"`
+	Title    string                `form:"title" binding:"required_without=PolicyID"`
+	Category string                `form:"category" binding:"required_without=PolicyID"`
 }
 
+// UploadDocumentRequestDTO selects policies by ID, by category, or both.
+// PolicyID is kept for single-policy clients.
 type UploadDocumentRequestDTO struct {
-	File     *multipart.FileHeader `form:"file"  binding:"required"`
-	PolicyID string                `form:"policy_id" binding:"required"`
+	File      *multipart.FileHeader `form:"file"  binding:"required"`
+	PolicyID  string                `form:"policy_id"`
+	PolicyIDs []string              `form:"policy_ids"`
+	Category  string                `form:"category"`
+}
+
+type UploadBatchRequestDTO struct {
+	Files     []*multipart.FileHeader `form:"files" binding:"required"`
+	PolicyIDs []string                `form:"policy_ids"`
+	Category  string                  `form:"category"`
 }
 
 const (
 	UserAPIKeyContext string = "user_api_key"
+	// APIKeyIDContext holds the uuid.UUID of the service API key the
+	// request was authenticated with.
+	APIKeyIDContext string = "api_key_id"
+	// WorkspaceIDContext holds the uuid.UUID of the caller's workspace.
+	WorkspaceIDContext string = "workspace_id"
+	// OwnerIDContext holds the uuid.UUID of the caller, an API key or a
+	// user, that rows created by the request belong to.
+	OwnerIDContext string = "owner_id"
+	// OwnedOnlyContext is true when the caller may only see its own rows.
+	OwnedOnlyContext string = "owned_only"
 )
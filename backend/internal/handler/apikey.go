@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"policy-match/internal/apperr"
+	"policy-match/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) HandleCreateAPIKey(c *gin.Context) {
+	var request CreateAPIKeyRequestDTO
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	key, plaintext, err := h.service.CreateAPIKey(c.Request.Context(), request.Name, request.Role, request.RequestQuota, request.TokenQuota)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(201, NewResponse(CreateAPIKeyResponseDTO{
+		APIKey: newAPIKeyDTO(key),
+		Key:    plaintext,
+	}, utils.Localize(c, "api_key_created_successfully")))
+}
+
+func (h *Handler) HandleGetAPIKeys(c *gin.Context) {
+	keys, err := h.service.GetAPIKeys(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	keysDTO := make([]APIKey, len(keys))
+	for i := range keys {
+		keysDTO[i] = newAPIKeyDTO(&keys[i])
+	}
+
+	c.JSON(200, NewResponse(keysDTO, utils.Localize(c, "api_keys_fetched_successfully")))
+}
+
+func (h *Handler) HandleRevokeAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("api_key_id_is_required", err))
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(nil, utils.Localize(c, "api_key_revoked_successfully")))
+}
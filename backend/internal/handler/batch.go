@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"policy-match/internal/apperr"
+	"policy-match/internal/dto"
+	"policy-match/internal/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) HandleSubmitBatch(c *gin.Context) {
+	var request dto.UploadBatchRequestDTO
+	if err := c.ShouldBind(&request); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	policyIDs, err := parsePolicyIDs(request.PolicyIDs)
+	if err != nil {
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		ctx = context.WithValue(ctx, dto.UserAPIKeyContext, apiKey)
+	}
+
+	batch, err := h.service.SubmitBatch(ctx, request, policyIDs)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(202, NewResponse(newBatchDTO(batch), utils.Localize(c, "batch_queued_for_compliance_check")))
+}
+
+func (h *Handler) HandleGetBatch(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	batch, err := h.service.GetBatch(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(newBatchDTO(batch), utils.Localize(c, "batch_fetched_successfully")))
+}
+
+func (h *Handler) HandleExportBatch(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.Error(apperr.InvalidInput("request_is_invalid", nil))
+		return
+	}
+
+	batch, err := h.service.GetBatch(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	report := newBatchDTO(batch)
+	filename := fmt.Sprintf("batch-%s.%s", report.BatchID, format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	if format == "json" {
+		c.JSON(200, report)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"job_id", "file_name", "status", "document_id", "policy_id", "policy_title",
+		"is_compliant", "compliance_percentage", "violation_percentage",
+		"is_human_review_required", "violations", "error",
+	})
+	for _, item := range report.Items {
+		if item.Result == nil || len(item.Result.Results) == 0 {
+			w.Write([]string{item.JobID, item.FileName, item.Status, "", "", "", "", "", "", "", "", item.Error})
+			continue
+		}
+		for _, res := range item.Result.Results {
+			w.Write([]string{
+				item.JobID,
+				item.FileName,
+				item.Status,
+				item.Result.DocumentID,
+				res.PolicyID,
+				res.PolicyTitle,
+				strconv.FormatBool(res.IsCompliant),
+				strconv.Itoa(res.CompliancePercentage),
+				strconv.Itoa(res.ViolationPercentage),
+				strconv.FormatBool(res.IsHumanReviewRequired),
+				strings.Join(res.Violations, "; "),
+				item.Error,
+			})
+		}
+	}
+	w.Flush()
+}
+
+// parsePolicyIDs accepts repeated form values as well as comma separated
+// lists. An empty list is valid when policies are selected by category.
+func parsePolicyIDs(values []string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := uuid.Parse(part)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"policy-match/internal/apperr"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) HandleDownloadPolicy(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
+		return
+	}
+
+	policy, rc, err := h.service.OpenPolicyFile(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer rc.Close()
+
+	sendFile(c, rc, policy.Size, policy.ContentType, policy.Path+policy.Extension)
+}
+
+func (h *Handler) HandleDownloadDocument(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	document, rc, err := h.service.OpenDocumentFile(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer rc.Close()
+
+	sendFile(c, rc, document.Size, document.ContentType, document.Path+document.Extension)
+}
+
+func sendFile(c *gin.Context, r io.Reader, size int64, contentType string, filename string) {
+	c.DataFromReader(200, size, contentType, r, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": filename}),
+	})
+}
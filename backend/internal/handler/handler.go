@@ -2,14 +2,13 @@ package handler
 
 import (
 	"context"
+	"policy-match/internal/apperr"
 	"policy-match/internal/dto"
 	"policy-match/internal/service"
 	"policy-match/internal/utils"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/rs/zerolog/log"
 )
 
 type Handler struct {
@@ -27,33 +26,44 @@ func (h *Handler) HandleGetHealth(c *gin.Context) {
 func (h *Handler) HandleUploadPolicy(c *gin.Context) {
 	var request dto.UploadPolicyRequestDTO
 	if err := c.ShouldBind(&request); err != nil {
-		c.JSON(400, NewResponse(nil, utils.Localize(c, "request_is_invalid")))
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
 		return
 	}
 
+	policyID := uuid.Nil
+	if request.PolicyID != "" {
+		id, err := uuid.Parse(request.PolicyID)
+		if err != nil {
+			c.Error(apperr.InvalidInput("policy_id_is_required", err))
+			return
+		}
+		policyID = id
+	}
+
 	ctx := c.Request.Context()
 	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
 		ctx = context.WithValue(ctx, dto.UserAPIKeyContext, apiKey)
 	}
 
-	err := h.service.UploadPolicy(ctx, request)
+	policy, err := h.service.UploadPolicy(ctx, request, policyID)
 	if err != nil {
-		if strings.Contains(err.Error(), "RATE_LIMIT") {
-			c.JSON(429, NewResponse(nil, err.Error()))
-			return
-		}
-		log.Error().Msg("error: " + err.Error())
-		c.JSON(500, NewResponse(nil, err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(200, NewResponse(nil, utils.Localize(c, "file_uploaded_successfully")))
+	c.JSON(200, NewResponse(newPolicyDTO(*policy), utils.Localize(c, "file_uploaded_successfully")))
 }
 
 func (h *Handler) HandleCheckDocumentCompliance(c *gin.Context) {
 	var request dto.UploadDocumentRequestDTO
 	if err := c.ShouldBind(&request); err != nil {
-		c.JSON(400, NewResponse(nil, utils.Localize(c, "request_is_invalid")))
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	policyIDs, err := parsePolicyIDs(append([]string{request.PolicyID}, request.PolicyIDs...))
+	if err != nil {
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
 		return
 	}
 
@@ -62,50 +72,92 @@ func (h *Handler) HandleCheckDocumentCompliance(c *gin.Context) {
 		ctx = context.WithValue(ctx, dto.UserAPIKeyContext, apiKey)
 	}
 
-	checkComplianceResponse, err := h.service.CheckDocumentCompliance(ctx, request)
+	job, err := h.service.SubmitDocument(ctx, request, policyIDs)
 	if err != nil {
-		if strings.Contains(err.Error(), "RATE_LIMIT") {
-			c.JSON(429, NewResponse(nil, err.Error()))
-			return
-		}
-		log.Error().Msg("error: " + err.Error())
-		c.JSON(500, NewResponse(nil, err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(200, NewResponse(checkComplianceResponse, utils.Localize(c, "file_uploaded_successfully")))
+	c.JSON(202, NewResponse(newJobDTO(job), utils.Localize(c, "document_queued_for_compliance_check")))
+}
+
+func (h *Handler) HandleGetJob(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	job, err := h.service.GetJob(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(newJobDTO(job), utils.Localize(c, "job_fetched_successfully")))
+}
+
+func (h *Handler) HandleGetPolicy(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	policy, err := h.service.GetPolicy(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(PolicyDetail{
+		Policy:        newPolicyDTO(*policy),
+		ExtractedText: policy.ExtractedText,
+		Metadata:      newExtractionMetadataDTO(policy.Extraction),
+	}, utils.Localize(c, "policy_fetched_successfully")))
+}
+
+func (h *Handler) HandleGetDocument(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	var request GetDocumentRequestDTO
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	document, err := h.service.GetDocument(c.Request.Context(), id, request.filter())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(DocumentDetail{
+		Document:      newDocumentDTO(*document),
+		ExtractedText: document.ExtractedText,
+		Metadata:      newExtractionMetadataDTO(document.Extraction),
+	}, utils.Localize(c, "document_fetched_successfully")))
 }
 
 func (h *Handler) HandleGetPolicies(c *gin.Context) {
-	var request PaginationRequest
+	var request GetPoliciesRequestDTO
 	if err := c.ShouldBindQuery(&request); err != nil {
-		c.JSON(400, NewResponse(nil, utils.Localize(c, "request_is_invalid")))
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
 		return
 	}
 
-	policies, total, err := h.service.GetPolicies(c.Request.Context(), request.Page, request.PageSize)
+	policies, total, err := h.service.GetPolicies(c.Request.Context(), request.filter(), request.Page, request.PageSize)
 	if err != nil {
-		log.Error().Msg("error: " + err.Error())
-		c.JSON(500, NewResponse(nil, utils.Localize(c, "an_error_occurred_while_processing_your_request")))
+		c.Error(err)
 		return
 	}
 	policiesDTO := make([]Policy, len(policies))
 	for i, policy := range policies {
-		rulesDTO := make([]Rule, len(policy.Rules))
-		for j, rule := range policy.Rules {
-			rulesDTO[j] = Rule{
-				RuleID:   rule.RuleID,
-				RuleText: rule.RuleText,
-			}
-		}
-		policiesDTO[i] = Policy{
-			PolicyID:   policy.ID.String(),
-			Title:      policy.Title,
-			Category:   policy.Category,
-			Extension:  Extension(policy.Extension),
-			Rules:      rulesDTO,
-			UploadedAt: policy.CreatedAt.Format("2006-01-02"),
-		}
+		policiesDTO[i] = newPolicyDTO(policy)
 	}
 
 	c.JSON(200, NewResponse(GetPoliciesResponseDTO{
@@ -117,33 +169,25 @@ func (h *Handler) HandleGetPolicies(c *gin.Context) {
 }
 
 func (h *Handler) HandleGetDocuments(c *gin.Context) {
-	var request PaginationRequest
+	var request GetDocumentsRequestDTO
 	if err := c.ShouldBindQuery(&request); err != nil {
-		c.JSON(400, NewResponse(nil, utils.Localize(c, "request_is_invalid")))
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+	if request.MinCompliance != nil && request.MaxCompliance != nil && *request.MinCompliance > *request.MaxCompliance {
+		c.Error(apperr.InvalidInput("request_is_invalid", nil))
 		return
 	}
 
-	documents, total, err := h.service.GetDocuments(c.Request.Context(), request.Page, request.PageSize)
+	documents, total, err := h.service.GetDocuments(c.Request.Context(), request.filter(), request.Page, request.PageSize)
 	if err != nil {
-		log.Error().Msg("error: " + err.Error())
-		c.JSON(500, NewResponse(nil, utils.Localize(c, "an_error_occurred_while_processing_your_request")))
+		c.Error(err)
 		return
 	}
 
 	documentsDTO := make([]Document, len(documents))
 	for i, document := range documents {
-		documentsDTO[i] = Document{
-			DocumentID:            document.ID.String(),
-			Title:                 document.Title,
-			Path:                  document.Path,
-			Extension:             Extension(document.Extension),
-			Violations:            document.Violations,
-			IsCompliant:           document.IsCompliant,
-			IsHumanReviewRequired: document.IsHumanReviewRequired,
-			CompliancePercentage:  document.CompliancePercentage,
-
-			PolicyTitle: document.Policy.Title,
-		}
+		documentsDTO[i] = newDocumentDTO(document)
 	}
 
 	c.JSON(200, NewResponse(GetDocumentsResponseDTO{
@@ -155,11 +199,15 @@ func (h *Handler) HandleGetDocuments(c *gin.Context) {
 }
 
 func (h *Handler) HandleDeleteDocument(c *gin.Context) {
-	id := c.Param("id")
-	err := h.service.DeleteDocument(c.Request.Context(), id)
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		log.Error().Msg("error: " + err.Error())
-		c.JSON(500, NewResponse(nil, utils.Localize(c, "an_error_occurred_while_processing_your_request")))
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	err = h.service.DeleteDocument(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -167,11 +215,15 @@ func (h *Handler) HandleDeleteDocument(c *gin.Context) {
 }
 
 func (h *Handler) HandleDeletePolicy(c *gin.Context) {
-	id := c.Param("id")
-	err := h.service.DeletePolicy(c.Request.Context(), id)
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		log.Error().Msg("error: " + err.Error())
-		c.JSON(500, NewResponse(nil, utils.Localize(c, "an_error_occurred_while_processing_your_request")))
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
+		return
+	}
+
+	err = h.service.DeletePolicy(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -179,11 +231,21 @@ func (h *Handler) HandleDeletePolicy(c *gin.Context) {
 }
 
 func (h *Handler) HandleDeleteRule(c *gin.Context) {
-	id := c.Param("id")
-	err := h.service.DeleteRule(c.Request.Context(), id)
+	policyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
+		return
+	}
+
+	ruleID := c.Param("rule_id")
+	if ruleID == "" {
+		c.Error(apperr.InvalidInput("rule_id_is_required", nil))
+		return
+	}
+
+	err = h.service.DeleteRule(c.Request.Context(), policyID, ruleID)
 	if err != nil {
-		log.Error().Msg("error: " + err.Error())
-		c.JSON(500, NewResponse(nil, utils.Localize(c, "an_error_occurred_while_processing_your_request")))
+		c.Error(err)
 		return
 	}
 
@@ -193,44 +255,93 @@ func (h *Handler) HandleDeleteRule(c *gin.Context) {
 func (h *Handler) HandleUpdateRule(c *gin.Context) {
 	var req UpdateRuleRequestDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, NewResponse(nil, utils.Localize(c, "request_is_invalid")))
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
 		return
 	}
 
 	policyID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(400, NewResponse(nil, utils.Localize(c, "policy_id_is_required")))
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
 		return
 	}
 
 	ruleID := c.Param("rule_id")
 	if ruleID == "" {
-		c.JSON(400, NewResponse(nil, utils.Localize(c, "rule_id_is_required")))
+		c.Error(apperr.InvalidInput("rule_id_is_required", nil))
 		return
 	}
 
 	updates := map[string]any{}
-	if req.Title != nil {
-		updates["title"] = *req.Title
-	}
 	if req.Category != nil {
 		updates["category"] = *req.Category
 	}
 	if req.RuleText != nil {
 		updates["rule_text"] = *req.RuleText
 	}
+	if req.Severity != nil {
+		updates["severity"] = *req.Severity
+	}
+	if req.Weight != nil {
+		updates["weight"] = *req.Weight
+	}
+	if req.Obligation != nil {
+		updates["obligation"] = *req.Obligation
+	}
+	if req.ParentRuleID != nil {
+		updates["parent_rule_id"] = *req.ParentRuleID
+	}
+	if req.Applicability != nil {
+		updates["applicability"] = *req.Applicability
+	}
 
 	if len(updates) == 0 {
-		c.JSON(400, NewResponse(nil, utils.Localize(c, "no_fields_to_update")))
+		c.Error(apperr.InvalidInput("no_fields_to_update", nil))
 		return
 	}
 
 	err = h.service.UpdateRule(c.Request.Context(), policyID, ruleID, updates)
 	if err != nil {
-		log.Error().Msg("error: " + err.Error())
-		c.JSON(500, NewResponse(nil, utils.Localize(c, "an_error_occurred_while_processing_your_request")))
+		c.Error(err)
 		return
 	}
 
 	c.JSON(200, NewResponse(nil, utils.Localize(c, "rule_updated_successfully")))
 }
+
+func (h *Handler) HandleUpdatePolicy(c *gin.Context) {
+	var req UpdatePolicyRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	policyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
+		return
+	}
+
+	updates := map[string]any{}
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if req.Category != nil {
+		updates["category"] = *req.Category
+	}
+	if req.PassThreshold != nil {
+		updates["pass_threshold"] = *req.PassThreshold
+	}
+
+	if len(updates) == 0 {
+		c.Error(apperr.InvalidInput("no_fields_to_update", nil))
+		return
+	}
+
+	err = h.service.UpdatePolicy(c.Request.Context(), policyID, updates)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(nil, utils.Localize(c, "policy_updated_successfully")))
+}
@@ -2,6 +2,11 @@ package handler
 
 import (
 	"mime/multipart"
+	"policy-match/internal/repository"
+	"policy-match/internal/service"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type HandlerResponse struct {
@@ -48,9 +53,98 @@ type PaginationRequest struct {
 	PageSize int `form:"page_size,default=10" binding:"min=1,max=50"`
 }
 
+// ListRequest holds the search, date range and sort parameters shared by
+// the list endpoints. Dates are inclusive days (YYYY-MM-DD).
+type ListRequest struct {
+	PaginationRequest
+	Query       string     `form:"q"`
+	Category    string     `form:"category"`
+	CreatedFrom *time.Time `form:"created_from" time_format:"2006-01-02"`
+	CreatedTo   *time.Time `form:"created_to"   time_format:"2006-01-02"`
+	Dir         string     `form:"dir,default=desc" binding:"oneof=asc desc"`
+}
+
+// createdTo returns the exclusive upper bound for CreatedTo.
+func (r ListRequest) createdTo() *time.Time {
+	if r.CreatedTo == nil {
+		return nil
+	}
+	to := r.CreatedTo.AddDate(0, 0, 1)
+	return &to
+}
+
+type GetPoliciesRequestDTO struct {
+	ListRequest
+	Sort string `form:"sort" binding:"omitempty,oneof=created_at title category"`
+}
+
+func (r GetPoliciesRequestDTO) filter() repository.PolicyFilter {
+	return repository.PolicyFilter{
+		Query:       r.Query,
+		Category:    r.Category,
+		CreatedFrom: r.CreatedFrom,
+		CreatedTo:   r.createdTo(),
+		Sort:        r.Sort,
+		Desc:        r.Dir == "desc",
+	}
+}
+
+// GetDocumentRequestDTO filters the rule results of a single document by
+// the attributes of their rules.
+type GetDocumentRequestDTO struct {
+	Severity   string `form:"severity" binding:"omitempty,oneof=critical major minor"`
+	Category   string `form:"rule_category"`
+	Obligation string `form:"obligation" binding:"omitempty,oneof=must must-not should may"`
+}
+
+func (r GetDocumentRequestDTO) filter() repository.RuleFilter {
+	return repository.RuleFilter{
+		Severity:   r.Severity,
+		Category:   r.Category,
+		Obligation: r.Obligation,
+	}
+}
+
+type GetDocumentsRequestDTO struct {
+	ListRequest
+	PolicyID      string `form:"policy_id" binding:"omitempty,uuid"`
+	Compliant     *bool  `form:"compliant"`
+	HumanReview   *bool  `form:"human_review"`
+	MinCompliance *int   `form:"min_compliance" binding:"omitempty,min=0,max=100"`
+	MaxCompliance *int   `form:"max_compliance" binding:"omitempty,min=0,max=100"`
+	Sort          string `form:"sort" binding:"omitempty,oneof=created_at title compliance_percentage violation_percentage"`
+}
+
+func (r GetDocumentsRequestDTO) filter() repository.DocumentFilter {
+	filter := repository.DocumentFilter{
+		Query:                 r.Query,
+		Category:              r.Category,
+		IsCompliant:           r.Compliant,
+		IsHumanReviewRequired: r.HumanReview,
+		MinCompliance:         r.MinCompliance,
+		MaxCompliance:         r.MaxCompliance,
+		CreatedFrom:           r.CreatedFrom,
+		CreatedTo:             r.createdTo(),
+		Sort:                  r.Sort,
+		Desc:                  r.Dir == "desc",
+	}
+	if id, err := uuid.Parse(r.PolicyID); err == nil {
+		filter.PolicyID = &id
+	}
+	return filter
+}
+
 type Rule struct {
-	RuleID   string `json:"rule_id"`
-	RuleText string `json:"rule_text"`
+	RuleID   string   `json:"rule_id"`
+	RuleText string   `json:"rule_text"`
+	Severity string   `json:"severity"`
+	Weight   float64  `json:"weight"`
+	Section  []string `json:"section"`
+
+	Category      string `json:"category"`
+	Obligation    string `json:"obligation"`
+	ParentRuleID  string `json:"parent_rule_id"`
+	Applicability string `json:"applicability"`
 }
 
 type Policy struct {
@@ -59,8 +153,66 @@ type Policy struct {
 	Category  string    `json:"category"`
 	Extension Extension `json:"extension"`
 
-	Rules      []Rule `json:"rules"`
-	UploadedAt string `json:"uploaded_at"`
+	PassThreshold   *int   `json:"pass_threshold"`
+	Version         int    `json:"version"`
+	Rules           []Rule `json:"rules"`
+	RulesOutputPath string `json:"rules_output_path"`
+	UploadedAt      string `json:"uploaded_at"`
+}
+
+func newPolicyDTO(policy repository.Policy) Policy {
+	rulesDTO := make([]Rule, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		rulesDTO[i] = Rule{
+			RuleID:   rule.RuleID,
+			RuleText: rule.RuleText,
+			Severity: rule.Severity,
+			Weight:   rule.Weight,
+			Section:  rule.Section,
+
+			Category:      rule.Category,
+			Obligation:    rule.Obligation,
+			ParentRuleID:  rule.ParentRuleID,
+			Applicability: rule.Applicability,
+		}
+	}
+	return Policy{
+		PolicyID:   policy.ID.String(),
+		Title:      policy.Title,
+		Category:   policy.Category,
+		Extension:  Extension(policy.Extension),
+		Rules:      rulesDTO,
+		UploadedAt: policy.CreatedAt.Format("2006-01-02"),
+
+		PassThreshold:   policy.PassThreshold,
+		Version:         policy.Version,
+		RulesOutputPath: policy.RulesOutputPath,
+	}
+}
+
+// ExtractionMetadata is what Tika reported about the original file.
+type ExtractionMetadata struct {
+	MimeType   string     `json:"mime_type"`
+	PageCount  int        `json:"page_count"`
+	Author     string     `json:"author"`
+	AuthoredAt *time.Time `json:"authored_at"`
+	Language   string     `json:"language"`
+}
+
+func newExtractionMetadataDTO(extraction repository.Extraction) ExtractionMetadata {
+	return ExtractionMetadata{
+		MimeType:   extraction.MimeType,
+		PageCount:  extraction.PageCount,
+		Author:     extraction.Author,
+		AuthoredAt: extraction.AuthoredAt,
+		Language:   extraction.Language,
+	}
+}
+
+type PolicyDetail struct {
+	Policy
+	ExtractedText string             `json:"extracted_text"`
+	Metadata      ExtractionMetadata `json:"metadata"`
 }
 
 type GetPoliciesResponseDTO struct {
@@ -83,6 +235,58 @@ type Document struct {
 	IsHumanReviewRequired bool     `json:"is_human_review_required"`
 	CompliancePercentage  int      `json:"compliance_percentage"`
 	ViolationPercentage   int      `json:"violation_percentage"`
+	IsStale               bool     `json:"is_stale"`
+
+	Results []PolicyResult `json:"results"`
+}
+
+type PolicyResult struct {
+	PolicyID    string `json:"policy_id"`
+	PolicyTitle string `json:"policy_title"`
+
+	Violations            []string `json:"violations"`
+	IsCompliant           bool     `json:"is_compliant"`
+	IsHumanReviewRequired bool     `json:"is_human_review_required"`
+	CompliancePercentage  int      `json:"compliance_percentage"`
+	ViolationPercentage   int      `json:"violation_percentage"`
+	PolicyVersion         int      `json:"policy_version"`
+	IsStale               bool     `json:"is_stale"`
+	OutputPath            string   `json:"output_path"`
+
+	CheckedAt    time.Time    `json:"checked_at"`
+	SupersededAt *time.Time   `json:"superseded_at"`
+	RuleResults  []RuleResult `json:"rule_results"`
+}
+
+type RuleResult struct {
+	RuleUUID      string  `json:"rule_uuid"`
+	RuleID        string  `json:"rule_id"`
+	Verdict       string  `json:"verdict"`
+	Confidence    float64 `json:"confidence"`
+	Evidence      string  `json:"evidence"`
+	EvidenceStart int     `json:"evidence_start"`
+	EvidenceEnd   int     `json:"evidence_end"`
+	Rationale     string  `json:"rationale"`
+
+	// Rule attributes are only loaded for a single document.
+	Severity   string `json:"severity,omitempty"`
+	Category   string `json:"category,omitempty"`
+	Obligation string `json:"obligation,omitempty"`
+}
+
+type DocumentDetail struct {
+	Document
+	ExtractedText string             `json:"extracted_text"`
+	Metadata      ExtractionMetadata `json:"metadata"`
+}
+
+type DocumentHistory struct {
+	DocumentID string         `json:"document_id"`
+	Results    []PolicyResult `json:"results"`
+}
+
+type RecheckPolicyRequestDTO struct {
+	StaleOnly bool `form:"stale_only"`
 }
 
 type GetDocumentsResponseDTO struct {
@@ -92,8 +296,466 @@ type GetDocumentsResponseDTO struct {
 	Total     int        `json:"total"`
 }
 
+type Job struct {
+	JobID      string     `json:"job_id"`
+	Kind       string     `json:"kind"`
+	Status     string     `json:"status"`
+	PolicyIDs  []string   `json:"policy_ids"`
+	FileName   string     `json:"file_name"`
+	Error      string     `json:"error,omitempty"`
+	Attempts   int        `json:"attempts"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+
+	Result *Document `json:"result"`
+}
+
+func newJobDTO(job *repository.Job) Job {
+	jobDTO := Job{
+		JobID:      job.ID.String(),
+		Kind:       job.Kind,
+		Status:     job.Status,
+		PolicyIDs:  make([]string, len(job.PolicyIDs)),
+		FileName:   job.FileName,
+		Error:      job.Error,
+		Attempts:   job.Attempts,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	for i, id := range job.PolicyIDs {
+		jobDTO.PolicyIDs[i] = id.String()
+	}
+	if job.Document != nil {
+		document := newDocumentDTO(*job.Document)
+		jobDTO.Result = &document
+	}
+	return jobDTO
+}
+
+type BatchTotals struct {
+	Jobs                int `json:"jobs"`
+	Queued              int `json:"queued"`
+	Running             int `json:"running"`
+	Done                int `json:"done"`
+	Failed              int `json:"failed"`
+	Compliant           int `json:"compliant"`
+	NonCompliant        int `json:"non_compliant"`
+	HumanReviewRequired int `json:"human_review_required"`
+	AverageCompliance   int `json:"average_compliance_percentage"`
+	AverageViolation    int `json:"average_violation_percentage"`
+}
+
+type Batch struct {
+	BatchID   string      `json:"batch_id"`
+	Status    string      `json:"status"`
+	PolicyIDs []string    `json:"policy_ids"`
+	FileCount int         `json:"file_count"`
+	CreatedAt time.Time   `json:"created_at"`
+	Totals    BatchTotals `json:"totals"`
+	Items     []Job       `json:"items"`
+}
+
+func newBatchDTO(batch *repository.Batch) Batch {
+	batchDTO := Batch{
+		BatchID:   batch.ID.String(),
+		PolicyIDs: make([]string, len(batch.PolicyIDs)),
+		FileCount: batch.FileCount,
+		CreatedAt: batch.CreatedAt,
+		Items:     make([]Job, len(batch.Jobs)),
+	}
+	for i, id := range batch.PolicyIDs {
+		batchDTO.PolicyIDs[i] = id.String()
+	}
+
+	totals := &batchDTO.Totals
+	var complianceSum, violationSum int
+	for i := range batch.Jobs {
+		item := newJobDTO(&batch.Jobs[i])
+		batchDTO.Items[i] = item
+		totals.Jobs++
+
+		switch item.Status {
+		case repository.JobStatusQueued:
+			totals.Queued++
+		case repository.JobStatusDone:
+			totals.Done++
+		case repository.JobStatusFailed:
+			totals.Failed++
+		default:
+			totals.Running++
+		}
+
+		if doc := item.Result; doc != nil {
+			if doc.IsCompliant {
+				totals.Compliant++
+			} else {
+				totals.NonCompliant++
+			}
+			if doc.IsHumanReviewRequired {
+				totals.HumanReviewRequired++
+			}
+			complianceSum += doc.CompliancePercentage
+			violationSum += doc.ViolationPercentage
+		}
+	}
+	if scored := totals.Compliant + totals.NonCompliant; scored > 0 {
+		totals.AverageCompliance = complianceSum / scored
+		totals.AverageViolation = violationSum / scored
+	}
+
+	switch {
+	case totals.Queued == totals.Jobs:
+		batchDTO.Status = repository.JobStatusQueued
+	case totals.Done+totals.Failed == totals.Jobs:
+		batchDTO.Status = repository.JobStatusDone
+	default:
+		batchDTO.Status = "running"
+	}
+
+	return batchDTO
+}
+
+func newRuleResultsDTO(ruleResults []repository.RuleResult) []RuleResult {
+	ruleResultsDTO := make([]RuleResult, len(ruleResults))
+	for j, res := range ruleResults {
+		ruleResultsDTO[j] = RuleResult{
+			RuleUUID:      res.RuleUUID.String(),
+			RuleID:        res.RuleID,
+			Verdict:       res.Verdict,
+			Confidence:    res.Confidence,
+			Evidence:      res.Evidence,
+			EvidenceStart: res.EvidenceStart,
+			EvidenceEnd:   res.EvidenceEnd,
+			Rationale:     res.Rationale,
+		}
+		if res.Rule != nil {
+			ruleResultsDTO[j].Severity = res.Rule.Severity
+			ruleResultsDTO[j].Category = res.Rule.Category
+			ruleResultsDTO[j].Obligation = res.Rule.Obligation
+		}
+	}
+	return ruleResultsDTO
+}
+
+func newPolicyResultsDTO(results []repository.ComplianceResult) []PolicyResult {
+	resultsDTO := make([]PolicyResult, len(results))
+	for i, res := range results {
+		resultsDTO[i] = PolicyResult{
+			PolicyID:              res.PolicyID.String(),
+			PolicyTitle:           res.Policy.Title,
+			Violations:            res.Violations,
+			IsCompliant:           res.IsCompliant,
+			IsHumanReviewRequired: res.IsHumanReviewRequired,
+			CompliancePercentage:  res.CompliancePercentage,
+			ViolationPercentage:   res.ViolationPercentage,
+			PolicyVersion:         res.PolicyVersion,
+			IsStale:               res.IsStale,
+			OutputPath:            res.OutputPath,
+			CheckedAt:             res.CreatedAt,
+			SupersededAt:          res.SupersededAt,
+			RuleResults:           newRuleResultsDTO(res.RuleResults),
+		}
+	}
+	return resultsDTO
+}
+
+func newDocumentDTO(document repository.Document) Document {
+	return Document{
+		DocumentID:            document.ID.String(),
+		Title:                 document.Title,
+		Path:                  document.Path,
+		Extension:             Extension(document.Extension),
+		Violations:            document.Violations,
+		IsCompliant:           document.IsCompliant,
+		IsHumanReviewRequired: document.IsHumanReviewRequired,
+		CompliancePercentage:  document.CompliancePercentage,
+		ViolationPercentage:   document.ViolationPercentage,
+		IsStale:               document.IsStale,
+
+		PolicyTitle: document.Policy.Title,
+		Results:     newPolicyResultsDTO(document.Results),
+	}
+}
+
 type UpdateRuleRequestDTO struct {
-	Title    *string `json:"title,omitempty"`
-	Category *string `json:"category,omitempty"`
-	RuleText *string `json:"rule_text,omitempty"`
+	Category *string  `json:"category,omitempty"`
+	RuleText *string  `json:"rule_text,omitempty"`
+	Severity *string  `json:"severity,omitempty" binding:"omitempty,oneof=critical major minor"`
+	Weight   *float64 `json:"weight,omitempty" binding:"omitempty,gt=0"`
+
+	Obligation    *string `json:"obligation,omitempty" binding:"omitempty,oneof=must must-not should may"`
+	ParentRuleID  *string `json:"parent_rule_id,omitempty"`
+	Applicability *string `json:"applicability,omitempty"`
+}
+
+type UpdatePolicyRequestDTO struct {
+	Title         *string `json:"title,omitempty"`
+	Category      *string `json:"category,omitempty"`
+	PassThreshold *int    `json:"pass_threshold,omitempty" binding:"omitempty,min=0,max=100"`
+}
+
+type RuleSnapshot struct {
+	RuleUUID string  `json:"rule_uuid"`
+	RuleID   string  `json:"rule_id"`
+	RuleText string  `json:"rule_text"`
+	Severity string  `json:"severity"`
+	Weight   float64 `json:"weight"`
+
+	Category      string `json:"category"`
+	Obligation    string `json:"obligation"`
+	ParentRuleID  string `json:"parent_rule_id"`
+	Applicability string `json:"applicability"`
+}
+
+type PolicyVersion struct {
+	Version   int            `json:"version"`
+	Source    string         `json:"source"`
+	Path      string         `json:"path"`
+	Extension Extension      `json:"extension"`
+	CreatedAt time.Time      `json:"created_at"`
+	Rules     []RuleSnapshot `json:"rules"`
+}
+
+type GetPolicyVersionsResponseDTO struct {
+	PolicyID string          `json:"policy_id"`
+	Versions []PolicyVersion `json:"versions"`
+}
+
+type RuleDiff struct {
+	Before RuleSnapshot `json:"before"`
+	After  RuleSnapshot `json:"after"`
+}
+
+type PolicyDiff struct {
+	PolicyID  string         `json:"policy_id"`
+	From      int            `json:"from"`
+	To        int            `json:"to"`
+	Added     []RuleSnapshot `json:"added"`
+	Removed   []RuleSnapshot `json:"removed"`
+	Modified  []RuleDiff     `json:"modified"`
+	Unchanged int            `json:"unchanged"`
+}
+
+type PolicyDiffRequestDTO struct {
+	From int `form:"from" binding:"required,min=1"`
+	To   int `form:"to" binding:"required,min=1"`
+}
+
+func newRuleSnapshotDTO(rule repository.RuleSnapshot) RuleSnapshot {
+	return RuleSnapshot{
+		RuleUUID: rule.RuleUUID.String(),
+		RuleID:   rule.RuleID,
+		RuleText: rule.RuleText,
+		Severity: rule.Severity,
+		Weight:   rule.Weight,
+
+		Category:      rule.Category,
+		Obligation:    rule.Obligation,
+		ParentRuleID:  rule.ParentRuleID,
+		Applicability: rule.Applicability,
+	}
+}
+
+func newRuleSnapshotsDTO(rules []repository.RuleSnapshot) []RuleSnapshot {
+	rulesDTO := make([]RuleSnapshot, len(rules))
+	for i, rule := range rules {
+		rulesDTO[i] = newRuleSnapshotDTO(rule)
+	}
+	return rulesDTO
+}
+
+func newPolicyDiffDTO(diff *service.PolicyDiff) PolicyDiff {
+	modified := make([]RuleDiff, len(diff.Modified))
+	for i, m := range diff.Modified {
+		modified[i] = RuleDiff{
+			Before: newRuleSnapshotDTO(m.Before),
+			After:  newRuleSnapshotDTO(m.After),
+		}
+	}
+	return PolicyDiff{
+		PolicyID:  diff.PolicyID.String(),
+		From:      diff.From,
+		To:        diff.To,
+		Added:     newRuleSnapshotsDTO(diff.Added),
+		Removed:   newRuleSnapshotsDTO(diff.Removed),
+		Modified:  modified,
+		Unchanged: diff.Unchanged,
+	}
+}
+
+// SearchRequestDTO takes q in web search syntax: "quoted phrases", -word to
+// exclude, or between alternatives.
+type SearchRequestDTO struct {
+	Query    string `form:"q"         binding:"required"`
+	Type     string `form:"type,default=all" binding:"oneof=all rules documents"`
+	PolicyID string `form:"policy_id" binding:"omitempty,uuid"`
+	Category string `form:"category"`
+	Limit    int    `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+func (r SearchRequestDTO) query() repository.SearchQuery {
+	query := repository.SearchQuery{
+		Text:     r.Query,
+		Category: r.Category,
+		Limit:    r.Limit,
+	}
+	if id, err := uuid.Parse(r.PolicyID); err == nil {
+		query.PolicyID = &id
+	}
+	return query
+}
+
+// Snippets are HTML-escaped, with matched terms wrapped in <mark> tags.
+type RuleHit struct {
+	ID          string  `json:"id"`
+	RuleID      string  `json:"rule_id"`
+	RuleText    string  `json:"rule_text"`
+	PolicyID    string  `json:"policy_id"`
+	PolicyTitle string  `json:"policy_title"`
+	Category    string  `json:"category"`
+	Rank        float64 `json:"rank"`
+	Snippet     string  `json:"snippet"`
+}
+
+type DocumentHit struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	Rank      float64   `json:"rank"`
+	Snippet   string    `json:"snippet"`
+}
+
+type SearchResponseDTO struct {
+	Rules     []RuleHit     `json:"rules"`
+	Documents []DocumentHit `json:"documents"`
+}
+
+func newSearchResponseDTO(results *service.SearchResults) SearchResponseDTO {
+	response := SearchResponseDTO{
+		Rules:     make([]RuleHit, len(results.Rules)),
+		Documents: make([]DocumentHit, len(results.Documents)),
+	}
+	for i, hit := range results.Rules {
+		response.Rules[i] = RuleHit{
+			ID:          hit.RuleUUID.String(),
+			RuleID:      hit.RuleID,
+			RuleText:    hit.RuleText,
+			PolicyID:    hit.PolicyID.String(),
+			PolicyTitle: hit.PolicyTitle,
+			Category:    hit.Category,
+			Rank:        hit.Rank,
+			Snippet:     hit.Snippet,
+		}
+	}
+	for i, hit := range results.Documents {
+		response.Documents[i] = DocumentHit{
+			ID:        hit.DocumentID.String(),
+			Title:     hit.Title,
+			CreatedAt: hit.CreatedAt,
+			Rank:      hit.Rank,
+			Snippet:   hit.Snippet,
+		}
+	}
+	return response
+}
+
+// CreateAPIKeyRequestDTO sets daily quotas; 0 means unlimited.
+type CreateAPIKeyRequestDTO struct {
+	Name         string `json:"name"          binding:"required,max=255"`
+	Role         string `json:"role"          binding:"required,oneof=admin policy_author reviewer submitter read_only"`
+	RequestQuota int    `json:"request_quota" binding:"min=0"`
+	TokenQuota   int64  `json:"token_quota"   binding:"min=0"`
+}
+
+// APIKey reports today's usage (UTC); counts from an earlier day read as 0.
+type APIKey struct {
+	ID            string     `json:"id"`
+	WorkspaceID   string     `json:"workspace_id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`
+	Role          string     `json:"role"`
+	RequestQuota  int        `json:"request_quota"`
+	TokenQuota    int64      `json:"token_quota"`
+	RequestsToday int        `json:"requests_today"`
+	TokensToday   int64      `json:"tokens_today"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyResponseDTO is the only response that includes the key.
+type CreateAPIKeyResponseDTO struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+func newAPIKeyDTO(key *repository.APIKey) APIKey {
+	apiKey := APIKey{
+		ID:           key.ID.String(),
+		WorkspaceID:  key.WorkspaceID.String(),
+		Name:         key.Name,
+		Prefix:       key.Prefix,
+		Role:         key.Role,
+		RequestQuota: key.RequestQuota,
+		TokenQuota:   key.TokenQuota,
+		LastUsedAt:   key.LastUsedAt,
+		RevokedAt:    key.RevokedAt,
+		CreatedAt:    key.CreatedAt,
+	}
+	if !key.UsageDay.Before(time.Now().UTC().Truncate(24 * time.Hour)) {
+		apiKey.RequestsToday = key.RequestCount
+		apiKey.TokensToday = key.TokenCount
+	}
+	return apiKey
+}
+
+type WorkspaceRequestDTO struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type AddWorkspaceMemberRequestDTO struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+	Name  string `json:"name" binding:"max=255"`
+	Role  string `json:"role" binding:"required,oneof=admin policy_author reviewer submitter read_only"`
+}
+
+type Workspace struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateWorkspaceResponseDTO includes the workspace's first API key, which
+// like any new key is only shown once.
+type CreateWorkspaceResponseDTO struct {
+	Workspace
+	APIKey CreateAPIKeyResponseDTO `json:"api_key"`
+}
+
+type WorkspaceMember struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newWorkspaceDTO(workspace *repository.Workspace) Workspace {
+	return Workspace{
+		ID:        workspace.ID.String(),
+		Name:      workspace.Name,
+		CreatedAt: workspace.CreatedAt,
+	}
+}
+
+func newWorkspaceMemberDTO(member *repository.WorkspaceMember) WorkspaceMember {
+	return WorkspaceMember{
+		UserID:    member.UserID.String(),
+		Email:     member.User.Email,
+		Name:      member.User.Name,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt,
+	}
 }
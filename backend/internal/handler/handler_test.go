@@ -0,0 +1,369 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/blob"
+	"policy-match/internal/client/llm"
+	"policy-match/internal/client/tika"
+	"policy-match/internal/config"
+	"policy-match/internal/middleware"
+	"policy-match/internal/repository"
+	"policy-match/internal/service"
+	"policy-match/internal/utils"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+var bundleOnce sync.Once
+
+// loadBundle loads the English messages, which utils.Init would look for
+// relative to the module root.
+func loadBundle() {
+	bundleOnce.Do(func() {
+		utils.Bundle = i18n.NewBundle(language.English)
+		utils.Bundle.RegisterUnmarshalFunc("json", json.Unmarshal)
+		utils.Bundle.MustLoadMessageFile("../locales/en.json")
+	})
+}
+
+type nopQueue struct{}
+
+func (nopQueue) Notify() {}
+
+// testPolicyText is extracted by the fake LLM as rules "1" and "2".
+const testPolicyText = "Staff must wear badges. Visitors must sign in."
+
+// testServer runs the API behind API key auth, on sqlite, a local blob
+// store, a Tika stand-in that returns uploads as their text, and a fake
+// LLM that answers each rule with verdicts[rule_id].
+type testServer struct {
+	t        *testing.T
+	repo     repository.Repository
+	service  *service.Service
+	router   *gin.Engine
+	verdicts map[string]string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	loadBundle()
+	gin.SetMode(gin.TestMode)
+
+	tikaSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode([]map[string]any{{
+			"Content-Type":   "text/plain",
+			"X-TIKA:content": string(body),
+			"dc:language":    "en",
+		}})
+	}))
+	t.Cleanup(tikaSrv.Close)
+
+	cfg := &config.Config{
+		DBDriver:                repository.DriverSQLite,
+		DBURL:                   ":memory:",
+		DBAutoMigrate:           true,
+		TikaURL:                 tikaSrv.URL,
+		LLMChunkTokens:          1000,
+		LLMSectionTokens:        1000,
+		LLMConcurrency:          1,
+		SeverityWeights:         map[string]float64{repository.SeverityCritical: 3, repository.SeverityMajor: 2, repository.SeverityMinor: 1},
+		ObligationWeights:       map[string]float64{},
+		CompliancePassThreshold: 80,
+		ReviewConfidence:        0.5,
+		JobAPIKeyTTL:            time.Hour,
+		BatchMaxFiles:           10,
+		BatchMaxFileBytes:       1 << 20,
+		BatchMaxZipBytes:        1 << 20,
+		BatchMaxTotalBytes:      1 << 20,
+	}
+	repo, err := repository.NewRepository(cfg)
+	if err != nil {
+		t.Fatalf("newRepository: %v", err)
+	}
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newLocalStore: %v", err)
+	}
+
+	ts := &testServer{t: t, repo: repo, verdicts: map[string]string{"1": "pass", "2": "pass"}}
+	provider := llm.NewFakeProvider(ts.reply)
+	ts.service = service.NewService(cfg, llm.NewLLMClient(cfg, repo, provider, nil), tika.NewTikaClient(cfg), repo, blobs, nopQueue{})
+
+	ts.router = gin.New()
+	ts.router.Use(middleware.LocaleMiddleware(utils.Bundle), middleware.ErrorHandler(utils.Bundle))
+	RegisterRoutes(ts.router, NewHandler(ts.service), middleware.APIKeyAuth(utils.Bundle, ts.service))
+	return ts
+}
+
+// reply answers rule extraction with rules "1" and "2", and compliance
+// checks from verdicts.
+func (ts *testServer) reply(req llm.ChatRequest) (string, error) {
+	if _, ok := req.ResponseFormat.JsonSchema.Schema.Properties["rules"]; ok {
+		return `{"rules": [
+			{"rule_id": "1", "rule_text": "Staff must wear badges.", "part": 1, "severity": "critical",
+			 "category": "Physical", "obligation": "must", "parent_rule_id": "", "applicability": ""},
+			{"rule_id": "2", "rule_text": "Visitors must sign in.", "part": 1, "severity": "major",
+			 "category": "Physical", "obligation": "must", "parent_rule_id": "", "applicability": ""}]}`, nil
+	}
+
+	results := make([]map[string]any, 0, len(ts.verdicts))
+	for id, verdict := range ts.verdicts {
+		results = append(results, map[string]any{
+			"rule_id": id, "verdict": verdict, "confidence": 0.9, "evidence": "", "rationale": "stated",
+		})
+	}
+	raw, err := json.Marshal(map[string]any{"results": results, "is_human_review_required": false})
+	return string(raw), err
+}
+
+// key creates an API key with role in workspaceID and returns its token.
+func (ts *testServer) key(workspaceID uuid.UUID, role string) string {
+	ts.t.Helper()
+	ctx := repository.WithWorkspace(context.Background(), workspaceID)
+	_, token, err := ts.service.CreateAPIKey(ctx, role, role, 0, 0)
+	if err != nil {
+		ts.t.Fatalf("createAPIKey: %v", err)
+	}
+	return token
+}
+
+// do sends a request with token as the bearer key.
+func (ts *testServer) do(method string, path string, token string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	req := httptest.NewRequest(method, path, body)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept-Language", "en")
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+	return w
+}
+
+// upload posts a multipart form with content as its "file" field.
+func (ts *testServer) upload(path string, token string, filename string, content string, fields map[string]string) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		ts.t.Fatalf("createFormFile: %v", err)
+	}
+	part.Write([]byte(content))
+	w.Close()
+	return ts.do(http.MethodPost, path, token, &body, w.FormDataContentType())
+}
+
+// uploadPolicy creates a policy through the API and returns its ID.
+func (ts *testServer) uploadPolicy(token string, title string) string {
+	ts.t.Helper()
+	w := ts.upload("/api/v1/policy", token, title+".txt", testPolicyText, map[string]string{"title": title, "category": "security"})
+	var res struct{ Data Policy }
+	ts.decode(w, http.StatusOK, &res)
+	return res.Data.PolicyID
+}
+
+// checkDocument submits a document, runs its job and returns its ID.
+func (ts *testServer) checkDocument(token string, policyID string, content string) string {
+	ts.t.Helper()
+	w := ts.upload("/api/v1/document", token, "handbook.txt", content, map[string]string{"policy_id": policyID})
+	var res struct{ Data Job }
+	ts.decode(w, http.StatusAccepted, &res)
+	ts.runJobs()
+	return res.Data.JobID
+}
+
+// runJobs processes queued jobs the way a worker would, until none are
+// left.
+func (ts *testServer) runJobs() {
+	ts.t.Helper()
+	ctx := context.Background()
+	for {
+		job, err := ts.repo.ClaimNextJob(ctx, time.Minute)
+		if err != nil {
+			ts.t.Fatalf("claimNextJob: %v", err)
+		}
+		if job == nil {
+			return
+		}
+		if err := ts.service.ProcessJob(ctx, job); err != nil {
+			ts.t.Fatalf("processJob: %v", err)
+		}
+		err = ts.repo.UpdateJob(ctx, job.ID, map[string]any{"status": repository.JobStatusDone, "lease_expires_at": nil})
+		if err != nil {
+			ts.t.Fatalf("updateJob: %v", err)
+		}
+		ts.service.ReleaseJob(job.ID)
+	}
+}
+
+// decode checks the status and decodes the JSON body into v.
+func (ts *testServer) decode(w *httptest.ResponseRecorder, status int, v any) {
+	ts.t.Helper()
+	if w.Code != status {
+		ts.t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		ts.t.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+}
+
+// errorCode returns the error code of an error response.
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var res struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+	return res.Error.Code
+}
+
+func jsonBody(t *testing.T, v any) io.Reader {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return bytes.NewReader(raw)
+}
+
+func path(format string, args ...any) string {
+	return "/api/v1" + fmt.Sprintf(format, args...)
+}
+
+func TestGetDocumentsFilters(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+	policyID := ts.uploadPolicy(admin, "security")
+	otherID := ts.uploadPolicy(admin, "privacy")
+	passing := ts.checkDocument(admin, policyID, "Staff wear badges.")
+	ts.verdicts["2"] = "fail"
+	failing := ts.checkDocument(admin, otherID, "Visitors walk in.")
+	today := time.Now().UTC().Format("2006-01-02")
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"no filter", "", []string{failing, passing}},
+		{"policy", "policy_id=" + policyID, []string{passing}},
+		{"compliant", "compliant=true", []string{passing}},
+		{"not compliant and max compliance", "compliant=false&max_compliance=60", []string{failing}},
+		{"min compliance", "min_compliance=100", []string{passing}},
+		{"policy and not compliant", "policy_id=" + policyID + "&compliant=false", []string{}},
+		{"created today", "created_from=" + today + "&created_to=" + today, []string{failing, passing}},
+		{"created before today", "created_to=2000-01-01", []string{}},
+		{"oldest first", "dir=asc", []string{passing, failing}},
+		{"title query", "q=HANDBOOK&page_size=1", []string{failing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res struct{ Data GetDocumentsResponseDTO }
+			ts.decode(ts.do(http.MethodGet, path("/documents?%s", tt.query), admin, nil, ""), http.StatusOK, &res)
+			got := make([]string, len(res.Data.Documents))
+			for i, d := range res.Data.Documents {
+				got[i] = d.DocumentID
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("documents = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListInvalidFilters(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+
+	for _, query := range []string{
+		"/documents?policy_id=not-a-uuid",
+		"/documents?compliant=maybe",
+		"/documents?human_review=2",
+		"/documents?min_compliance=-1",
+		"/documents?max_compliance=101",
+		"/documents?min_compliance=ten",
+		"/documents?sort=category",
+		"/documents?dir=sideways",
+		"/documents?created_from=01-02-2024",
+		"/documents?created_to=2024-13-01",
+		"/documents?page=0",
+		"/documents?page_size=51",
+		"/policies?sort=compliance_percentage",
+		"/policies?created_from=yesterday",
+		"/policies?page_size=0",
+	} {
+		t.Run(query, func(t *testing.T) {
+			w := ts.do(http.MethodGet, path("%s", query), admin, nil, "")
+			if w.Code != http.StatusBadRequest || errorCode(t, w) != "request_is_invalid" {
+				t.Errorf("response = %d %s, want 400 request_is_invalid", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestEditUnknownRule(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+	policyID := ts.uploadPolicy(admin, "security")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   map[string]any
+		status int
+		code   string
+	}{
+		{"update unknown rule", http.MethodPatch, path("/policy/%s/rule/9", policyID), map[string]any{"rule_text": "Anything goes."}, http.StatusNotFound, "rule_not_found"},
+		{"delete unknown rule", http.MethodDelete, path("/policy/%s/rule/9", policyID), nil, http.StatusNotFound, "rule_not_found"},
+		{"update unknown policy", http.MethodPatch, path("/policy/%s/rule/1", uuid.NewString()), map[string]any{"rule_text": "Anything goes."}, http.StatusNotFound, "policy_not_found"},
+		{"update title only", http.MethodPatch, path("/policy/%s/rule/1", policyID), map[string]any{"title": "Badges"}, http.StatusBadRequest, "no_fields_to_update"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != nil {
+				body = jsonBody(t, tt.body)
+			}
+			w := ts.do(tt.method, tt.path, admin, body, "application/json")
+			if w.Code != tt.status || errorCode(t, w) != tt.code {
+				t.Errorf("response = %d %s, want %d %s", w.Code, w.Body.String(), tt.status, tt.code)
+			}
+		})
+	}
+
+	var policy struct{ Data Policy }
+	ts.decode(ts.do(http.MethodGet, path("/policy/%s", policyID), admin, nil, ""), http.StatusOK, &policy)
+	if policy.Data.Version != 1 || len(policy.Data.Rules) != 2 {
+		t.Errorf("policy = version %d with %d rules, want version 1 with 2", policy.Data.Version, len(policy.Data.Rules))
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"policy-match/internal/apperr"
+	"policy-match/internal/dto"
+	"policy-match/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) HandleRecheckDocument(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		ctx = context.WithValue(ctx, dto.UserAPIKeyContext, apiKey)
+	}
+
+	job, err := h.service.RecheckDocument(ctx, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(202, NewResponse(newJobDTO(job), utils.Localize(c, "document_queued_for_recheck")))
+}
+
+func (h *Handler) HandleRecheckPolicy(c *gin.Context) {
+	var request RecheckPolicyRequestDTO
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		ctx = context.WithValue(ctx, dto.UserAPIKeyContext, apiKey)
+	}
+
+	batch, err := h.service.RecheckPolicy(ctx, id, request.StaleOnly)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(202, NewResponse(newBatchDTO(batch), utils.Localize(c, "documents_queued_for_recheck")))
+}
+
+func (h *Handler) HandleGetDocumentHistory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	results, err := h.service.GetResultHistory(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(DocumentHistory{
+		DocumentID: id.String(),
+		Results:    newPolicyResultsDTO(results),
+	}, utils.Localize(c, "document_history_fetched_successfully")))
+}
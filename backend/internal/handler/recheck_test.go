@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"net/http"
+	"policy-match/internal/repository"
+	"testing"
+)
+
+func TestRecheckPolicyAfterEdit(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+
+	policyID := ts.uploadPolicy(admin, "security")
+	otherID := ts.uploadPolicy(admin, "privacy")
+	ts.verdicts["2"] = "fail"
+	documentID := ts.checkDocument(admin, policyID, "Staff wear badges.")
+	otherDocumentID := ts.checkDocument(admin, otherID, "Staff wear badges.")
+
+	var doc struct{ Data DocumentDetail }
+	ts.decode(ts.do(http.MethodGet, path("/document/%s", documentID), admin, nil, ""), http.StatusOK, &doc)
+	if doc.Data.IsStale || doc.Data.IsCompliant {
+		t.Fatalf("document = %+v, want a fresh failing result", doc.Data.Document)
+	}
+
+	// Nothing is stale before the policy changes.
+	w := ts.do(http.MethodPost, path("/policy/%s/recheck?stale_only=true", policyID), admin, nil, "")
+	if w.Code != http.StatusBadRequest || errorCode(t, w) != "no_documents_to_recheck" {
+		t.Fatalf("recheck before edit = %d %s, want no_documents_to_recheck", w.Code, w.Body.String())
+	}
+
+	w = ts.do(http.MethodPatch, path("/policy/%s/rule/2", policyID), admin,
+		jsonBody(t, map[string]any{"rule_text": "Visitors must sign in at reception."}), "application/json")
+	ts.decode(w, http.StatusOK, nil)
+
+	ts.decode(ts.do(http.MethodGet, path("/document/%s", documentID), admin, nil, ""), http.StatusOK, &doc)
+	if !doc.Data.IsStale {
+		t.Fatal("document not stale after its policy was edited")
+	}
+	ts.decode(ts.do(http.MethodGet, path("/document/%s", otherDocumentID), admin, nil, ""), http.StatusOK, &doc)
+	if doc.Data.IsStale {
+		t.Fatal("editing one policy marked another policy's document stale")
+	}
+
+	var batch struct{ Data Batch }
+	w = ts.do(http.MethodPost, path("/policy/%s/recheck?stale_only=true", policyID), admin, nil, "")
+	ts.decode(w, http.StatusAccepted, &batch)
+	if batch.Data.FileCount != 1 || len(batch.Data.Items) != 1 {
+		t.Fatalf("batch = %+v, want one job for the stale document", batch.Data)
+	}
+
+	ts.verdicts["2"] = "pass"
+	ts.runJobs()
+
+	ts.decode(ts.do(http.MethodGet, path("/document/%s", documentID), admin, nil, ""), http.StatusOK, &doc)
+	if doc.Data.IsStale || !doc.Data.IsCompliant || doc.Data.CompliancePercentage != 100 {
+		t.Errorf("document after recheck = %+v, want a fresh passing result", doc.Data.Document)
+	}
+
+	var history struct{ Data DocumentHistory }
+	ts.decode(ts.do(http.MethodGet, path("/document/%s/history", documentID), admin, nil, ""), http.StatusOK, &history)
+	if len(history.Data.Results) != 2 {
+		t.Fatalf("history has %d results, want 2", len(history.Data.Results))
+	}
+	current, old := history.Data.Results[0], history.Data.Results[1]
+	if current.PolicyVersion != 2 || current.SupersededAt != nil || !current.IsCompliant {
+		t.Errorf("current result = %+v, want version 2, passing and not superseded", current)
+	}
+	if old.PolicyVersion != 1 || old.SupersededAt == nil || old.IsCompliant {
+		t.Errorf("old result = %+v, want version 1, failing and superseded", old)
+	}
+
+	w = ts.do(http.MethodPost, path("/policy/%s/recheck?stale_only=true", policyID), admin, nil, "")
+	if w.Code != http.StatusBadRequest || errorCode(t, w) != "no_documents_to_recheck" {
+		t.Errorf("recheck after recheck = %d %s, want no_documents_to_recheck", w.Code, w.Body.String())
+	}
+
+	// Without stale_only every document of the policy is re-checked.
+	ts.decode(ts.do(http.MethodPost, path("/policy/%s/recheck", policyID), admin, nil, ""), http.StatusAccepted, &batch)
+	if batch.Data.FileCount != 1 {
+		t.Errorf("batch has %d files, want 1", batch.Data.FileCount)
+	}
+}
+
+func TestRecheckDocument(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+	policyID := ts.uploadPolicy(admin, "security")
+	documentID := ts.checkDocument(admin, policyID, "Staff wear badges.")
+
+	var job struct{ Data Job }
+	ts.decode(ts.do(http.MethodPost, path("/document/%s/recheck", documentID), admin, nil, ""), http.StatusAccepted, &job)
+	if job.Data.Kind != repository.JobKindRecheck || len(job.Data.PolicyIDs) != 1 || job.Data.PolicyIDs[0] != policyID {
+		t.Fatalf("job = %+v, want a recheck against %s", job.Data, policyID)
+	}
+	ts.runJobs()
+
+	var history struct{ Data DocumentHistory }
+	ts.decode(ts.do(http.MethodGet, path("/document/%s/history", documentID), admin, nil, ""), http.StatusOK, &history)
+	if len(history.Data.Results) != 2 {
+		t.Errorf("history has %d results, want 2", len(history.Data.Results))
+	}
+}
+
+func TestRecheckInvalidRequests(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   string
+	}{
+		{"invalid policy id", path("/policy/not-a-uuid/recheck"), http.StatusBadRequest, "policy_id_is_required"},
+		{"invalid stale_only", path("/policy/%s/recheck?stale_only=maybe", repository.DefaultWorkspaceID), http.StatusBadRequest, "request_is_invalid"},
+		{"unknown policy", path("/policy/%s/recheck", repository.DefaultWorkspaceID), http.StatusNotFound, "policy_not_found"},
+		{"invalid document id", path("/document/not-a-uuid/recheck"), http.StatusBadRequest, "request_is_invalid"},
+		{"unknown document", path("/document/%s/recheck", repository.DefaultWorkspaceID), http.StatusNotFound, "document_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(http.MethodPost, tt.path, admin, nil, "")
+			if w.Code != tt.status || errorCode(t, w) != tt.code {
+				t.Errorf("response = %d %s, want %d %s", w.Code, w.Body.String(), tt.status, tt.code)
+			}
+		})
+	}
+}
@@ -1,24 +1,66 @@
 package handler
 
 import (
+	"policy-match/internal/middleware"
+	"policy-match/internal/utils"
+
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(r *gin.Engine, h *Handler) {
-	api := r.Group("/api/v1")
+// RegisterRoutes mounts the API. Every route except health runs behind
+// auth, which must set the caller's role for the per-route permissions.
+func RegisterRoutes(r *gin.Engine, h *Handler, auth ...gin.HandlerFunc) {
+	r.GET("/api/v1/health", h.HandleGetHealth)
+
+	read := middleware.Require(utils.Bundle, middleware.PermRead)
+	writePolicies := middleware.Require(utils.Bundle, middleware.PermWritePolicies)
+	submit := middleware.Require(utils.Bundle, middleware.PermSubmitDocuments)
+	review := middleware.Require(utils.Bundle, middleware.PermReviewDocuments)
+	manage := middleware.Require(utils.Bundle, middleware.PermManageWorkspace)
+
+	api := r.Group("/api/v1", auth...)
 	{
-		api.GET("/health", h.HandleGetHealth)
+		api.POST("/policy", writePolicies, h.HandleUploadPolicy)
+		api.POST("/document", submit, h.HandleCheckDocumentCompliance)
+		api.GET("/jobs/:id", read, h.HandleGetJob)
+
+		api.POST("/batch", submit, h.HandleSubmitBatch)
+		api.GET("/batch/:id", read, h.HandleGetBatch)
+		api.GET("/batch/:id/export", read, h.HandleExportBatch)
+
+		api.GET("/documents", read, h.HandleGetDocuments)
+		api.GET("/policies", read, h.HandleGetPolicies)
+		api.GET("/search", read, h.HandleSearch)
+
+		api.GET("/policy/:id", read, h.HandleGetPolicy)
+		api.GET("/document/:id", read, h.HandleGetDocument)
+		api.GET("/document/:id/history", read, h.HandleGetDocumentHistory)
+		api.POST("/document/:id/recheck", submit, h.HandleRecheckDocument)
+		api.POST("/policy/:id/recheck", review, h.HandleRecheckPolicy)
+
+		api.GET("/policy/:id/versions", read, h.HandleGetPolicyVersions)
+		api.GET("/policy/:id/diff", read, h.HandleDiffPolicyVersions)
+
+		api.GET("/policy/:id/file", read, h.HandleDownloadPolicy)
+		api.GET("/document/:id/file", read, h.HandleDownloadDocument)
 
-		api.POST("/policy", h.HandleUploadPolicy)
-		api.POST("/document", h.HandleCheckDocumentCompliance)
+		api.DELETE("/document/:id", review, h.HandleDeleteDocument)
+		api.DELETE("/policy/:id", writePolicies, h.HandleDeletePolicy)
+		api.PATCH("/policy/:id", writePolicies, h.HandleUpdatePolicy)
 
-		api.GET("/documents", h.HandleGetDocuments)
-		api.GET("/policies", h.HandleGetPolicies)
+		api.DELETE("/policy/:id/rule/:rule_id", writePolicies, h.HandleDeleteRule)
+		api.PATCH("/policy/:id/rule/:rule_id", writePolicies, h.HandleUpdateRule)
 
-		api.DELETE("/document/:id", h.HandleDeleteDocument)
-		api.DELETE("/policy/:id", h.HandleDeletePolicy)
+		api.POST("/api-keys", manage, h.HandleCreateAPIKey)
+		api.GET("/api-keys", manage, h.HandleGetAPIKeys)
+		api.DELETE("/api-keys/:id", manage, h.HandleRevokeAPIKey)
 
-		api.DELETE("/policy/:id/rule/:rule_id", h.HandleDeleteRule)
-		api.PATCH("/policy/:id/rule/:rule_id", h.HandleUpdateRule)
+		api.POST("/workspaces", manage, h.HandleCreateWorkspace)
+		api.GET("/workspaces", read, h.HandleGetWorkspaces)
+		api.GET("/workspaces/:id", read, h.HandleGetWorkspace)
+		api.PATCH("/workspaces/:id", manage, h.HandleUpdateWorkspace)
+		api.GET("/workspaces/:id/members", manage, h.HandleGetWorkspaceMembers)
+		api.POST("/workspaces/:id/members", manage, h.HandleAddWorkspaceMember)
+		api.DELETE("/workspaces/:id/members/:user_id", manage, h.HandleRemoveWorkspaceMember)
 	}
 }
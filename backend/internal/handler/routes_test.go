@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"policy-match/internal/repository"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// workspace creates a workspace and returns its ID.
+func (ts *testServer) workspace(name string) uuid.UUID {
+	ts.t.Helper()
+	ws := &repository.Workspace{BaseModel: repository.BaseModel{ID: uuid.New()}, Name: name}
+	if err := ts.repo.CreateWorkspace(context.Background(), ws); err != nil {
+		ts.t.Fatalf("createWorkspace: %v", err)
+	}
+	return ws.ID
+}
+
+// submitBatch submits contents as one batch against policyID, runs its
+// jobs and returns its ID.
+func (ts *testServer) submitBatch(token string, policyID string, contents ...string) string {
+	ts.t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("policy_ids", policyID)
+	for _, content := range contents {
+		part, err := w.CreateFormFile("files", "handbook.txt")
+		if err != nil {
+			ts.t.Fatalf("createFormFile: %v", err)
+		}
+		part.Write([]byte(content))
+	}
+	w.Close()
+
+	var res struct{ Data Batch }
+	ts.decode(ts.do(http.MethodPost, path("/batch"), token, &body, w.FormDataContentType()), http.StatusAccepted, &res)
+	ts.runJobs()
+	return res.Data.BatchID
+}
+
+func TestWorkspaceIsolation(t *testing.T) {
+	ts := newTestServer(t)
+	owner := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+	other := ts.workspace("Other")
+	intruder := ts.key(other, repository.RoleAdmin)
+
+	const secret = "Staff wear badges in the vault."
+	policyID := ts.uploadPolicy(owner, "security")
+	documentID := ts.checkDocument(owner, policyID, secret)
+	batchID := ts.submitBatch(owner, policyID, secret)
+	workspaceID := repository.DefaultWorkspaceID.String()
+
+	tests := []struct {
+		method string
+		path   string
+		body   map[string]any
+		code   string
+	}{
+		{http.MethodGet, path("/policy/%s", policyID), nil, "policy_not_found"},
+		{http.MethodGet, path("/policy/%s/versions", policyID), nil, "policy_not_found"},
+		{http.MethodGet, path("/policy/%s/diff?from=1&to=1", policyID), nil, "policy_not_found"},
+		{http.MethodGet, path("/policy/%s/file", policyID), nil, "policy_not_found"},
+		{http.MethodPatch, path("/policy/%s", policyID), map[string]any{"title": "taken"}, "policy_not_found"},
+		{http.MethodPatch, path("/policy/%s/rule/1", policyID), map[string]any{"rule_text": "Anything goes."}, "policy_not_found"},
+		{http.MethodDelete, path("/policy/%s/rule/1", policyID), nil, "policy_not_found"},
+		{http.MethodPost, path("/policy/%s/recheck", policyID), nil, "policy_not_found"},
+		{http.MethodDelete, path("/policy/%s", policyID), nil, "policy_not_found"},
+		{http.MethodGet, path("/document/%s", documentID), nil, "document_not_found"},
+		{http.MethodGet, path("/document/%s/history", documentID), nil, "document_not_found"},
+		{http.MethodGet, path("/document/%s/file", documentID), nil, "document_not_found"},
+		{http.MethodPost, path("/document/%s/recheck", documentID), nil, "document_not_found"},
+		{http.MethodDelete, path("/document/%s", documentID), nil, "document_not_found"},
+		{http.MethodGet, path("/jobs/%s", documentID), nil, "job_not_found"},
+		{http.MethodGet, path("/batch/%s", batchID), nil, "batch_not_found"},
+		{http.MethodGet, path("/batch/%s/export", batchID), nil, "batch_not_found"},
+		{http.MethodGet, path("/workspaces/%s", workspaceID), nil, "workspace_not_found"},
+		{http.MethodPatch, path("/workspaces/%s", workspaceID), map[string]any{"name": "taken"}, "workspace_not_found"},
+		{http.MethodGet, path("/workspaces/%s/members", workspaceID), nil, "workspace_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != nil {
+				body = jsonBody(t, tt.body)
+			}
+			w := ts.do(tt.method, tt.path, intruder, body, "application/json")
+			if w.Code != http.StatusNotFound || errorCode(t, w) != tt.code {
+				t.Errorf("response = %d %s, want 404 %s", w.Code, w.Body.String(), tt.code)
+			}
+			if strings.Contains(w.Body.String(), "vault") || strings.Contains(w.Body.String(), "badges") {
+				t.Errorf("response leaks the other workspace's data: %s", w.Body.String())
+			}
+		})
+	}
+
+	t.Run("new documents cannot use its policies", func(t *testing.T) {
+		w := ts.upload(path("/document"), intruder, "handbook.txt", secret, map[string]string{"policy_id": policyID})
+		if w.Code != http.StatusNotFound || errorCode(t, w) != "policy_not_found" {
+			t.Errorf("response = %d %s, want 404 policy_not_found", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("lists and search are empty", func(t *testing.T) {
+		var documents struct{ Data GetDocumentsResponseDTO }
+		ts.decode(ts.do(http.MethodGet, path("/documents"), intruder, nil, ""), http.StatusOK, &documents)
+		if documents.Data.Total != 0 || len(documents.Data.Documents) != 0 {
+			t.Errorf("documents = %+v, want none", documents.Data)
+		}
+		var policies struct{ Data GetPoliciesResponseDTO }
+		ts.decode(ts.do(http.MethodGet, path("/policies"), intruder, nil, ""), http.StatusOK, &policies)
+		if policies.Data.Total != 0 || len(policies.Data.Policies) != 0 {
+			t.Errorf("policies = %+v, want none", policies.Data)
+		}
+		var search struct{ Data SearchResponseDTO }
+		ts.decode(ts.do(http.MethodGet, path("/search?q=badges"), intruder, nil, ""), http.StatusOK, &search)
+		if len(search.Data.Rules) != 0 || len(search.Data.Documents) != 0 {
+			t.Errorf("search = %+v, want no hits", search.Data)
+		}
+		var workspaces struct{ Data []Workspace }
+		ts.decode(ts.do(http.MethodGet, path("/workspaces"), intruder, nil, ""), http.StatusOK, &workspaces)
+		if len(workspaces.Data) != 1 || workspaces.Data[0].ID != other.String() {
+			t.Errorf("workspaces = %+v, want only its own", workspaces.Data)
+		}
+		var keys struct{ Data []APIKey }
+		ts.decode(ts.do(http.MethodGet, path("/api-keys"), intruder, nil, ""), http.StatusOK, &keys)
+		if len(keys.Data) != 1 {
+			t.Errorf("api keys = %+v, want only its own", keys.Data)
+		}
+	})
+
+	// The refused writes above changed nothing.
+	var policy struct{ Data Policy }
+	ts.decode(ts.do(http.MethodGet, path("/policy/%s", policyID), owner, nil, ""), http.StatusOK, &policy)
+	if policy.Data.Title != "security" || policy.Data.Version != 1 || len(policy.Data.Rules) != 2 {
+		t.Errorf("policy = %+v, want it untouched", policy.Data)
+	}
+	for _, p := range []string{
+		path("/document/%s", documentID),
+		path("/document/%s/file", documentID),
+		path("/policy/%s/file", policyID),
+		path("/batch/%s", batchID),
+	} {
+		if w := ts.do(http.MethodGet, p, owner, nil, ""); w.Code != http.StatusOK {
+			t.Errorf("owner GET %s = %d, want 200", p, w.Code)
+		}
+	}
+}
+
+func TestRoutePermissions(t *testing.T) {
+	ts := newTestServer(t)
+	const (
+		read = iota
+		writePolicies
+		submit
+		review
+		manage
+	)
+	roles := map[string][]int{
+		repository.RoleAdmin:        {read, writePolicies, submit, review, manage},
+		repository.RolePolicyAuthor: {read, writePolicies},
+		repository.RoleReviewer:     {read, submit, review},
+		repository.RoleSubmitter:    {read, submit},
+		repository.RoleReadOnly:     {read},
+	}
+
+	// Unknown IDs and empty bodies keep allowed requests from changing
+	// anything; they only have to get past Require.
+	id, ws := uuid.NewString(), repository.DefaultWorkspaceID.String()
+	routes := []struct {
+		group  int
+		method string
+		path   string
+	}{
+		{writePolicies, http.MethodPost, path("/policy")},
+		{writePolicies, http.MethodPatch, path("/policy/%s", id)},
+		{writePolicies, http.MethodDelete, path("/policy/%s", id)},
+		{writePolicies, http.MethodPatch, path("/policy/%s/rule/1", id)},
+		{writePolicies, http.MethodDelete, path("/policy/%s/rule/1", id)},
+		{submit, http.MethodPost, path("/document")},
+		{submit, http.MethodPost, path("/batch")},
+		{submit, http.MethodPost, path("/document/%s/recheck", id)},
+		{review, http.MethodPost, path("/policy/%s/recheck", id)},
+		{review, http.MethodDelete, path("/document/%s", id)},
+		{manage, http.MethodPost, path("/api-keys")},
+		{manage, http.MethodGet, path("/api-keys")},
+		{manage, http.MethodDelete, path("/api-keys/%s", id)},
+		{manage, http.MethodPost, path("/workspaces")},
+		{manage, http.MethodPatch, path("/workspaces/%s", ws)},
+		{manage, http.MethodGet, path("/workspaces/%s/members", ws)},
+		{manage, http.MethodPost, path("/workspaces/%s/members", ws)},
+		{manage, http.MethodDelete, path("/workspaces/%s/members/%s", ws, id)},
+		{read, http.MethodGet, path("/jobs/%s", id)},
+		{read, http.MethodGet, path("/batch/%s", id)},
+		{read, http.MethodGet, path("/batch/%s/export", id)},
+		{read, http.MethodGet, path("/documents")},
+		{read, http.MethodGet, path("/policies")},
+		{read, http.MethodGet, path("/search?q=badges")},
+		{read, http.MethodGet, path("/policy/%s", id)},
+		{read, http.MethodGet, path("/policy/%s/versions", id)},
+		{read, http.MethodGet, path("/policy/%s/diff?from=1&to=2", id)},
+		{read, http.MethodGet, path("/policy/%s/file", id)},
+		{read, http.MethodGet, path("/document/%s", id)},
+		{read, http.MethodGet, path("/document/%s/history", id)},
+		{read, http.MethodGet, path("/document/%s/file", id)},
+		{read, http.MethodGet, path("/workspaces")},
+		{read, http.MethodGet, path("/workspaces/%s", ws)},
+	}
+	for role, groups := range roles {
+		token := ts.key(repository.DefaultWorkspaceID, role)
+		for _, route := range routes {
+			t.Run(role+" "+route.method+" "+route.path, func(t *testing.T) {
+				w := ts.do(route.method, route.path, token, nil, "application/json")
+				allowed := slices.Contains(groups, route.group)
+				if denied := w.Code == http.StatusForbidden; denied == allowed {
+					t.Errorf("status = %d %s, want allowed %v", w.Code, w.Body.String(), allowed)
+				}
+				if !allowed && errorCode(t, w) != "permission_denied" {
+					t.Errorf("code = %q, want permission_denied", errorCode(t, w))
+				}
+			})
+		}
+	}
+
+	if w := ts.do(http.MethodGet, path("/policies"), "", nil, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without a key: status = %d, want 401", w.Code)
+	}
+}
+
+func TestSubmitterSeesOwnRows(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+	submitter := ts.key(repository.DefaultWorkspaceID, repository.RoleSubmitter)
+	other := ts.key(repository.DefaultWorkspaceID, repository.RoleSubmitter)
+	reviewer := ts.key(repository.DefaultWorkspaceID, repository.RoleReviewer)
+
+	policyID := ts.uploadPolicy(admin, "security")
+	documentID := ts.checkDocument(submitter, policyID, "Staff wear badges.")
+	batchID := ts.submitBatch(submitter, policyID, "Visitors sign in with badges.")
+
+	own := []struct {
+		method string
+		path   string
+		code   string
+	}{
+		{http.MethodGet, path("/document/%s", documentID), "document_not_found"},
+		{http.MethodGet, path("/document/%s/history", documentID), "document_not_found"},
+		{http.MethodGet, path("/document/%s/file", documentID), "document_not_found"},
+		{http.MethodPost, path("/document/%s/recheck", documentID), "document_not_found"},
+		{http.MethodGet, path("/jobs/%s", documentID), "job_not_found"},
+		{http.MethodGet, path("/batch/%s", batchID), "batch_not_found"},
+		{http.MethodGet, path("/batch/%s/export", batchID), "batch_not_found"},
+	}
+	for _, tt := range own {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := ts.do(tt.method, tt.path, other, nil, "")
+			if w.Code != http.StatusNotFound || errorCode(t, w) != tt.code {
+				t.Errorf("other submitter: response = %d %s, want 404 %s", w.Code, w.Body.String(), tt.code)
+			}
+			if tt.method != http.MethodGet {
+				return
+			}
+			for _, token := range []string{submitter, reviewer} {
+				if w := ts.do(tt.method, tt.path, token, nil, ""); w.Code != http.StatusOK {
+					t.Errorf("status = %d %s, want 200 for the owner and reviewers", w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+
+	total := func(token string) (documents int, hits int) {
+		var list struct{ Data GetDocumentsResponseDTO }
+		ts.decode(ts.do(http.MethodGet, path("/documents"), token, nil, ""), http.StatusOK, &list)
+		var search struct{ Data SearchResponseDTO }
+		ts.decode(ts.do(http.MethodGet, path("/search?q=badges&type=documents"), token, nil, ""), http.StatusOK, &search)
+		return list.Data.Total, len(search.Data.Documents)
+	}
+	if documents, hits := total(submitter); documents != 2 || hits != 2 {
+		t.Errorf("owner sees %d documents and %d search hits, want 2 and 2", documents, hits)
+	}
+	if documents, hits := total(other); documents != 0 || hits != 0 {
+		t.Errorf("other submitter sees %d documents and %d search hits, want none", documents, hits)
+	}
+	if documents, hits := total(reviewer); documents != 2 || hits != 2 {
+		t.Errorf("reviewer sees %d documents and %d search hits, want 2 and 2", documents, hits)
+	}
+
+	// Policies are shared by the workspace.
+	if w := ts.do(http.MethodGet, path("/policy/%s", policyID), other, nil, ""); w.Code != http.StatusOK {
+		t.Errorf("other submitter GET policy = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"policy-match/internal/apperr"
+	"policy-match/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) HandleSearch(c *gin.Context) {
+	var request SearchRequestDTO
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	results, err := h.service.Search(c.Request.Context(), request.query(), request.Type)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(newSearchResponseDTO(results), utils.Localize(c, "search_completed_successfully")))
+}
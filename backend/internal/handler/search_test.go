@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"policy-match/internal/repository"
+	"testing"
+)
+
+func TestSearchFilters(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+	policyID := ts.uploadPolicy(admin, "security")
+	otherID := ts.uploadPolicy(admin, "privacy")
+	ts.checkDocument(admin, policyID, "Staff wear badges.")
+	ts.checkDocument(admin, otherID, "Badges are issued at reception.")
+
+	tests := []struct {
+		name      string
+		query     string
+		rules     int
+		documents int
+	}{
+		{"all", "q=badges", 2, 2},
+		{"rules only", "q=badges&type=rules", 2, 0},
+		{"documents only", "q=badges&type=documents", 0, 2},
+		{"policy", "q=badges&policy_id=" + policyID, 1, 1},
+		{"category", "q=badges&category=security", 2, 2},
+		{"unknown category", "q=badges&category=finance", 0, 0},
+		{"policy and type", "q=reception&type=documents&policy_id=" + otherID, 0, 1},
+		{"limit", "q=badges&limit=1", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res struct{ Data SearchResponseDTO }
+			ts.decode(ts.do(http.MethodGet, path("/search?%s", tt.query), admin, nil, ""), http.StatusOK, &res)
+			if len(res.Data.Rules) != tt.rules || len(res.Data.Documents) != tt.documents {
+				t.Errorf("hits = %d rules, %d documents, want %d, %d", len(res.Data.Rules), len(res.Data.Documents), tt.rules, tt.documents)
+			}
+		})
+	}
+}
+
+func TestSearchInvalidRequests(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.key(repository.DefaultWorkspaceID, repository.RoleAdmin)
+
+	for _, query := range []string{
+		"",
+		"q=",
+		"q=badges&type=policies",
+		"q=badges&policy_id=not-a-uuid",
+		"q=badges&limit=0",
+		"q=badges&limit=101",
+		"q=badges&limit=many",
+	} {
+		t.Run(query, func(t *testing.T) {
+			w := ts.do(http.MethodGet, path("/search?%s", query), admin, nil, "")
+			if w.Code != http.StatusBadRequest || errorCode(t, w) != "request_is_invalid" {
+				t.Errorf("response = %d %s, want 400 request_is_invalid", w.Code, w.Body.String())
+			}
+		})
+	}
+}
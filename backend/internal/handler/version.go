@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"policy-match/internal/apperr"
+	"policy-match/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) HandleGetPolicyVersions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
+		return
+	}
+
+	versions, err := h.service.GetPolicyVersions(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	versionsDTO := make([]PolicyVersion, len(versions))
+	for i, version := range versions {
+		versionsDTO[i] = PolicyVersion{
+			Version:   version.Version,
+			Source:    version.Source,
+			Path:      version.Path,
+			Extension: Extension(version.Extension),
+			CreatedAt: version.CreatedAt,
+			Rules:     newRuleSnapshotsDTO(version.Rules),
+		}
+	}
+
+	c.JSON(200, NewResponse(GetPolicyVersionsResponseDTO{
+		PolicyID: id.String(),
+		Versions: versionsDTO,
+	}, utils.Localize(c, "policy_versions_fetched_successfully")))
+}
+
+func (h *Handler) HandleDiffPolicyVersions(c *gin.Context) {
+	var request PolicyDiffRequestDTO
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("policy_id_is_required", err))
+		return
+	}
+
+	diff, err := h.service.DiffPolicyVersions(c.Request.Context(), id, request.From, request.To)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(newPolicyDiffDTO(diff), utils.Localize(c, "policy_diff_fetched_successfully")))
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"policy-match/internal/apperr"
+	"policy-match/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) HandleCreateWorkspace(c *gin.Context) {
+	var request WorkspaceRequestDTO
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	workspace, key, plaintext, err := h.service.CreateWorkspace(c.Request.Context(), request.Name)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(201, NewResponse(CreateWorkspaceResponseDTO{
+		Workspace: newWorkspaceDTO(workspace),
+		APIKey: CreateAPIKeyResponseDTO{
+			APIKey: newAPIKeyDTO(key),
+			Key:    plaintext,
+		},
+	}, utils.Localize(c, "workspace_created_successfully")))
+}
+
+func (h *Handler) HandleGetWorkspaces(c *gin.Context) {
+	workspaces, err := h.service.GetWorkspaces(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	workspacesDTO := make([]Workspace, len(workspaces))
+	for i := range workspaces {
+		workspacesDTO[i] = newWorkspaceDTO(&workspaces[i])
+	}
+
+	c.JSON(200, NewResponse(workspacesDTO, utils.Localize(c, "workspaces_fetched_successfully")))
+}
+
+func (h *Handler) HandleGetWorkspace(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("workspace_id_is_required", err))
+		return
+	}
+
+	workspace, err := h.service.GetWorkspace(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(newWorkspaceDTO(workspace), utils.Localize(c, "workspace_fetched_successfully")))
+}
+
+func (h *Handler) HandleUpdateWorkspace(c *gin.Context) {
+	var request WorkspaceRequestDTO
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("workspace_id_is_required", err))
+		return
+	}
+
+	workspace, err := h.service.RenameWorkspace(c.Request.Context(), id, request.Name)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(newWorkspaceDTO(workspace), utils.Localize(c, "workspace_updated_successfully")))
+}
+
+func (h *Handler) HandleGetWorkspaceMembers(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("workspace_id_is_required", err))
+		return
+	}
+
+	members, err := h.service.GetWorkspaceMembers(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	membersDTO := make([]WorkspaceMember, len(members))
+	for i := range members {
+		membersDTO[i] = newWorkspaceMemberDTO(&members[i])
+	}
+
+	c.JSON(200, NewResponse(membersDTO, utils.Localize(c, "workspace_members_fetched_successfully")))
+}
+
+func (h *Handler) HandleAddWorkspaceMember(c *gin.Context) {
+	var request AddWorkspaceMemberRequestDTO
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(apperr.InvalidInput("request_is_invalid", err))
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("workspace_id_is_required", err))
+		return
+	}
+
+	member, err := h.service.AddWorkspaceMember(c.Request.Context(), id, request.Email, request.Name, request.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(201, NewResponse(newWorkspaceMemberDTO(member), utils.Localize(c, "workspace_member_added_successfully")))
+}
+
+func (h *Handler) HandleRemoveWorkspaceMember(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("workspace_id_is_required", err))
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.Error(apperr.InvalidInput("user_id_is_required", err))
+		return
+	}
+
+	if err := h.service.RemoveWorkspaceMember(c.Request.Context(), id, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, NewResponse(nil, utils.Localize(c, "workspace_member_removed_successfully")))
+}
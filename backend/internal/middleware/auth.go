@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"policy-match/internal/dto"
+	"policy-match/internal/repository"
+	"policy-match/internal/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/rs/zerolog/log"
+)
+
+// APIKeyAuthenticator resolves a service API key and counts the request
+// against its quota.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*repository.APIKey, error)
+}
+
+// APIKeyAuth requires a service key as "Authorization: Bearer pm_...". The
+// key's ID is added to the request context so LLM tokens are charged to
+// it, and the request is scoped to the key's workspace and role. X-API-Key stays
+// reserved for the caller's own LLM key. Requests an earlier middleware,
+// such as OIDCAuth, already authenticated pass through.
+func APIKeyAuth(b *i18n.Bundle, auth APIKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authenticated(c) {
+			c.Next()
+			return
+		}
+
+		token := bearerToken(c)
+		if token == "" {
+			abort(c, b, http.StatusUnauthorized, "api_key_is_required")
+			return
+		}
+
+		key, err := auth.AuthenticateAPIKey(c.Request.Context(), token)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrInvalidAPIKey):
+				abort(c, b, http.StatusUnauthorized, "api_key_is_invalid")
+			case errors.Is(err, service.ErrQuotaExceeded):
+				abort(c, b, http.StatusTooManyRequests, "api_key_quota_exceeded")
+			default:
+				log.Error().Msg("error: " + err.Error())
+				abort(c, b, http.StatusInternalServerError, "an_error_occurred_while_processing_your_request")
+			}
+			return
+		}
+
+		c.Set("APIKeyID", key.ID)
+		c.Set("WorkspaceID", key.WorkspaceID)
+		ctx := context.WithValue(c.Request.Context(), dto.APIKeyIDContext, key.ID)
+		c.Request = c.Request.WithContext(repository.WithWorkspace(ctx, key.WorkspaceID))
+		setCaller(c, key.Role, &key.ID)
+		c.Next()
+	}
+}
+
+func bearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// abort ends the request with the same {data, message} body the handlers
+// use, plus the message ID as a machine-readable error code.
+func abort(c *gin.Context, b *i18n.Bundle, code int, messageID string) {
+	msg, _ := i18n.NewLocalizer(b, GetLang(c)).Localize(&i18n.LocalizeConfig{MessageID: messageID})
+	c.AbortWithStatusJSON(code, gin.H{"data": nil, "message": msg, "error": gin.H{"code": messageID}})
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/dto"
+	"policy-match/internal/repository"
+	"policy-match/internal/service"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+type stubAPIKeys struct {
+	token string
+	key   *repository.APIKey
+	err   error
+}
+
+func (s *stubAPIKeys) AuthenticateAPIKey(ctx context.Context, token string) (*repository.APIKey, error) {
+	s.token = token
+	return s.key, s.err
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := &repository.APIKey{
+		BaseModel: repository.BaseModel{ID: uuid.New()},
+		Tenant:    repository.Tenant{WorkspaceID: uuid.New()},
+		Role:      repository.RoleReviewer,
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		err           error
+		status        int
+		code          string
+		token         string
+	}{
+		{"valid key", "Bearer pm_secret", nil, http.StatusNoContent, "", "pm_secret"},
+		{"scheme ignores case", "bearer  pm_secret ", nil, http.StatusNoContent, "", "pm_secret"},
+		{"no header", "", nil, http.StatusUnauthorized, "api_key_is_required", ""},
+		{"not bearer", "Basic pm_secret", nil, http.StatusUnauthorized, "api_key_is_required", ""},
+		{"invalid or revoked", "Bearer pm_revoked", service.ErrInvalidAPIKey, http.StatusUnauthorized, "api_key_is_invalid", "pm_revoked"},
+		{"quota exceeded", "Bearer pm_secret", service.ErrQuotaExceeded, http.StatusTooManyRequests, "api_key_quota_exceeded", "pm_secret"},
+		{"store failure", "Bearer pm_secret", errors.New("connection refused"), http.StatusInternalServerError, "an_error_occurred_while_processing_your_request", "pm_secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &stubAPIKeys{key: key, err: tt.err}
+			r := gin.New()
+			r.Use(APIKeyAuth(i18n.NewBundle(language.English), keys))
+			r.GET("/", func(c *gin.Context) {
+				ctx := c.Request.Context()
+				if id, _ := repository.WorkspaceFromContext(ctx); id != key.WorkspaceID {
+					t.Errorf("workspace = %s, want the key's %s", id, key.WorkspaceID)
+				}
+				if id, _ := ctx.Value(dto.APIKeyIDContext).(uuid.UUID); id != key.ID {
+					t.Errorf("api key id = %s, want %s", id, key.ID)
+				}
+				if role := c.GetString("Role"); role != key.Role {
+					t.Errorf("role = %q, want %q", role, key.Role)
+				}
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if keys.token != tt.token {
+				t.Errorf("authenticated %q, want %q", keys.token, tt.token)
+			}
+			if tt.code == "" {
+				return
+			}
+			var body struct {
+				Error struct{ Code string } `json:"error"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if body.Error.Code != tt.code {
+				t.Errorf("code = %q, want %q", body.Error.Code, tt.code)
+			}
+			if tt.err != nil && strings.Contains(w.Body.String(), tt.err.Error()) {
+				t.Errorf("response leaks the error: %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"policy-match/internal/apperr"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/rs/zerolog/log"
+)
+
+var kindStatus = map[apperr.Kind]int{
+	apperr.KindNotFound:        http.StatusNotFound,
+	apperr.KindInvalidInput:    http.StatusBadRequest,
+	apperr.KindConflict:        http.StatusConflict,
+	apperr.KindUpstreamFailure: http.StatusServiceUnavailable,
+	apperr.KindRateLimited:     http.StatusTooManyRequests,
+}
+
+// ErrorHandler writes the response for a handler that ended with
+// c.Error(err). An *apperr.Error picks the status from its kind and the
+// message from its code; anything else is logged and reported as a 500
+// without its details.
+func ErrorHandler(b *i18n.Bundle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+
+		appErr, ok := apperr.As(last.Err)
+		if !ok {
+			log.Error().Str("request_id", c.GetString("RequestID")).Msg("error: " + last.Err.Error())
+			abort(c, b, http.StatusInternalServerError, apperr.CodeInternal)
+			return
+		}
+
+		// Client mistakes are expected; only failures of services we depend
+		// on are worth a warning.
+		event := log.Debug()
+		if appErr.Kind == apperr.KindUpstreamFailure || appErr.Kind == apperr.KindRateLimited {
+			event = log.Warn()
+		}
+		event.Str("request_id", c.GetString("RequestID")).Msg("error: " + appErr.Error())
+		if appErr.Kind == apperr.KindRateLimited && appErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+		}
+		abort(c, b, kindStatus[appErr.Kind], appErr.Code)
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/apperr"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Every cause carries this, so a response containing it leaks.
+	const secret = "pq: password authentication failed for user admin"
+	cause := errors.New(secret)
+
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		code       string
+		retryAfter string
+	}{
+		{"not found", apperr.NotFound("policy_not_found", cause), http.StatusNotFound, "policy_not_found", ""},
+		{"invalid input", apperr.InvalidInput("request_is_invalid", cause), http.StatusBadRequest, "request_is_invalid", ""},
+		{"conflict", apperr.Conflict("workspace_member_exists", cause), http.StatusConflict, "workspace_member_exists", ""},
+		{"upstream failure", apperr.UpstreamFailure("llm_unavailable", cause), http.StatusServiceUnavailable, "llm_unavailable", ""},
+		{"rate limited", apperr.RateLimited("llm_rate_limited", 1500*time.Millisecond, cause), http.StatusTooManyRequests, "llm_rate_limited", "2"},
+		{"rate limited without a wait", apperr.RateLimited("llm_rate_limited", 0, cause), http.StatusTooManyRequests, "llm_rate_limited", ""},
+		{"wrapped", fmt.Errorf("getPolicy :: %w", apperr.NotFound("policy_not_found", cause)), http.StatusNotFound, "policy_not_found", ""},
+		{"without a cause", apperr.InvalidInput("no_fields_to_update", nil), http.StatusBadRequest, "no_fields_to_update", ""},
+		{"plain error", fmt.Errorf("getPolicy :: %w", cause), http.StatusInternalServerError, apperr.CodeInternal, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(ErrorHandler(i18n.NewBundle(language.English)))
+			r.GET("/", func(c *gin.Context) {
+				c.Error(tt.err)
+			})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			var body struct {
+				Data  any                   `json:"data"`
+				Error struct{ Code string } `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode %s: %v", w.Body.String(), err)
+			}
+			if body.Error.Code != tt.code || body.Data != nil {
+				t.Errorf("body = %s, want code %q and no data", w.Body.String(), tt.code)
+			}
+			if strings.Contains(w.Body.String(), "password") || strings.Contains(w.Body.String(), "getPolicy") {
+				t.Errorf("response leaks the error: %s", w.Body.String())
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerKeepsWrittenResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(i18n.NewBundle(language.English)))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusAccepted, "queued")
+		c.Error(errors.New("notify worker: queue closed"))
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusAccepted || w.Body.String() != "queued" {
+		t.Errorf("response = %d %q, want the handler's 202 queued", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"policy-match/internal/client/oidc"
+	"policy-match/internal/repository"
+	"policy-match/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/rs/zerolog/log"
+)
+
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*oidc.Identity, error)
+}
+
+// UserAuthenticator resolves a verified identity to the caller's
+// membership, which carries their workspace and role.
+type UserAuthenticator interface {
+	AuthenticateUser(ctx context.Context, identity oidc.Identity) (*repository.WorkspaceMember, error)
+}
+
+// OIDCAuth accepts "Authorization: Bearer <jwt>" from the configured
+// issuer. Other requests, such as those with an API key, pass through
+// untouched, so it goes before APIKeyAuth in the chain.
+func OIDCAuth(b *i18n.Bundle, verifier TokenVerifier, users UserAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if !oidc.LooksLikeJWT(token) {
+			c.Next()
+			return
+		}
+
+		identity, err := verifier.Verify(c.Request.Context(), token)
+		if err != nil {
+			if errors.Is(err, oidc.ErrInvalidToken) {
+				log.Debug().Err(err).Msg("oidc: rejected token")
+				abort(c, b, http.StatusUnauthorized, "token_is_invalid")
+				return
+			}
+			log.Error().Msg("error: " + err.Error())
+			abort(c, b, http.StatusServiceUnavailable, "identity_provider_unavailable")
+			return
+		}
+
+		member, err := users.AuthenticateUser(c.Request.Context(), *identity)
+		if err != nil {
+			if errors.Is(err, service.ErrNoWorkspaceAccess) {
+				abort(c, b, http.StatusForbidden, "workspace_access_denied")
+				return
+			}
+			log.Error().Msg("error: " + err.Error())
+			abort(c, b, http.StatusInternalServerError, "an_error_occurred_while_processing_your_request")
+			return
+		}
+
+		c.Set("UserID", member.UserID)
+		c.Set("WorkspaceID", member.WorkspaceID)
+		c.Request = c.Request.WithContext(repository.WithWorkspace(c.Request.Context(), member.WorkspaceID))
+		setCaller(c, member.Role, &member.UserID)
+		c.Next()
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/client/oidc"
+	"policy-match/internal/repository"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+type stubVerifier struct {
+	calls    int
+	identity *oidc.Identity
+	err      error
+}
+
+func (v *stubVerifier) Verify(ctx context.Context, token string) (*oidc.Identity, error) {
+	v.calls++
+	return v.identity, v.err
+}
+
+type stubUsers struct {
+	member *repository.WorkspaceMember
+}
+
+func (u *stubUsers) AuthenticateUser(ctx context.Context, identity oidc.Identity) (*repository.WorkspaceMember, error) {
+	return u.member, nil
+}
+
+func TestOIDCAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	member := &repository.WorkspaceMember{WorkspaceID: uuid.New(), UserID: uuid.New(), Role: repository.RoleAdmin}
+
+	tests := []struct {
+		name          string
+		authorization string
+		verifyErr     error
+		status        int
+		verified      bool
+	}{
+		{"api key passes through", "Bearer pm_0123456789abcdef", nil, http.StatusNoContent, false},
+		{"no header passes through", "", nil, http.StatusNoContent, false},
+		{"valid token", "Bearer a.b.c", nil, http.StatusNoContent, true},
+		{"invalid token", "Bearer a.b.c", oidc.ErrInvalidToken, http.StatusUnauthorized, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := &stubVerifier{identity: &oidc.Identity{Email: "ada@example.com"}, err: tt.verifyErr}
+			r := gin.New()
+			r.Use(OIDCAuth(i18n.NewBundle(language.English), verifier, &stubUsers{member: member}))
+			r.GET("/", func(c *gin.Context) {
+				if tt.verified && c.GetString("Role") != repository.RoleAdmin {
+					t.Errorf("role = %q, want %q", c.GetString("Role"), repository.RoleAdmin)
+				}
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if called := verifier.calls > 0; called != tt.verified {
+				t.Errorf("verifier called = %v, want %v", called, tt.verified)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"policy-match/internal/repository"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+type Permission string
+
+const (
+	// PermRead covers every GET. Submitters are further limited to their
+	// own documents, jobs and batches.
+	PermRead Permission = "read"
+	// PermWritePolicies covers uploading, editing and deleting policies
+	// and their rules.
+	PermWritePolicies Permission = "policies:write"
+	// PermSubmitDocuments covers checking documents and re-checking them.
+	PermSubmitDocuments Permission = "documents:submit"
+	// PermReviewDocuments covers deleting documents and re-checking every
+	// document of a policy.
+	PermReviewDocuments Permission = "documents:review"
+	// PermManageWorkspace covers API keys, workspaces and members.
+	PermManageWorkspace Permission = "workspace:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	repository.RoleAdmin:        {PermRead, PermWritePolicies, PermSubmitDocuments, PermReviewDocuments, PermManageWorkspace},
+	repository.RolePolicyAuthor: {PermRead, PermWritePolicies},
+	repository.RoleReviewer:     {PermRead, PermSubmitDocuments, PermReviewDocuments},
+	repository.RoleSubmitter:    {PermRead, PermSubmitDocuments},
+	repository.RoleReadOnly:     {PermRead},
+}
+
+// Require lets the request through only if the caller's role, set by the
+// auth middleware, grants perm.
+func Require(b *i18n.Bundle, perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(rolePermissions[c.GetString("Role")], perm) {
+			abort(c, b, http.StatusForbidden, "permission_denied")
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticated reports whether setCaller already ran for this request.
+func authenticated(c *gin.Context) bool {
+	_, ok := c.Get("Role")
+	return ok
+}
+
+// setCaller records who is calling: their role for Require, and ownerID
+// for the rows they create. Submitters are limited to rows they own.
+func setCaller(c *gin.Context, role string, ownerID *uuid.UUID) {
+	c.Set("Role", role)
+
+	ctx := c.Request.Context()
+	if ownerID != nil {
+		ctx = repository.WithOwner(ctx, *ownerID)
+	}
+	if role == repository.RoleSubmitter {
+		ctx = repository.OwnedOnly(ctx)
+	}
+	c.Request = c.Request.WithContext(ctx)
+}
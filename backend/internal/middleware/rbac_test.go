@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/dto"
+	"policy-match/internal/repository"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+func TestRequire(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	perms := []Permission{PermRead, PermWritePolicies, PermSubmitDocuments, PermReviewDocuments, PermManageWorkspace}
+
+	tests := []struct {
+		role    string
+		allowed []Permission
+	}{
+		{repository.RoleAdmin, perms},
+		{repository.RolePolicyAuthor, []Permission{PermRead, PermWritePolicies}},
+		{repository.RoleReviewer, []Permission{PermRead, PermSubmitDocuments, PermReviewDocuments}},
+		{repository.RoleSubmitter, []Permission{PermRead, PermSubmitDocuments}},
+		{repository.RoleReadOnly, []Permission{PermRead}},
+		{"", nil},
+		{"owner", nil},
+	}
+	for _, tt := range tests {
+		for _, perm := range perms {
+			t.Run(tt.role+" "+string(perm), func(t *testing.T) {
+				r := gin.New()
+				r.GET("/", func(c *gin.Context) {
+					if tt.role != "" {
+						c.Set("Role", tt.role)
+					}
+				}, Require(i18n.NewBundle(language.English), perm), func(c *gin.Context) {
+					c.Status(http.StatusNoContent)
+				})
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+				want := http.StatusForbidden
+				for _, p := range tt.allowed {
+					if p == perm {
+						want = http.StatusNoContent
+					}
+				}
+				if w.Code != want {
+					t.Errorf("status = %d, want %d", w.Code, want)
+				}
+			})
+		}
+	}
+}
+
+func TestSetCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ownerID := uuid.New()
+
+	tests := []struct {
+		role      string
+		owner     *uuid.UUID
+		ownedOnly bool
+	}{
+		{repository.RoleAdmin, &ownerID, false},
+		{repository.RoleReviewer, &ownerID, false},
+		{repository.RoleSubmitter, &ownerID, true},
+		{repository.RoleAdmin, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			setCaller(c, tt.role, tt.owner)
+
+			ctx := c.Request.Context()
+			if c.GetString("Role") != tt.role || !authenticated(c) {
+				t.Errorf("role = %q, want %q", c.GetString("Role"), tt.role)
+			}
+			id, ok := ctx.Value(dto.OwnerIDContext).(uuid.UUID)
+			if ok != (tt.owner != nil) || (ok && id != *tt.owner) {
+				t.Errorf("owner = %s, %v, want %v", id, ok, tt.owner)
+			}
+			if only, _ := ctx.Value(dto.OwnedOnlyContext).(bool); only != tt.ownedOnly {
+				t.Errorf("owned only = %v, want %v", only, tt.ownedOnly)
+			}
+		})
+	}
+}
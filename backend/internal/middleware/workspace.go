@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"policy-match/internal/repository"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+type WorkspaceResolver interface {
+	GetWorkspace(ctx context.Context, id uuid.UUID) (*repository.Workspace, error)
+}
+
+// Workspace scopes unauthenticated requests to the default workspace with
+// the admin role. It is only used when API key auth is disabled, since a
+// key already carries its workspace and role. An X-Workspace-ID header
+// naming any other workspace is refused: without auth the caller is
+// anyone, and letting them pick a workspace would make every workspace's
+// data public.
+func Workspace(b *i18n.Bundle, resolver WorkspaceResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := repository.DefaultWorkspaceID
+		if header := strings.TrimSpace(c.GetHeader("X-Workspace-ID")); header != "" {
+			parsed, err := uuid.Parse(header)
+			if err != nil {
+				abort(c, b, http.StatusBadRequest, "workspace_id_is_invalid")
+				return
+			}
+			if parsed != id {
+				abort(c, b, http.StatusForbidden, "workspace_requires_auth")
+				return
+			}
+		}
+
+		if _, err := resolver.GetWorkspace(c.Request.Context(), id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				abort(c, b, http.StatusNotFound, "workspace_not_found")
+				return
+			}
+			log.Error().Msg("error: " + err.Error())
+			abort(c, b, http.StatusInternalServerError, "an_error_occurred_while_processing_your_request")
+			return
+		}
+
+		c.Set("WorkspaceID", id)
+		c.Request = c.Request.WithContext(repository.WithWorkspace(c.Request.Context(), id))
+		setCaller(c, repository.RoleAdmin, nil)
+		c.Next()
+	}
+}
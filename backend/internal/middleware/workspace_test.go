@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+)
+
+type stubWorkspaces struct {
+	err error
+}
+
+func (s stubWorkspaces) GetWorkspace(ctx context.Context, id uuid.UUID) (*repository.Workspace, error) {
+	return &repository.Workspace{BaseModel: repository.BaseModel{ID: id}}, s.err
+}
+
+func TestWorkspace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		header string
+		err    error
+		status int
+		code   string
+	}{
+		{"no header", "", nil, http.StatusNoContent, ""},
+		{"default workspace", repository.DefaultWorkspaceID.String(), nil, http.StatusNoContent, ""},
+		{"other workspace", uuid.NewString(), nil, http.StatusForbidden, "workspace_requires_auth"},
+		{"invalid header", "acme", nil, http.StatusBadRequest, "workspace_id_is_invalid"},
+		{"missing default workspace", "", gorm.ErrRecordNotFound, http.StatusNotFound, "workspace_not_found"},
+		{"store failure", "", errors.New("connection refused"), http.StatusInternalServerError, "an_error_occurred_while_processing_your_request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(Workspace(i18n.NewBundle(language.English), stubWorkspaces{err: tt.err}))
+			r.GET("/", func(c *gin.Context) {
+				if id, _ := repository.WorkspaceFromContext(c.Request.Context()); id != repository.DefaultWorkspaceID {
+					t.Errorf("workspace = %s, want the default", id)
+				}
+				if role := c.GetString("Role"); role != repository.RoleAdmin {
+					t.Errorf("role = %q, want admin", role)
+				}
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Workspace-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.code == "" {
+				return
+			}
+			var body struct {
+				Error struct{ Code string } `json:"error"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if body.Error.Code != tt.code {
+				t.Errorf("code = %q, want %q", body.Error.Code, tt.code)
+			}
+			if tt.err != nil && strings.Contains(w.Body.String(), tt.err.Error()) {
+				t.Errorf("response leaks the error: %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"policy-match/internal/apperr"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (r *gormRepository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	return r.db.
+		WithContext(ctx).
+		Create(key).
+		Error
+}
+
+func (r *gormRepository) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	err := r.db.
+		WithContext(ctx).
+		Order("created_at DESC").
+		Find(&keys).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *gormRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	var key APIKey
+	err := r.db.
+		WithContext(ctx).
+		Where("key_hash = ?", hash).
+		First(&key).
+		Error
+	if err != nil {
+		return nil, notFound(err, "api_key_not_found")
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey marks the key revoked; revoking twice keeps the first time.
+func (r *gormRepository) RevokeAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error {
+	res := r.db.
+		WithContext(ctx).
+		Model(&APIKey{}).
+		Where("id = ?", id).
+		Update("revoked_at", gorm.Expr("COALESCE(revoked_at, ?)", at))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apperr.NotFound("api_key_not_found", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// UseAPIKey counts one request against the key and records it as last
+// used. It returns false without counting when the key is revoked or has
+// used up a quota for the day. The check and the increment are a single
+// statement, so concurrent requests cannot overshoot the quota.
+func (r *gormRepository) UseAPIKey(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	day := at.UTC().Truncate(24 * time.Hour)
+	res := r.db.
+		WithContext(ctx).
+		Model(&APIKey{}).
+		Where("id = ?", id).
+		Where("revoked_at IS NULL").
+		Where("usage_day < ? OR ((request_quota = 0 OR request_count < request_quota) AND (token_quota = 0 OR token_count < token_quota))", day).
+		Updates(map[string]any{
+			"request_count": gorm.Expr("CASE WHEN usage_day < ? THEN 1 ELSE request_count + 1 END", day),
+			"token_count":   gorm.Expr("CASE WHEN usage_day < ? THEN 0 ELSE token_count END", day),
+			"usage_day":     day,
+			"last_used_at":  at,
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// AddAPIKeyTokens counts LLM tokens used on behalf of the key.
+func (r *gormRepository) AddAPIKeyTokens(ctx context.Context, id uuid.UUID, tokens int) error {
+	return r.db.
+		WithContext(ctx).
+		Model(&APIKey{}).
+		Where("id = ?", id).
+		UpdateColumn("token_count", gorm.Expr("token_count + ?", tokens)).
+		Error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"errors"
+	"policy-match/internal/apperr"
+
+	"gorm.io/gorm"
+)
+
+// notFound tags a missing row as an apperr not-found error with code. The
+// gorm error stays in the chain, so errors.Is(err, gorm.ErrRecordNotFound)
+// still holds.
+func notFound(err error, code string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.NotFound(code, err)
+	}
+	return err
+}
+
+// conflict tags a unique index violation as an apperr conflict with code.
+// It relies on gorm.Config.TranslateError.
+func conflict(err error, code string) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return apperr.Conflict(code, err)
+	}
+	return err
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	SortCreatedAt            = "created_at"
+	SortTitle                = "title"
+	SortCategory             = "category"
+	SortCompliancePercentage = "compliance_percentage"
+	SortViolationPercentage  = "violation_percentage"
+)
+
+// DocumentFilter narrows and orders the documents list. Nil and empty
+// fields are ignored. PolicyID and Category match any current result, not
+// only the primary policy.
+type DocumentFilter struct {
+	Query                 string
+	PolicyID              *uuid.UUID
+	Category              string
+	IsCompliant           *bool
+	IsHumanReviewRequired *bool
+	MinCompliance         *int
+	MaxCompliance         *int
+	CreatedFrom           *time.Time
+	CreatedTo             *time.Time
+
+	Sort string
+	Desc bool
+}
+
+type PolicyFilter struct {
+	Query       string
+	Category    string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+
+	Sort string
+	Desc bool
+}
+
+// RuleFilter narrows a document's rule results by the attributes of their
+// rules. Empty fields are ignored.
+type RuleFilter struct {
+	Severity   string
+	Category   string
+	Obligation string
+}
+
+func (f RuleFilter) IsZero() bool {
+	return f == RuleFilter{}
+}
+
+// Match reports whether rule passes the filter. Category matches case
+// insensitively. A result whose rule was not loaded only passes an empty
+// filter.
+func (f RuleFilter) Match(rule *Rule) bool {
+	if f.IsZero() {
+		return true
+	}
+	if rule == nil {
+		return false
+	}
+	return (f.Severity == "" || rule.Severity == f.Severity) &&
+		(f.Category == "" || strings.EqualFold(rule.Category, f.Category)) &&
+		(f.Obligation == "" || rule.Obligation == f.Obligation)
+}
+
+var (
+	documentSorts = map[string]bool{
+		SortCreatedAt:            true,
+		SortTitle:                true,
+		SortCompliancePercentage: true,
+		SortViolationPercentage:  true,
+	}
+	policySorts = map[string]bool{
+		SortCreatedAt: true,
+		SortTitle:     true,
+		SortCategory:  true,
+	}
+)
+
+func (f DocumentFilter) scope(db *gorm.DB) *gorm.DB {
+	db = titleSearch(db, f.Query)
+	db = createdBetween(db, f.CreatedFrom, f.CreatedTo)
+
+	if f.PolicyID != nil || f.Category != "" {
+		results := db.
+			Session(&gorm.Session{NewDB: true}).
+			Table("compliance_results").
+			Select("compliance_results.document_id").
+			Where("compliance_results.superseded_at IS NULL").
+			Where("compliance_results.deleted_at IS NULL")
+		if f.PolicyID != nil {
+			results = results.Where("compliance_results.policy_id = ?", *f.PolicyID)
+		}
+		if f.Category != "" {
+			results = results.
+				Joins("JOIN policies ON policies.id = compliance_results.policy_id").
+				Where("policies.category = ?", f.Category)
+		}
+		db = db.Where("documents.id IN (?)", results)
+	}
+	if f.IsCompliant != nil {
+		db = db.Where("documents.is_compliant = ?", *f.IsCompliant)
+	}
+	if f.IsHumanReviewRequired != nil {
+		db = db.Where("documents.is_human_review_required = ?", *f.IsHumanReviewRequired)
+	}
+	if f.MinCompliance != nil {
+		db = db.Where("documents.compliance_percentage >= ?", *f.MinCompliance)
+	}
+	if f.MaxCompliance != nil {
+		db = db.Where("documents.compliance_percentage <= ?", *f.MaxCompliance)
+	}
+	return db
+}
+
+func (f DocumentFilter) order(db *gorm.DB) *gorm.DB {
+	return orderBy(db, "documents", f.Sort, f.Desc, documentSorts)
+}
+
+func (f PolicyFilter) scope(db *gorm.DB) *gorm.DB {
+	db = titleSearch(db, f.Query)
+	db = createdBetween(db, f.CreatedFrom, f.CreatedTo)
+	if f.Category != "" {
+		db = db.Where("category = ?", f.Category)
+	}
+	return db
+}
+
+func (f PolicyFilter) order(db *gorm.DB) *gorm.DB {
+	return orderBy(db, "policies", f.Sort, f.Desc, policySorts)
+}
+
+// titleSearch matches query anywhere in the title, ignoring case. LIKE
+// wildcards in query are matched literally.
+func titleSearch(db *gorm.DB, query string) *gorm.DB {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return db
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(query))
+	return db.Where(`LOWER(title) LIKE ? ESCAPE '\'`, "%"+escaped+"%")
+}
+
+// createdBetween keeps rows created in [from, to).
+func createdBetween(db *gorm.DB, from, to *time.Time) *gorm.DB {
+	if from != nil {
+		db = db.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		db = db.Where("created_at < ?", *to)
+	}
+	return db
+}
+
+// orderBy sorts by an allowed column, created_at otherwise, with the
+// primary key as a tie-breaker so pages are stable.
+func orderBy(db *gorm.DB, table, sort string, desc bool, allowed map[string]bool) *gorm.DB {
+	if !allowed[sort] {
+		sort = SortCreatedAt
+	}
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	column := table + "." + sort
+	if sort == SortTitle || sort == SortCategory {
+		column = "LOWER(" + column + ")"
+	}
+	return db.Order(column + dir).Order(table + ".id" + dir)
+}
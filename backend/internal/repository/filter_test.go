@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type filterFixture struct {
+	security, privacy *Policy
+}
+
+func createFilterPolicy(t *testing.T, ctx context.Context, repo Repository, title, category string, created time.Time) *Policy {
+	t.Helper()
+	policy := &Policy{
+		BaseModel: BaseModel{ID: uuid.New(), CreatedAt: created},
+		Title:     title,
+		Category:  category,
+		Path:      title,
+		Extension: ".pdf",
+		Version:   1,
+	}
+	if err := repo.CreatePolicy(ctx, policy); err != nil {
+		t.Fatalf("createPolicy: %v", err)
+	}
+	return policy
+}
+
+// seedFilterDocuments stores four documents:
+//
+//	Staff Handbook  compliant          90%  2024-01-10  security
+//	Visitor Log     review             40%  2024-02-15  security, privacy (superseded)
+//	Privacy Notice                     70%  2024-03-20  privacy
+//	100% Report     compliant, review 100%  2024-03-21  security, privacy
+func seedFilterDocuments(t *testing.T, ctx context.Context, repo Repository) filterFixture {
+	t.Helper()
+	f := filterFixture{
+		security: createFilterPolicy(t, ctx, repo, "Security Policy", "security", day(2023, 12, 1)),
+		privacy:  createFilterPolicy(t, ctx, repo, "privacy policy", "privacy", day(2023, 12, 2)),
+	}
+	superseded := day(2024, 2, 16)
+
+	docs := []struct {
+		title      string
+		text       string
+		compliant  bool
+		review     bool
+		percentage int
+		created    time.Time
+		policies   []*Policy
+		old        []*Policy
+	}{
+		{"Staff Handbook", "Staff wear badges at all times.", true, false, 90, day(2024, 1, 10), []*Policy{f.security}, nil},
+		{"Visitor Log", "Visitors sign in at reception.", false, true, 40, day(2024, 2, 15), []*Policy{f.security}, []*Policy{f.privacy}},
+		{"Privacy Notice", "Badges show no personal data.", false, false, 70, day(2024, 3, 20), []*Policy{f.privacy}, nil},
+		{"100% Report", "Quarterly summary.", true, true, 100, day(2024, 3, 21), []*Policy{f.security, f.privacy}, nil},
+	}
+	for _, d := range docs {
+		id := uuid.New()
+		document := &Document{
+			BaseModel:             BaseModel{ID: id, CreatedAt: d.created},
+			Title:                 d.title,
+			Path:                  d.title,
+			Extension:             ".pdf",
+			IsCompliant:           d.compliant,
+			IsHumanReviewRequired: d.review,
+			CompliancePercentage:  d.percentage,
+			PolicyID:              d.policies[0].ID,
+			Extraction:            Extraction{ExtractedText: d.text},
+		}
+		for _, p := range d.policies {
+			document.Results = append(document.Results, ComplianceResult{BaseModel: BaseModel{ID: uuid.New()}, DocumentID: id, PolicyID: p.ID})
+		}
+		for _, p := range d.old {
+			document.Results = append(document.Results, ComplianceResult{BaseModel: BaseModel{ID: uuid.New()}, DocumentID: id, PolicyID: p.ID, SupersededAt: &superseded})
+		}
+		if err := repo.CreateDocument(ctx, document); err != nil {
+			t.Fatalf("createDocument: %v", err)
+		}
+	}
+	return f
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 12, 0, 0, 0, time.UTC)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func documentTitles(documents []Document) []string {
+	titles := make([]string, len(documents))
+	for i, d := range documents {
+		titles[i] = d.Title
+	}
+	return titles
+}
+
+func TestDocumentFilter(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	f := seedFilterDocuments(t, ctx, repo)
+
+	tests := []struct {
+		name   string
+		filter DocumentFilter
+		want   []string
+	}{
+		{"no filter", DocumentFilter{}, []string{"Staff Handbook", "Visitor Log", "Privacy Notice", "100% Report"}},
+		{"title query", DocumentFilter{Query: " HANDBOOK "}, []string{"Staff Handbook"}},
+		{"literal percent", DocumentFilter{Query: "100%"}, []string{"100% Report"}},
+		{"literal underscore", DocumentFilter{Query: "_"}, []string{}},
+		{"policy", DocumentFilter{PolicyID: &f.security.ID}, []string{"Staff Handbook", "Visitor Log", "100% Report"}},
+		{"policy skips superseded results", DocumentFilter{PolicyID: &f.privacy.ID}, []string{"Privacy Notice", "100% Report"}},
+		{"unknown policy", DocumentFilter{PolicyID: ptr(uuid.New())}, []string{}},
+		{"category", DocumentFilter{Category: "privacy"}, []string{"Privacy Notice", "100% Report"}},
+		{"policy outside the category", DocumentFilter{PolicyID: &f.security.ID, Category: "privacy"}, []string{}},
+		{"policy in the category", DocumentFilter{PolicyID: &f.privacy.ID, Category: "privacy"}, []string{"Privacy Notice", "100% Report"}},
+		{"compliant", DocumentFilter{IsCompliant: ptr(true)}, []string{"Staff Handbook", "100% Report"}},
+		{"not compliant", DocumentFilter{IsCompliant: ptr(false)}, []string{"Visitor Log", "Privacy Notice"}},
+		{"review", DocumentFilter{IsHumanReviewRequired: ptr(true)}, []string{"Visitor Log", "100% Report"}},
+		{"min compliance", DocumentFilter{MinCompliance: ptr(70)}, []string{"Staff Handbook", "Privacy Notice", "100% Report"}},
+		{"max compliance", DocumentFilter{MaxCompliance: ptr(70)}, []string{"Visitor Log", "Privacy Notice"}},
+		{"compliance range", DocumentFilter{MinCompliance: ptr(50), MaxCompliance: ptr(95)}, []string{"Staff Handbook", "Privacy Notice"}},
+		{"empty compliance range", DocumentFilter{MinCompliance: ptr(95), MaxCompliance: ptr(50)}, []string{}},
+		{"created range", DocumentFilter{CreatedFrom: ptr(day(2024, 2, 1)), CreatedTo: ptr(day(2024, 3, 21))}, []string{"Visitor Log", "Privacy Notice"}},
+		{"category and not compliant", DocumentFilter{Category: "privacy", IsCompliant: ptr(false)}, []string{"Privacy Notice"}},
+		{"policy, review and min compliance", DocumentFilter{PolicyID: &f.security.ID, IsHumanReviewRequired: ptr(true), MinCompliance: ptr(50)}, []string{"100% Report"}},
+		{"query and created from", DocumentFilter{Query: "o", CreatedFrom: ptr(day(2024, 3, 1))}, []string{"Privacy Notice", "100% Report"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, total, err := repo.GetAllDocuments(ctx, tt.filter, 0, 10)
+			if err != nil {
+				t.Fatalf("getAllDocuments: %v", err)
+			}
+			if got := documentTitles(documents); !slices.Equal(got, tt.want) {
+				t.Errorf("documents = %v, want %v", got, tt.want)
+			}
+			if total != len(tt.want) {
+				t.Errorf("total = %d, want %d", total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestDocumentFilterOrder(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	seedFilterDocuments(t, ctx, repo)
+
+	tests := []struct {
+		name   string
+		filter DocumentFilter
+		want   []string
+	}{
+		{"created", DocumentFilter{Sort: SortCreatedAt, Desc: true}, []string{"100% Report", "Privacy Notice", "Visitor Log", "Staff Handbook"}},
+		{"title", DocumentFilter{Sort: SortTitle}, []string{"100% Report", "Privacy Notice", "Staff Handbook", "Visitor Log"}},
+		{"compliance", DocumentFilter{Sort: SortCompliancePercentage, Desc: true}, []string{"100% Report", "Staff Handbook", "Privacy Notice", "Visitor Log"}},
+		{"unknown sort falls back to created", DocumentFilter{Sort: "extracted_text"}, []string{"Staff Handbook", "Visitor Log", "Privacy Notice", "100% Report"}},
+		{"policy sort falls back to created", DocumentFilter{Sort: SortCategory}, []string{"Staff Handbook", "Visitor Log", "Privacy Notice", "100% Report"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, _, err := repo.GetAllDocuments(ctx, tt.filter, 0, 10)
+			if err != nil {
+				t.Fatalf("getAllDocuments: %v", err)
+			}
+			if got := documentTitles(documents); !slices.Equal(got, tt.want) {
+				t.Errorf("documents = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	documents, total, err := repo.GetAllDocuments(ctx, DocumentFilter{Sort: SortTitle}, 1, 2)
+	if err != nil {
+		t.Fatalf("getAllDocuments: %v", err)
+	}
+	if got := documentTitles(documents); !slices.Equal(got, []string{"Privacy Notice", "Staff Handbook"}) || total != 4 {
+		t.Errorf("page = %v of %d, want [Privacy Notice Staff Handbook] of 4", got, total)
+	}
+}
+
+func TestPolicyFilter(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	seedFilterDocuments(t, ctx, repo)
+
+	tests := []struct {
+		name   string
+		filter PolicyFilter
+		want   []string
+	}{
+		{"no filter", PolicyFilter{}, []string{"Security Policy", "privacy policy"}},
+		{"query", PolicyFilter{Query: "PRIVACY"}, []string{"privacy policy"}},
+		{"category", PolicyFilter{Category: "security"}, []string{"Security Policy"}},
+		{"category is exact", PolicyFilter{Category: "Security"}, []string{}},
+		{"created to", PolicyFilter{CreatedTo: ptr(day(2023, 12, 2))}, []string{"Security Policy"}},
+		{"title order ignores case", PolicyFilter{Sort: SortTitle, Desc: true}, []string{"Security Policy", "privacy policy"}},
+		{"query and category", PolicyFilter{Query: "policy", Category: "privacy"}, []string{"privacy policy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, total, err := repo.GetAllPolicies(ctx, tt.filter, 0, 10)
+			if err != nil {
+				t.Fatalf("getAllPolicies: %v", err)
+			}
+			got := make([]string, len(policies))
+			for i, p := range policies {
+				got[i] = p.Title
+			}
+			if !slices.Equal(got, tt.want) || total != len(tt.want) {
+				t.Errorf("policies = %v (total %d), want %v", got, total, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchDocumentsFilters(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	f := seedFilterDocuments(t, ctx, repo)
+
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  []string
+	}{
+		{"text", SearchQuery{Text: "badges"}, []string{"Privacy Notice", "Staff Handbook"}},
+		{"every word", SearchQuery{Text: "badges staff"}, []string{"Staff Handbook"}},
+		{"policy", SearchQuery{Text: "badges", PolicyID: &f.security.ID}, []string{"Staff Handbook"}},
+		{"category", SearchQuery{Text: "badges", Category: "privacy"}, []string{"Privacy Notice"}},
+		{"superseded result", SearchQuery{Text: "visitors", PolicyID: &f.privacy.ID}, []string{}},
+		{"policy outside the category", SearchQuery{Text: "summary", PolicyID: &f.security.ID, Category: "privacy"}, []string{}},
+		{"limit", SearchQuery{Text: "s", Limit: 1}, []string{"100% Report"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.query.Limit == 0 {
+				tt.query.Limit = 10
+			}
+			hits, err := repo.SearchDocuments(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("searchDocuments: %v", err)
+			}
+			got := make([]string, len(hits))
+			for i, hit := range hits {
+				got[i] = hit.Title
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("hits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func newTestMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	db, err := openSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("openSQLite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	migrator, err := NewMigrator(sqlDB, DriverSQLite)
+	if err != nil {
+		t.Fatalf("newMigrator: %v", err)
+	}
+	t.Cleanup(func() { migrator.Close() })
+	return migrator
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	ctx := context.Background()
+	migrator := newTestMigrator(t)
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrator.migrations))
+	}
+
+	applied, err = migrator.Up(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("second up applied %d migrations, err %v", len(applied), err)
+	}
+
+	reverted, err := migrator.Down(ctx, len(migrator.migrations))
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != len(migrator.migrations) {
+		t.Fatalf("reverted %d migrations, want %d", len(reverted), len(migrator.migrations))
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+}
+
+func TestMigratorRefusesAutoMigrateSchema(t *testing.T) {
+	ctx := context.Background()
+	migrator := newTestMigrator(t)
+
+	// The columns AutoMigrate created for policies before migrations existed.
+	_, err := migrator.db.ExecContext(ctx, `CREATE TABLE policies (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime,
+    title varchar(255) NOT NULL,
+    category varchar(255) NOT NULL,
+    path varchar(255) NOT NULL,
+    extension varchar(255) NOT NULL
+)`)
+	if err != nil {
+		t.Fatalf("create policies: %v", err)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "created before migrations") {
+		t.Fatalf("up returned %v, want a baseline error", err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("applied %d migrations, want none", len(applied))
+	}
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS policy_versions;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS batches;
+DROP TABLE IF EXISTS rule_results;
+DROP TABLE IF EXISTS compliance_results;
+DROP TABLE IF EXISTS documents;
+DROP TABLE IF EXISTS rules;
+DROP TABLE IF EXISTS policies;
@@ -0,0 +1,188 @@
+-- Baseline matching the schema AutoMigrate used to create. Databases that
+-- AutoMigrate created before migrations existed already have policies,
+-- rules and documents, but only with their original columns, so the
+-- ALTER TABLE statements below add the ones those tables are missing.
+
+CREATE TABLE IF NOT EXISTS policies (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    title varchar(255) NOT NULL,
+    category varchar(255) NOT NULL,
+    path varchar(255) NOT NULL,
+    extension varchar(255) NOT NULL,
+    blob_key varchar(64) NOT NULL DEFAULT '',
+    content_type varchar(255) NOT NULL DEFAULT '',
+    size bigint NOT NULL DEFAULT 0,
+    extracted_text text NOT NULL DEFAULT '',
+    page_count integer NOT NULL DEFAULT 0,
+    mime_type varchar(255) NOT NULL DEFAULT '',
+    author varchar(255) NOT NULL DEFAULT '',
+    authored_at timestamptz,
+    language varchar(16) NOT NULL DEFAULT '',
+    pass_threshold integer,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS rules (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    policy_id uuid NOT NULL,
+    rule_id varchar(255) NOT NULL,
+    rule_text text NOT NULL,
+    severity varchar(32) NOT NULL DEFAULT 'major',
+    weight double precision NOT NULL DEFAULT 1,
+    CONSTRAINT fk_policies_rules FOREIGN KEY (policy_id) REFERENCES policies (id)
+);
+
+CREATE TABLE IF NOT EXISTS documents (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    title varchar(255) NOT NULL,
+    path varchar(255) NOT NULL,
+    extension varchar(255) NOT NULL,
+    blob_key varchar(64) NOT NULL DEFAULT '',
+    content_type varchar(255) NOT NULL DEFAULT '',
+    size bigint NOT NULL DEFAULT 0,
+    violations jsonb,
+    is_compliant boolean NOT NULL,
+    is_human_review_required boolean NOT NULL,
+    compliance_percentage integer NOT NULL,
+    violation_percentage integer NOT NULL DEFAULT 0,
+    policy_id uuid NOT NULL,
+    is_stale boolean NOT NULL DEFAULT false,
+    extracted_text text NOT NULL DEFAULT '',
+    page_count integer NOT NULL DEFAULT 0,
+    mime_type varchar(255) NOT NULL DEFAULT '',
+    author varchar(255) NOT NULL DEFAULT '',
+    authored_at timestamptz,
+    language varchar(16) NOT NULL DEFAULT '',
+    CONSTRAINT fk_documents_policy FOREIGN KEY (policy_id) REFERENCES policies (id)
+);
+
+ALTER TABLE policies
+    ADD COLUMN IF NOT EXISTS blob_key varchar(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS content_type varchar(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS size bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS extracted_text text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS page_count integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS mime_type varchar(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS author varchar(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS authored_at timestamptz,
+    ADD COLUMN IF NOT EXISTS language varchar(16) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS pass_threshold integer,
+    ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+
+ALTER TABLE rules
+    ADD COLUMN IF NOT EXISTS severity varchar(32) NOT NULL DEFAULT 'major',
+    ADD COLUMN IF NOT EXISTS weight double precision NOT NULL DEFAULT 1;
+
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS blob_key varchar(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS content_type varchar(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS size bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS violation_percentage integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS is_stale boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS extracted_text text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS page_count integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS mime_type varchar(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS author varchar(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS authored_at timestamptz,
+    ADD COLUMN IF NOT EXISTS language varchar(16) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS compliance_results (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    document_id uuid NOT NULL,
+    policy_id uuid NOT NULL,
+    violations jsonb,
+    is_compliant boolean NOT NULL,
+    is_human_review_required boolean NOT NULL,
+    compliance_percentage integer NOT NULL,
+    violation_percentage integer NOT NULL,
+    policy_version integer NOT NULL DEFAULT 1,
+    is_stale boolean NOT NULL DEFAULT false,
+    superseded_at timestamptz,
+    CONSTRAINT fk_compliance_results_policy FOREIGN KEY (policy_id) REFERENCES policies (id),
+    CONSTRAINT fk_documents_results FOREIGN KEY (document_id) REFERENCES documents (id)
+);
+CREATE INDEX IF NOT EXISTS idx_compliance_results_policy_id ON compliance_results (policy_id);
+CREATE INDEX IF NOT EXISTS idx_compliance_results_document_id ON compliance_results (document_id);
+
+CREATE TABLE IF NOT EXISTS rule_results (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    document_id uuid NOT NULL,
+    compliance_result_id uuid,
+    rule_uuid uuid,
+    rule_id varchar(255) NOT NULL,
+    verdict varchar(32) NOT NULL,
+    confidence double precision NOT NULL,
+    evidence text NOT NULL,
+    evidence_start integer NOT NULL,
+    evidence_end integer NOT NULL,
+    rationale text NOT NULL,
+    CONSTRAINT fk_compliance_results_rule_results FOREIGN KEY (compliance_result_id) REFERENCES compliance_results (id),
+    CONSTRAINT fk_documents_rule_results FOREIGN KEY (document_id) REFERENCES documents (id)
+);
+CREATE INDEX IF NOT EXISTS idx_rule_results_compliance_result_id ON rule_results (compliance_result_id);
+CREATE INDEX IF NOT EXISTS idx_rule_results_document_id ON rule_results (document_id);
+
+CREATE TABLE IF NOT EXISTS batches (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    policy_ids jsonb,
+    file_count integer NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    kind varchar(32) NOT NULL DEFAULT 'check',
+    status varchar(32) NOT NULL,
+    policy_ids jsonb,
+    file_name varchar(255) NOT NULL,
+    blob_key varchar(64) NOT NULL,
+    size bigint NOT NULL,
+    error text NOT NULL DEFAULT '',
+    attempts integer NOT NULL DEFAULT 0,
+    batch_id uuid,
+    document_id uuid,
+    lease_expires_at timestamptz,
+    started_at timestamptz,
+    finished_at timestamptz,
+    CONSTRAINT fk_jobs_document FOREIGN KEY (document_id) REFERENCES documents (id),
+    CONSTRAINT fk_batches_jobs FOREIGN KEY (batch_id) REFERENCES batches (id)
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_batch_id ON jobs (batch_id);
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs (status);
+
+CREATE TABLE IF NOT EXISTS policy_versions (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    policy_id uuid NOT NULL,
+    version integer NOT NULL,
+    source varchar(32) NOT NULL,
+    path varchar(255) NOT NULL,
+    extension varchar(255) NOT NULL,
+    blob_key varchar(64) NOT NULL DEFAULT '',
+    size bigint NOT NULL DEFAULT 0,
+    rules jsonb,
+    CONSTRAINT fk_policies_versions FOREIGN KEY (policy_id) REFERENCES policies (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_policy_versions_policy_id_version ON policy_versions (policy_id, version);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_documents_policy_id;
+DROP INDEX IF EXISTS idx_rules_policy_id_rule_id;
+DROP INDEX IF EXISTS idx_rules_policy_id;
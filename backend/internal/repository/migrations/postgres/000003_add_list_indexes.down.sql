@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_compliance_results_current;
+DROP INDEX IF EXISTS idx_policies_created_at;
+DROP INDEX IF EXISTS idx_policies_category;
+DROP INDEX IF EXISTS idx_documents_review_required;
+DROP INDEX IF EXISTS idx_documents_is_compliant;
+DROP INDEX IF EXISTS idx_documents_compliance_percentage;
+DROP INDEX IF EXISTS idx_documents_created_at;
+DROP INDEX IF EXISTS idx_policies_title_trgm;
+DROP INDEX IF EXISTS idx_documents_title_trgm;
@@ -0,0 +1,31 @@
+-- Substring title search uses trigram indexes on the lower-cased title.
+-- Creating the pg_trgm extension needs CREATE on the database (and, before
+-- Postgres 13, superuser). Without it the indexes are skipped and title
+-- search still works with a sequential scan; see the README to add them
+-- later.
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN insufficient_privilege THEN
+    RAISE WARNING 'pg_trgm could not be created, skipping title trigram indexes: %', SQLERRM;
+END
+$$;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX IF NOT EXISTS idx_documents_title_trgm ON documents USING gin (lower(title) gin_trgm_ops);
+        CREATE INDEX IF NOT EXISTS idx_policies_title_trgm ON policies USING gin (lower(title) gin_trgm_ops);
+    END IF;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_documents_created_at ON documents (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_documents_compliance_percentage ON documents (compliance_percentage);
+CREATE INDEX IF NOT EXISTS idx_documents_is_compliant ON documents (is_compliant);
+CREATE INDEX IF NOT EXISTS idx_documents_review_required ON documents (created_at) WHERE is_human_review_required;
+
+CREATE INDEX IF NOT EXISTS idx_policies_category ON policies (category);
+CREATE INDEX IF NOT EXISTS idx_policies_created_at ON policies (created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_compliance_results_current ON compliance_results (policy_id, document_id) WHERE superseded_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_documents_extracted_text_fts;
+DROP INDEX IF EXISTS idx_rules_rule_text_fts;
@@ -0,0 +1,6 @@
+-- Expression indexes for full-text search. Queries must use exactly the
+-- same expressions (see repository/search.go) for the planner to use them.
+-- Document text is capped so very large files stay under the tsvector size
+-- limit.
+CREATE INDEX IF NOT EXISTS idx_rules_rule_text_fts ON rules USING gin (to_tsvector('english', rule_text));
+CREATE INDEX IF NOT EXISTS idx_documents_extracted_text_fts ON documents USING gin (to_tsvector('english', left(extracted_text, 1000000)));
@@ -0,0 +1,78 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    name varchar(255) NOT NULL
+);
+
+-- Existing rows move to the default workspace.
+INSERT INTO workspaces (id, created_at, updated_at, name)
+VALUES ('00000000-0000-0000-0000-000000000001', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 'Default');
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    email varchar(255) NOT NULL,
+    name varchar(255) NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    id uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz DEFAULT NULL,
+    workspace_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    CONSTRAINT fk_workspaces_members FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
+    CONSTRAINT fk_workspace_members_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_members_workspace_id_user_id ON workspace_members (workspace_id, user_id);
+
+ALTER TABLE policies ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    CONSTRAINT fk_workspaces_policies REFERENCES workspaces (id);
+ALTER TABLE policies ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_policies_workspace_id ON policies (workspace_id);
+
+ALTER TABLE policy_versions ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    CONSTRAINT fk_workspaces_policy_versions REFERENCES workspaces (id);
+ALTER TABLE policy_versions ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_policy_versions_workspace_id ON policy_versions (workspace_id);
+
+ALTER TABLE rules ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    CONSTRAINT fk_workspaces_rules REFERENCES workspaces (id);
+ALTER TABLE rules ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_rules_workspace_id ON rules (workspace_id);
+
+ALTER TABLE documents ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    CONSTRAINT fk_workspaces_documents REFERENCES workspaces (id);
+ALTER TABLE documents ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_documents_workspace_id ON documents (workspace_id);
+
+ALTER TABLE compliance_results ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    CONSTRAINT fk_workspaces_compliance_results REFERENCES workspaces (id);
+ALTER TABLE compliance_results ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_compliance_results_workspace_id ON compliance_results (workspace_id);
+
+ALTER TABLE rule_results ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    CONSTRAINT fk_workspaces_rule_results REFERENCES workspaces (id);
+ALTER TABLE rule_results ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_rule_results_workspace_id ON rule_results (workspace_id);
+
+ALTER TABLE jobs ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    CONSTRAINT fk_workspaces_jobs REFERENCES workspaces (id);
+ALTER TABLE jobs ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_jobs_workspace_id ON jobs (workspace_id);
+
+ALTER TABLE batches ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    CONSTRAINT fk_workspaces_batches REFERENCES workspaces (id);
+ALTER TABLE batches ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_batches_workspace_id ON batches (workspace_id);
+
+ALTER TABLE api_keys ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    CONSTRAINT fk_workspaces_api_keys REFERENCES workspaces (id);
+ALTER TABLE api_keys ALTER COLUMN workspace_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_api_keys_workspace_id ON api_keys (workspace_id);
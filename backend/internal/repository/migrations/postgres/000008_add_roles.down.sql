@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_batches_owner_id;
+ALTER TABLE batches DROP COLUMN owner_id;
+DROP INDEX IF EXISTS idx_jobs_owner_id;
+ALTER TABLE jobs DROP COLUMN owner_id;
+DROP INDEX IF EXISTS idx_documents_owner_id;
+ALTER TABLE documents DROP COLUMN owner_id;
+
+ALTER TABLE workspace_members DROP COLUMN role;
+ALTER TABLE api_keys DROP COLUMN role;
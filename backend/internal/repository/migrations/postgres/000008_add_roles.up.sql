@@ -0,0 +1,12 @@
+-- Existing keys keep full access.
+ALTER TABLE api_keys ADD COLUMN role varchar(32) NOT NULL DEFAULT 'admin';
+ALTER TABLE workspace_members ADD COLUMN role varchar(32) NOT NULL DEFAULT 'read_only';
+
+-- Rows created before ownership was tracked have no owner, so submitters
+-- do not see them.
+ALTER TABLE documents ADD COLUMN owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_documents_owner_id ON documents (owner_id);
+ALTER TABLE jobs ADD COLUMN owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_jobs_owner_id ON jobs (owner_id);
+ALTER TABLE batches ADD COLUMN owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_batches_owner_id ON batches (owner_id);
@@ -0,0 +1,2 @@
+ALTER TABLE compliance_results DROP COLUMN output_path;
+ALTER TABLE policies DROP COLUMN rules_output_path;
@@ -0,0 +1,4 @@
+ALTER TABLE rules DROP COLUMN applicability;
+ALTER TABLE rules DROP COLUMN parent_rule_id;
+ALTER TABLE rules DROP COLUMN obligation;
+ALTER TABLE rules DROP COLUMN category;
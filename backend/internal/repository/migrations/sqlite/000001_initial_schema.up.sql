@@ -0,0 +1,157 @@
+-- Baseline matching the schema AutoMigrate used to create. SQLite cannot
+-- add a column only if it is missing, so the migrator refuses to apply this
+-- to a database AutoMigrate created instead of adopting it.
+
+CREATE TABLE policies (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    title varchar(255) NOT NULL,
+    category varchar(255) NOT NULL,
+    path varchar(255) NOT NULL,
+    extension varchar(255) NOT NULL,
+    blob_key varchar(64) NOT NULL DEFAULT '',
+    content_type varchar(255) NOT NULL DEFAULT '',
+    size bigint NOT NULL DEFAULT 0,
+    extracted_text text NOT NULL DEFAULT '',
+    page_count integer NOT NULL DEFAULT 0,
+    mime_type varchar(255) NOT NULL DEFAULT '',
+    author varchar(255) NOT NULL DEFAULT '',
+    authored_at datetime,
+    language varchar(16) NOT NULL DEFAULT '',
+    pass_threshold integer,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE rules (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    policy_id uuid NOT NULL,
+    rule_id varchar(255) NOT NULL,
+    rule_text text NOT NULL,
+    severity varchar(32) NOT NULL DEFAULT 'major',
+    weight double precision NOT NULL DEFAULT 1,
+    CONSTRAINT fk_policies_rules FOREIGN KEY (policy_id) REFERENCES policies (id)
+);
+
+CREATE TABLE documents (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    title varchar(255) NOT NULL,
+    path varchar(255) NOT NULL,
+    extension varchar(255) NOT NULL,
+    blob_key varchar(64) NOT NULL DEFAULT '',
+    content_type varchar(255) NOT NULL DEFAULT '',
+    size bigint NOT NULL DEFAULT 0,
+    violations text,
+    is_compliant boolean NOT NULL,
+    is_human_review_required boolean NOT NULL,
+    compliance_percentage integer NOT NULL,
+    violation_percentage integer NOT NULL DEFAULT 0,
+    policy_id uuid NOT NULL,
+    is_stale boolean NOT NULL DEFAULT false,
+    extracted_text text NOT NULL DEFAULT '',
+    page_count integer NOT NULL DEFAULT 0,
+    mime_type varchar(255) NOT NULL DEFAULT '',
+    author varchar(255) NOT NULL DEFAULT '',
+    authored_at datetime,
+    language varchar(16) NOT NULL DEFAULT '',
+    CONSTRAINT fk_documents_policy FOREIGN KEY (policy_id) REFERENCES policies (id)
+);
+
+CREATE TABLE compliance_results (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    document_id uuid NOT NULL,
+    policy_id uuid NOT NULL,
+    violations text,
+    is_compliant boolean NOT NULL,
+    is_human_review_required boolean NOT NULL,
+    compliance_percentage integer NOT NULL,
+    violation_percentage integer NOT NULL,
+    policy_version integer NOT NULL DEFAULT 1,
+    is_stale boolean NOT NULL DEFAULT false,
+    superseded_at datetime,
+    CONSTRAINT fk_compliance_results_policy FOREIGN KEY (policy_id) REFERENCES policies (id),
+    CONSTRAINT fk_documents_results FOREIGN KEY (document_id) REFERENCES documents (id)
+);
+CREATE INDEX idx_compliance_results_policy_id ON compliance_results (policy_id);
+CREATE INDEX idx_compliance_results_document_id ON compliance_results (document_id);
+
+CREATE TABLE rule_results (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    document_id uuid NOT NULL,
+    compliance_result_id uuid,
+    rule_uuid uuid,
+    rule_id varchar(255) NOT NULL,
+    verdict varchar(32) NOT NULL,
+    confidence double precision NOT NULL,
+    evidence text NOT NULL,
+    evidence_start integer NOT NULL,
+    evidence_end integer NOT NULL,
+    rationale text NOT NULL,
+    CONSTRAINT fk_compliance_results_rule_results FOREIGN KEY (compliance_result_id) REFERENCES compliance_results (id),
+    CONSTRAINT fk_documents_rule_results FOREIGN KEY (document_id) REFERENCES documents (id)
+);
+CREATE INDEX idx_rule_results_compliance_result_id ON rule_results (compliance_result_id);
+CREATE INDEX idx_rule_results_document_id ON rule_results (document_id);
+
+CREATE TABLE batches (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    policy_ids text,
+    file_count integer NOT NULL
+);
+
+CREATE TABLE jobs (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    kind varchar(32) NOT NULL DEFAULT 'check',
+    status varchar(32) NOT NULL,
+    policy_ids text,
+    file_name varchar(255) NOT NULL,
+    blob_key varchar(64) NOT NULL,
+    size bigint NOT NULL,
+    error text NOT NULL DEFAULT '',
+    attempts integer NOT NULL DEFAULT 0,
+    batch_id uuid,
+    document_id uuid,
+    lease_expires_at datetime,
+    started_at datetime,
+    finished_at datetime,
+    CONSTRAINT fk_jobs_document FOREIGN KEY (document_id) REFERENCES documents (id),
+    CONSTRAINT fk_batches_jobs FOREIGN KEY (batch_id) REFERENCES batches (id)
+);
+CREATE INDEX idx_jobs_batch_id ON jobs (batch_id);
+CREATE INDEX idx_jobs_status ON jobs (status);
+
+CREATE TABLE policy_versions (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    policy_id uuid NOT NULL,
+    version integer NOT NULL,
+    source varchar(32) NOT NULL,
+    path varchar(255) NOT NULL,
+    extension varchar(255) NOT NULL,
+    blob_key varchar(64) NOT NULL DEFAULT '',
+    size bigint NOT NULL DEFAULT 0,
+    rules text,
+    CONSTRAINT fk_policies_versions FOREIGN KEY (policy_id) REFERENCES policies (id)
+);
+CREATE UNIQUE INDEX idx_policy_versions_policy_id_version ON policy_versions (policy_id, version);
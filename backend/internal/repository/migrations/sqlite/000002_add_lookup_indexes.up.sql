@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_rules_policy_id ON rules (policy_id);
+CREATE INDEX IF NOT EXISTS idx_rules_policy_id_rule_id ON rules (policy_id, rule_id);
+CREATE INDEX IF NOT EXISTS idx_documents_policy_id ON documents (policy_id);
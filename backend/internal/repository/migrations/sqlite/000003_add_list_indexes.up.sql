@@ -0,0 +1,9 @@
+CREATE INDEX IF NOT EXISTS idx_documents_created_at ON documents (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_documents_compliance_percentage ON documents (compliance_percentage);
+CREATE INDEX IF NOT EXISTS idx_documents_is_compliant ON documents (is_compliant);
+CREATE INDEX IF NOT EXISTS idx_documents_review_required ON documents (created_at) WHERE is_human_review_required;
+
+CREATE INDEX IF NOT EXISTS idx_policies_category ON policies (category);
+CREATE INDEX IF NOT EXISTS idx_policies_created_at ON policies (created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_compliance_results_current ON compliance_results (policy_id, document_id) WHERE superseded_at IS NULL;
@@ -0,0 +1,3 @@
+-- SQLite has no equivalent of the Postgres full-text indexes; search falls
+-- back to LIKE matching (see repository/search.go).
+SELECT 1;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS rule_embeddings (
+    rule_id uuid NOT NULL,
+    model varchar(255) NOT NULL,
+    text_hash varchar(64) NOT NULL,
+    vector blob NOT NULL,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    PRIMARY KEY (rule_id, model),
+    CONSTRAINT fk_rules_rule_embeddings FOREIGN KEY (rule_id) REFERENCES rules (id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE jobs DROP COLUMN api_key_id;
+
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    name varchar(255) NOT NULL,
+    prefix varchar(16) NOT NULL,
+    key_hash varchar(64) NOT NULL,
+    request_quota integer NOT NULL DEFAULT 0,
+    token_quota bigint NOT NULL DEFAULT 0,
+    usage_day datetime NOT NULL,
+    request_count integer NOT NULL DEFAULT 0,
+    token_count bigint NOT NULL DEFAULT 0,
+    last_used_at datetime,
+    revoked_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+
+ALTER TABLE jobs ADD COLUMN api_key_id uuid;
@@ -0,0 +1,30 @@
+DROP INDEX IF EXISTS idx_api_keys_workspace_id;
+ALTER TABLE api_keys DROP COLUMN workspace_id;
+
+DROP INDEX IF EXISTS idx_batches_workspace_id;
+ALTER TABLE batches DROP COLUMN workspace_id;
+
+DROP INDEX IF EXISTS idx_jobs_workspace_id;
+ALTER TABLE jobs DROP COLUMN workspace_id;
+
+DROP INDEX IF EXISTS idx_rule_results_workspace_id;
+ALTER TABLE rule_results DROP COLUMN workspace_id;
+
+DROP INDEX IF EXISTS idx_compliance_results_workspace_id;
+ALTER TABLE compliance_results DROP COLUMN workspace_id;
+
+DROP INDEX IF EXISTS idx_documents_workspace_id;
+ALTER TABLE documents DROP COLUMN workspace_id;
+
+DROP INDEX IF EXISTS idx_rules_workspace_id;
+ALTER TABLE rules DROP COLUMN workspace_id;
+
+DROP INDEX IF EXISTS idx_policy_versions_workspace_id;
+ALTER TABLE policy_versions DROP COLUMN workspace_id;
+
+DROP INDEX IF EXISTS idx_policies_workspace_id;
+ALTER TABLE policies DROP COLUMN workspace_id;
+
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS workspaces;
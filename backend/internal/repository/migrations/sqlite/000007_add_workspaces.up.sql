@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    name varchar(255) NOT NULL
+);
+
+-- Existing rows move to the default workspace.
+INSERT INTO workspaces (id, created_at, updated_at, name)
+VALUES ('00000000-0000-0000-0000-000000000001', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 'Default');
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    email varchar(255) NOT NULL,
+    name varchar(255) NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    id uuid PRIMARY KEY,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    workspace_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    CONSTRAINT fk_workspaces_members FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
+    CONSTRAINT fk_workspace_members_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_members_workspace_id_user_id ON workspace_members (workspace_id, user_id);
+
+-- SQLite cannot add a foreign key column with a non-null default, so
+-- workspace_id is only enforced by the application here.
+
+ALTER TABLE policies ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+CREATE INDEX IF NOT EXISTS idx_policies_workspace_id ON policies (workspace_id);
+
+ALTER TABLE policy_versions ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+CREATE INDEX IF NOT EXISTS idx_policy_versions_workspace_id ON policy_versions (workspace_id);
+
+ALTER TABLE rules ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+CREATE INDEX IF NOT EXISTS idx_rules_workspace_id ON rules (workspace_id);
+
+ALTER TABLE documents ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+CREATE INDEX IF NOT EXISTS idx_documents_workspace_id ON documents (workspace_id);
+
+ALTER TABLE compliance_results ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+CREATE INDEX IF NOT EXISTS idx_compliance_results_workspace_id ON compliance_results (workspace_id);
+
+ALTER TABLE rule_results ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+CREATE INDEX IF NOT EXISTS idx_rule_results_workspace_id ON rule_results (workspace_id);
+
+ALTER TABLE jobs ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+CREATE INDEX IF NOT EXISTS idx_jobs_workspace_id ON jobs (workspace_id);
+
+ALTER TABLE batches ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+CREATE INDEX IF NOT EXISTS idx_batches_workspace_id ON batches (workspace_id);
+
+ALTER TABLE api_keys ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+CREATE INDEX IF NOT EXISTS idx_api_keys_workspace_id ON api_keys (workspace_id);
@@ -0,0 +1,4 @@
+-- How the model's structured output was obtained: clean, repaired or
+-- retried. Rows from before this was recorded are left empty.
+ALTER TABLE policies ADD COLUMN rules_output_path varchar(16) NOT NULL DEFAULT '';
+ALTER TABLE compliance_results ADD COLUMN output_path varchar(16) NOT NULL DEFAULT '';
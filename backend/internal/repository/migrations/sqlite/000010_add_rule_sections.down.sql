@@ -0,0 +1 @@
+ALTER TABLE rules DROP COLUMN section;
@@ -0,0 +1,3 @@
+-- Heading breadcrumb of the policy section each rule was extracted from,
+-- outermost first. Rules from before sectioned extraction have none.
+ALTER TABLE rules ADD COLUMN section text;
@@ -0,0 +1,6 @@
+-- Structured rule attributes filled in by extraction and editable per rule.
+-- Existing rules are left blank until the policy is re-uploaded or edited.
+ALTER TABLE rules ADD COLUMN category varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE rules ADD COLUMN obligation varchar(16) NOT NULL DEFAULT '';
+ALTER TABLE rules ADD COLUMN parent_rule_id varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE rules ADD COLUMN applicability text NOT NULL DEFAULT '';
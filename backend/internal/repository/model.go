@@ -7,6 +7,49 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	SeverityCritical = "critical"
+	SeverityMajor    = "major"
+	SeverityMinor    = "minor"
+)
+
+// Obligations are the modality of a rule: whether it requires, forbids,
+// recommends or permits something.
+const (
+	ObligationMust    = "must"
+	ObligationMustNot = "must-not"
+	ObligationShould  = "should"
+	ObligationMay     = "may"
+)
+
+const (
+	JobStatusQueued     = "queued"
+	JobStatusExtracting = "extracting"
+	JobStatusAnalyzing  = "analyzing"
+	JobStatusDone       = "done"
+	JobStatusFailed     = "failed"
+)
+
+const (
+	// JobKindCheck creates a new document from an uploaded file.
+	JobKindCheck = "check"
+	// JobKindRecheck re-runs an existing document against the current
+	// rules using its stored text.
+	JobKindRecheck = "recheck"
+)
+
+// Roles, from most to least privileged. Submitters only see the documents,
+// jobs and batches they created.
+const (
+	RoleAdmin        = "admin"
+	RolePolicyAuthor = "policy_author"
+	RoleReviewer     = "reviewer"
+	RoleSubmitter    = "submitter"
+	RoleReadOnly     = "read_only"
+)
+
+var Roles = []string{RoleAdmin, RolePolicyAuthor, RoleReviewer, RoleSubmitter, RoleReadOnly}
+
 type BaseModel struct {
 	ID        uuid.UUID      `gorm:"primaryKey;type:uuid;"`
 	CreatedAt time.Time      `gorm:"not null;autoCreateTime"`
@@ -14,35 +57,270 @@ type BaseModel struct {
 	DeletedAt gorm.DeletedAt `gorm:"default:null"`
 }
 
+// Extraction is what Tika extracted from an upload. ExtractedText is the
+// cleaned text that is sent to the model.
+type Extraction struct {
+	ExtractedText string     `gorm:"not null;type:text;default:''"`
+	PageCount     int        `gorm:"not null;type:integer;default:0"`
+	MimeType      string     `gorm:"not null;type:varchar(255);default:''"`
+	Author        string     `gorm:"not null;type:varchar(255);default:''"`
+	AuthoredAt    *time.Time `gorm:"type:timestamptz"`
+	Language      string     `gorm:"not null;type:varchar(16);default:''"`
+}
+
 type Policy struct {
 	BaseModel
+	Tenant
 	Title     string `gorm:"not null;type:varchar(255)"`
 	Category  string `gorm:"not null;type:varchar(255)"`
 	Path      string `gorm:"not null;type:varchar(255)"`
 	Extension string `gorm:"not null;type:varchar(255)"`
 
-	Rules []Rule `gorm:"foreignKey:PolicyID"`
+	BlobKey     string `gorm:"not null;type:varchar(64);default:''"`
+	ContentType string `gorm:"not null;type:varchar(255);default:''"`
+	Size        int64  `gorm:"not null;type:bigint;default:0"`
+
+	Extraction `gorm:"embedded"`
+	// RulesOutputPath is how the model's rule extraction was obtained:
+	// clean, repaired or retried.
+	RulesOutputPath string `gorm:"not null;type:varchar(16);default:''"`
+
+	// PassThreshold overrides the configured minimum compliance percentage.
+	PassThreshold *int `gorm:"type:integer"`
+
+	// Version is the latest version; the policy row and its rules always
+	// hold that version.
+	Version int `gorm:"not null;type:integer;default:1"`
+
+	Rules    []Rule          `gorm:"foreignKey:PolicyID"`
+	Versions []PolicyVersion `gorm:"foreignKey:PolicyID"`
+}
+
+const (
+	VersionSourceUpload = "upload"
+	VersionSourceEdit   = "edit"
+)
+
+// PolicyVersion is a snapshot of a policy's file and rules. Rules keep
+// their UUID across versions, so snapshots can be diffed rule by rule.
+type PolicyVersion struct {
+	BaseModel
+	Tenant
+	PolicyID uuid.UUID `gorm:"not null;type:uuid;uniqueIndex:idx_policy_versions_policy_id_version"`
+	Version  int       `gorm:"not null;type:integer;uniqueIndex:idx_policy_versions_policy_id_version"`
+	Source   string    `gorm:"not null;type:varchar(32)"`
+
+	Path      string `gorm:"not null;type:varchar(255)"`
+	Extension string `gorm:"not null;type:varchar(255)"`
+	BlobKey   string `gorm:"not null;type:varchar(64);default:''"`
+	Size      int64  `gorm:"not null;type:bigint;default:0"`
+
+	Rules RuleSnapshots
+}
+
+type RuleSnapshot struct {
+	RuleUUID uuid.UUID `json:"rule_uuid"`
+	RuleID   string    `json:"rule_id"`
+	RuleText string    `json:"rule_text"`
+	Severity string    `json:"severity"`
+	Weight   float64   `json:"weight"`
+
+	Category      string `json:"category,omitempty"`
+	Obligation    string `json:"obligation,omitempty"`
+	ParentRuleID  string `json:"parent_rule_id,omitempty"`
+	Applicability string `json:"applicability,omitempty"`
 }
 
 type Rule struct {
 	BaseModel
+	Tenant
 	PolicyID uuid.UUID `gorm:"not null;type:uuid;"`
 	RuleID   string    `gorm:"not null;type:varchar(255)"`
 	RuleText string    `gorm:"not null;type:text"`
+	Severity string    `gorm:"not null;type:varchar(32);default:major"`
+	Weight   float64   `gorm:"not null;type:double precision;default:1"`
+	// Section is the heading breadcrumb the rule was found under,
+	// outermost first.
+	Section StringList
+	// Category is the topic of the rule, such as "data retention".
+	Category   string `gorm:"not null;type:varchar(255);default:''"`
+	Obligation string `gorm:"not null;type:varchar(16);default:''"`
+	// ParentRuleID is the RuleID of the clause this one is nested under.
+	ParentRuleID string `gorm:"not null;type:varchar(255);default:''"`
+	// Applicability states when the rule applies, e.g. "only to remote
+	// employees". Empty means always.
+	Applicability string `gorm:"not null;type:text;default:''"`
 
 	Policy Policy `gorm:"foreignKey:PolicyID"`
 }
 
+// IsRequired reports whether a document must satisfy the rule: it is
+// critical, or its obligation is not merely a recommendation or
+// permission. Rules with no recorded obligation count as required.
+func (r Rule) IsRequired() bool {
+	return r.Severity == SeverityCritical ||
+		(r.Obligation != ObligationShould && r.Obligation != ObligationMay)
+}
+
+// RuleEmbedding caches a rule's vector for one embedding model. TextHash
+// is the SHA-256 of the text that was embedded, so edited rules are
+// re-embedded.
+type RuleEmbedding struct {
+	RuleID    uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Model     string    `gorm:"primaryKey;type:varchar(255)"`
+	TextHash  string    `gorm:"not null;type:varchar(64)"`
+	Vector    Vector
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"`
+}
+
 type Document struct {
 	BaseModel
-	Title                 string    `gorm:"not null;type:varchar(255)"`
-	Path                  string    `gorm:"not null;type:varchar(255)"`
-	Extension             string    `gorm:"not null;type:varchar(255)"`
-	Violations            []string  `gorm:"type:jsonb;serializer:json"`
+	Tenant
+	Owner
+	Title                 string `gorm:"not null;type:varchar(255)"`
+	Path                  string `gorm:"not null;type:varchar(255)"`
+	Extension             string `gorm:"not null;type:varchar(255)"`
+	BlobKey               string `gorm:"not null;type:varchar(64);default:''"`
+	ContentType           string `gorm:"not null;type:varchar(255);default:''"`
+	Size                  int64  `gorm:"not null;type:bigint;default:0"`
+	Violations            StringList
 	IsCompliant           bool      `gorm:"not null;type:boolean"`
 	IsHumanReviewRequired bool      `gorm:"not null;type:boolean"`
 	CompliancePercentage  int       `gorm:"not null;type:integer"`
+	ViolationPercentage   int       `gorm:"not null;type:integer;default:0"`
 	PolicyID              uuid.UUID `gorm:"not null;type:uuid;"`
 
-	Policy Policy `gorm:"foreignKey:PolicyID"`
+	// IsStale is set when a policy it was checked against has changed
+	// since its current results were produced.
+	IsStale bool `gorm:"not null;type:boolean;default:false"`
+
+	Extraction `gorm:"embedded"`
+
+	Policy  Policy             `gorm:"foreignKey:PolicyID"`
+	Results []ComplianceResult `gorm:"foreignKey:DocumentID"`
+}
+
+// ComplianceResult is the outcome of checking a document against one
+// policy. The compliance fields on Document roll up all of its current
+// results; superseded ones are kept as history.
+type ComplianceResult struct {
+	BaseModel
+	Tenant
+	DocumentID            uuid.UUID `gorm:"not null;type:uuid;index"`
+	PolicyID              uuid.UUID `gorm:"not null;type:uuid;index"`
+	Violations            StringList
+	IsCompliant           bool `gorm:"not null;type:boolean"`
+	IsHumanReviewRequired bool `gorm:"not null;type:boolean"`
+	CompliancePercentage  int  `gorm:"not null;type:integer"`
+	ViolationPercentage   int  `gorm:"not null;type:integer"`
+	PolicyVersion         int  `gorm:"not null;type:integer;default:1"`
+	// OutputPath is how the model's verdicts were obtained: clean,
+	// repaired or retried.
+	OutputPath string `gorm:"not null;type:varchar(16);default:''"`
+
+	IsStale      bool `gorm:"not null;type:boolean;default:false"`
+	SupersededAt *time.Time
+
+	Policy      Policy       `gorm:"foreignKey:PolicyID"`
+	RuleResults []RuleResult `gorm:"foreignKey:ComplianceResultID"`
+}
+
+type RuleResult struct {
+	BaseModel
+	Tenant
+	DocumentID         uuid.UUID  `gorm:"not null;type:uuid;index"`
+	ComplianceResultID *uuid.UUID `gorm:"type:uuid;index"`
+	RuleUUID           uuid.UUID  `gorm:"type:uuid;"`
+	RuleID             string     `gorm:"not null;type:varchar(255)"`
+	Verdict            string     `gorm:"not null;type:varchar(32)"`
+	Confidence         float64    `gorm:"not null;type:double precision"`
+	Evidence           string     `gorm:"not null;type:text"`
+	EvidenceStart      int        `gorm:"not null;type:integer"`
+	EvidenceEnd        int        `gorm:"not null;type:integer"`
+	Rationale          string     `gorm:"not null;type:text"`
+
+	Rule *Rule `gorm:"foreignKey:RuleUUID"`
+}
+
+type Job struct {
+	BaseModel
+	Tenant
+	Owner
+	Kind      string `gorm:"not null;type:varchar(32);default:check"`
+	Status    string `gorm:"not null;type:varchar(32);index"`
+	PolicyIDs UUIDList
+	FileName  string `gorm:"not null;type:varchar(255)"`
+	BlobKey   string `gorm:"not null;type:varchar(64)"`
+	Size      int64  `gorm:"not null;type:bigint"`
+	Error     string `gorm:"not null;type:text;default:''"`
+	Attempts  int    `gorm:"not null;type:integer;default:0"`
+
+	BatchID        *uuid.UUID `gorm:"type:uuid;index"`
+	DocumentID     *uuid.UUID `gorm:"type:uuid;"`
+	APIKeyID       *uuid.UUID `gorm:"type:uuid;"`
+	LeaseExpiresAt *time.Time
+	StartedAt      *time.Time
+	FinishedAt     *time.Time
+
+	Document *Document `gorm:"foreignKey:DocumentID"`
+}
+
+type Batch struct {
+	BaseModel
+	Tenant
+	Owner
+	PolicyIDs UUIDList
+	FileCount int `gorm:"not null;type:integer"`
+
+	Jobs []Job `gorm:"foreignKey:BatchID"`
+}
+
+// APIKey is a service key for our own API. Only the SHA-256 of the key is
+// stored; Prefix is kept so keys can be told apart in listings. Quotas are
+// per UTC day and 0 means unlimited.
+type APIKey struct {
+	BaseModel
+	Tenant
+	Name    string `gorm:"not null;type:varchar(255)"`
+	Prefix  string `gorm:"not null;type:varchar(16)"`
+	KeyHash string `gorm:"not null;type:varchar(64);uniqueIndex"`
+	Role    string `gorm:"not null;type:varchar(32);default:admin"`
+
+	RequestQuota int   `gorm:"not null;type:integer;default:0"`
+	TokenQuota   int64 `gorm:"not null;type:bigint;default:0"`
+
+	// UsageDay is the day RequestCount and TokenCount belong to; they
+	// restart on the first request of a new day.
+	UsageDay     time.Time `gorm:"not null"`
+	RequestCount int       `gorm:"not null;type:integer;default:0"`
+	TokenCount   int64     `gorm:"not null;type:bigint;default:0"`
+
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// Workspace isolates policies, documents and everything derived from them.
+type Workspace struct {
+	BaseModel
+	Name string `gorm:"not null;type:varchar(255)"`
+
+	Members []WorkspaceMember `gorm:"foreignKey:WorkspaceID"`
+}
+
+// User is a person who can be a member of workspaces. Email is stored
+// lowercased and is unique.
+type User struct {
+	BaseModel
+	Email string `gorm:"not null;type:varchar(255);uniqueIndex"`
+	Name  string `gorm:"not null;type:varchar(255);default:''"`
+}
+
+type WorkspaceMember struct {
+	BaseModel
+	WorkspaceID uuid.UUID `gorm:"not null;type:uuid;uniqueIndex:idx_workspace_members_workspace_id_user_id"`
+	UserID      uuid.UUID `gorm:"not null;type:uuid;uniqueIndex:idx_workspace_members_workspace_id_user_id"`
+	Role        string    `gorm:"not null;type:varchar(32);default:read_only"`
+
+	User User `gorm:"foreignKey:UserID"`
 }
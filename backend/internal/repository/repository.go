@@ -2,61 +2,195 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"policy-match/internal/apperr"
+	"policy-match/internal/config"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/rs/zerolog/log"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type Repository struct {
-	db *gorm.DB
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Repository is the persistence layer used by the service, the LLM client
+// and the worker pool.
+type Repository interface {
+	CreatePolicy(ctx context.Context, policy *Policy) error
+	CreateRules(ctx context.Context, rules []Rule) error
+	GetAllPolicies(ctx context.Context, filter PolicyFilter, offset int, pageSize int) ([]Policy, int, error)
+	GetPolicyByID(ctx context.Context, id uuid.UUID) (*Policy, error)
+	GetPoliciesByCategory(ctx context.Context, category string) ([]Policy, error)
+	UpdatePolicy(ctx context.Context, id uuid.UUID, updates map[string]any) error
+	DeletePolicy(ctx context.Context, id uuid.UUID) error
+	LockPolicy(ctx context.Context, id uuid.UUID, fn func(repo Repository) error) error
+	UpdateRule(ctx context.Context, policyID uuid.UUID, ruleID string, updates map[string]any) error
+	DeleteRule(ctx context.Context, policyID uuid.UUID, ruleID string) error
+	GetRuleEmbeddings(ctx context.Context, model string, ruleIDs []uuid.UUID) ([]RuleEmbedding, error)
+	SaveRuleEmbeddings(ctx context.Context, embeddings []RuleEmbedding) error
+
+	SavePolicyVersion(ctx context.Context, policy *Policy, changes RuleChanges, version *PolicyVersion) error
+	CreatePolicyVersion(ctx context.Context, version *PolicyVersion) error
+	GetPolicyVersions(ctx context.Context, policyID uuid.UUID) ([]PolicyVersion, error)
+	GetPolicyVersion(ctx context.Context, policyID uuid.UUID, version int) (*PolicyVersion, error)
+
+	CreateDocument(ctx context.Context, document *Document) error
+	GetAllDocuments(ctx context.Context, filter DocumentFilter, offset int, pageSize int) ([]Document, int, error)
+	GetDocumentByID(ctx context.Context, id uuid.UUID) (*Document, error)
+	GetDocumentsByPolicy(ctx context.Context, policyID uuid.UUID, staleOnly bool) ([]Document, error)
+	UpdateExtraction(ctx context.Context, documentID uuid.UUID, extraction Extraction) error
+	DeleteDocument(ctx context.Context, id uuid.UUID) error
+
+	MarkPolicyResultsStale(ctx context.Context, policyID uuid.UUID) error
+	ReplaceResults(ctx context.Context, document *Document, results []ComplianceResult) error
+	GetResultHistory(ctx context.Context, documentID uuid.UUID) ([]ComplianceResult, error)
+
+	SearchRules(ctx context.Context, q SearchQuery) ([]RuleHit, error)
+	SearchDocuments(ctx context.Context, q SearchQuery) ([]DocumentHit, error)
+
+	CreateJob(ctx context.Context, job *Job) error
+	GetJobByID(ctx context.Context, id uuid.UUID) (*Job, error)
+	ClaimNextJob(ctx context.Context, lease time.Duration) (*Job, error)
+	UpdateJob(ctx context.Context, id uuid.UUID, updates map[string]any) error
+	CreateBatch(ctx context.Context, batch *Batch, jobs []Job) error
+	GetBatchByID(ctx context.Context, id uuid.UUID) (*Batch, error)
+	BlobInUse(ctx context.Context, key string) (bool, error)
+
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeys(ctx context.Context) ([]APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error
+	UseAPIKey(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	AddAPIKeyTokens(ctx context.Context, id uuid.UUID, tokens int) error
+
+	CreateWorkspace(ctx context.Context, workspace *Workspace) error
+	GetWorkspaces(ctx context.Context) ([]Workspace, error)
+	GetWorkspaceByID(ctx context.Context, id uuid.UUID) (*Workspace, error)
+	UpdateWorkspace(ctx context.Context, id uuid.UUID, updates map[string]any) error
+	GetOrCreateUser(ctx context.Context, email string, name string) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	AddWorkspaceMember(ctx context.Context, member *WorkspaceMember) error
+	GetWorkspaceMembers(ctx context.Context, workspaceID uuid.UUID) ([]WorkspaceMember, error)
+	GetWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) (*WorkspaceMember, error)
+	RemoveWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) error
+}
+
+// NewRepository opens the database selected by DB_DRIVER and, unless
+// DB_AUTO_MIGRATE is off, applies pending migrations.
+func NewRepository(cfg *config.Config) (Repository, error) {
+	db, err := open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("newRepository :: %w", err)
+	}
+
+	if cfg.DBAutoMigrate {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("newRepository :: %w", err)
+		}
+		migrator, err := NewMigrator(sqlDB, cfg.DBDriver)
+		if err != nil {
+			return nil, fmt.Errorf("newRepository :: %w", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			return nil, fmt.Errorf("newRepository :: %w", err)
+		}
+	}
+
+	return &gormRepository{db: db}, nil
 }
 
-func NewRepository(dbURL string) *Repository {
-	db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{})
+// OpenMigrator opens the configured database for the migrate command.
+// Close the returned migrator when done.
+func OpenMigrator(cfg *config.Config) (*Migrator, error) {
+	db, err := open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("openMigrator :: %w", err)
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Error().Msg("Failed to connect to database: " + err.Error())
+		return nil, fmt.Errorf("openMigrator :: %w", err)
 	}
+	return NewMigrator(sqlDB, cfg.DBDriver)
+}
 
-	err = db.AutoMigrate(
-		&Policy{},
-		&Rule{},
-		&Document{},
+func open(cfg *config.Config) (*gorm.DB, error) {
+	var (
+		db  *gorm.DB
+		err error
 	)
+	switch cfg.DBDriver {
+	case DriverPostgres:
+		db, err = openPostgres(cfg.DBURL)
+	case DriverSQLite:
+		db, err = openSQLite(cfg.DBURL)
+	default:
+		return nil, fmt.Errorf("unknown driver %q", cfg.DBDriver)
+	}
 	if err != nil {
-		log.Error().Msg("migration failed: " + err.Error())
+		return nil, fmt.Errorf("open %s: %w", cfg.DBDriver, err)
 	}
-	return &Repository{db: db}
+	if err := registerTenantCallbacks(db); err != nil {
+		return nil, fmt.Errorf("open %s: %w", cfg.DBDriver, err)
+	}
+	return db, nil
+}
+
+// gormRepository implements Repository for every gorm dialect we support.
+type gormRepository struct {
+	db *gorm.DB
+}
+
+// omitText skips the extracted text column when loading policies or
+// documents only for listing or as associations.
+func omitText(db *gorm.DB) *gorm.DB {
+	return db.Omit("ExtractedText")
+}
+
+// currentResults skips compliance results that a re-check superseded.
+func currentResults(db *gorm.DB) *gorm.DB {
+	return db.Where("superseded_at IS NULL")
+}
+
+// withDeleted includes soft-deleted rows, so results keep the attributes of
+// rules removed since they were checked.
+func withDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
 }
 
-func (r *Repository) CreatePolicy(ctx context.Context, policy *Policy) error {
+func (r *gormRepository) CreatePolicy(ctx context.Context, policy *Policy) error {
 	return r.db.
 		WithContext(ctx).
 		Create(policy).
 		Error
 }
 
-func (r *Repository) CreateRules(ctx context.Context, rules []Rule) error {
+func (r *gormRepository) CreateRules(ctx context.Context, rules []Rule) error {
 	return r.db.
 		WithContext(ctx).
 		Create(rules).
 		Error
 }
 
-func (r *Repository) CreateDocument(ctx context.Context, document *Document) error {
+func (r *gormRepository) CreateDocument(ctx context.Context, document *Document) error {
 	return r.db.
 		WithContext(ctx).
 		Create(document).
 		Error
 }
 
-func (r *Repository) GetAllPolicies(ctx context.Context, offset int, pageSize int) ([]Policy, int, error) {
+func (r *gormRepository) GetAllPolicies(ctx context.Context, filter PolicyFilter, offset int, pageSize int) ([]Policy, int, error) {
 	var policies []Policy
 	var total int64
 
 	err := r.db.
 		WithContext(ctx).
+		Scopes(omitText, filter.scope, filter.order).
 		Preload("Rules").
 		Offset(offset).
 		Limit(pageSize).
@@ -68,6 +202,7 @@ func (r *Repository) GetAllPolicies(ctx context.Context, offset int, pageSize in
 	err = r.db.
 		WithContext(ctx).
 		Model(&Policy{}).
+		Scopes(filter.scope).
 		Count(&total).
 		Error
 	if err != nil {
@@ -76,12 +211,17 @@ func (r *Repository) GetAllPolicies(ctx context.Context, offset int, pageSize in
 	return policies, int(total), nil
 }
 
-func (r *Repository) GetAllDocuments(ctx context.Context, offset int, pageSize int) ([]Document, int, error) {
+func (r *gormRepository) GetAllDocuments(ctx context.Context, filter DocumentFilter, offset int, pageSize int) ([]Document, int, error) {
 	var documents []Document
 	var total int64
 
 	err := r.db.
 		WithContext(ctx).
+		Scopes(omitText, filter.scope, filter.order).
+		Preload("Policy", omitText).
+		Preload("Results", currentResults).
+		Preload("Results.Policy", omitText).
+		Preload("Results.RuleResults").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&documents).
@@ -92,6 +232,7 @@ func (r *Repository) GetAllDocuments(ctx context.Context, offset int, pageSize i
 	err = r.db.
 		WithContext(ctx).
 		Model(&Document{}).
+		Scopes(filter.scope).
 		Count(&total).
 		Error
 	if err != nil {
@@ -100,7 +241,7 @@ func (r *Repository) GetAllDocuments(ctx context.Context, offset int, pageSize i
 	return documents, int(total), nil
 }
 
-func (r *Repository) GetPolicyByID(ctx context.Context, id uuid.UUID) (*Policy, error) {
+func (r *gormRepository) GetPolicyByID(ctx context.Context, id uuid.UUID) (*Policy, error) {
 	var policy Policy
 
 	err := r.db.
@@ -109,38 +250,518 @@ func (r *Repository) GetPolicyByID(ctx context.Context, id uuid.UUID) (*Policy,
 		First(&policy, "id = ?", id).
 		Error
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "policy_not_found")
 	}
 	return &policy, nil
 }
 
-func (r *Repository) DeleteDocument(ctx context.Context, id uuid.UUID) error {
-	return r.db.
+func (r *gormRepository) GetDocumentByID(ctx context.Context, id uuid.UUID) (*Document, error) {
+	var document Document
+
+	err := r.db.
 		WithContext(ctx).
-		Delete(&Document{}, id).
+		Preload("Policy", omitText).
+		Preload("Results", currentResults).
+		Preload("Results.Policy", omitText).
+		Preload("Results.RuleResults").
+		Preload("Results.RuleResults.Rule", withDeleted).
+		First(&document, "id = ?", id).
 		Error
+	if err != nil {
+		return nil, notFound(err, "document_not_found")
+	}
+	return &document, nil
 }
 
-func (r *Repository) DeletePolicy(ctx context.Context, id uuid.UUID) error {
-	return r.db.
+func (r *gormRepository) GetPoliciesByCategory(ctx context.Context, category string) ([]Policy, error) {
+	var policies []Policy
+
+	err := r.db.
 		WithContext(ctx).
-		Delete(&Policy{}, id).
+		Preload("Rules").
+		Where("category = ?", category).
+		Order("created_at").
+		Find(&policies).
 		Error
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
 }
 
-func (r *Repository) DeleteRule(ctx context.Context, id uuid.UUID) error {
-	return r.db.
+func (r *gormRepository) DeleteDocument(ctx context.Context, id uuid.UUID) error {
+	res := r.db.
 		WithContext(ctx).
-		Delete(&Rule{}, id).
+		Delete(&Document{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apperr.NotFound("document_not_found", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (r *gormRepository) DeletePolicy(ctx context.Context, id uuid.UUID) error {
+	res := r.db.
+		WithContext(ctx).
+		Delete(&Policy{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apperr.NotFound("policy_not_found", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// LockPolicy runs fn in a transaction that holds a lock on the policy row,
+// with a Repository bound to that transaction, so concurrent edits of one
+// policy run one after another. fn must only use repo. On SQLite the single
+// connection already serializes transactions.
+func (r *gormRepository) LockPolicy(ctx context.Context, id uuid.UUID, fn func(repo Repository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx
+		if tx.Dialector.Name() == DriverPostgres {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		var policy Policy
+		if err := query.Select("id").First(&policy, "id = ?", id).Error; err != nil {
+			return notFound(err, "policy_not_found")
+		}
+		return fn(&gormRepository{db: tx})
+	})
+}
+
+func (r *gormRepository) DeleteRule(ctx context.Context, policyID uuid.UUID, ruleID string) error {
+	res := r.db.
+		WithContext(ctx).
+		Where("policy_id = ?", policyID).
+		Where("rule_id = ?", ruleID).
+		Delete(&Rule{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apperr.NotFound("rule_not_found", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (r *gormRepository) GetRuleEmbeddings(ctx context.Context, model string, ruleIDs []uuid.UUID) ([]RuleEmbedding, error) {
+	var embeddings []RuleEmbedding
+	if len(ruleIDs) == 0 {
+		return embeddings, nil
+	}
+	err := r.db.
+		WithContext(ctx).
+		Where("model = ?", model).
+		Where("rule_id IN ?", ruleIDs).
+		Find(&embeddings).
 		Error
+	if err != nil {
+		return nil, err
+	}
+	return embeddings, nil
 }
 
-func (r *Repository) UpdateRule(ctx context.Context, policyID uuid.UUID, ruleID string, updates map[string]any) error {
+// SaveRuleEmbeddings inserts embeddings, replacing any stored for the same
+// rule and model.
+func (r *gormRepository) SaveRuleEmbeddings(ctx context.Context, embeddings []RuleEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
 	return r.db.
+		WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "rule_id"}, {Name: "model"}},
+			DoUpdates: clause.AssignmentColumns([]string{"text_hash", "vector", "updated_at"}),
+		}).
+		Create(&embeddings).
+		Error
+}
+
+func (r *gormRepository) UpdatePolicy(ctx context.Context, id uuid.UUID, updates map[string]any) error {
+	res := r.db.
+		WithContext(ctx).
+		Model(&Policy{}).
+		Where("id = ?", id).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apperr.NotFound("policy_not_found", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (r *gormRepository) UpdateRule(ctx context.Context, policyID uuid.UUID, ruleID string, updates map[string]any) error {
+	res := r.db.
 		WithContext(ctx).
 		Model(&Rule{}).
 		Where("policy_id = ?", policyID).
 		Where("rule_id = ?", ruleID).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apperr.NotFound("rule_not_found", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (r *gormRepository) CreateJob(ctx context.Context, job *Job) error {
+	return r.db.
+		WithContext(ctx).
+		Create(job).
+		Error
+}
+
+func (r *gormRepository) GetJobByID(ctx context.Context, id uuid.UUID) (*Job, error) {
+	var job Job
+
+	err := r.db.
+		WithContext(ctx).
+		Preload("Document", omitText).
+		Preload("Document.Policy", omitText).
+		Preload("Document.Results", currentResults).
+		Preload("Document.Results.Policy", omitText).
+		Preload("Document.Results.RuleResults").
+		First(&job, "id = ?", id).
+		Error
+	if err != nil {
+		return nil, notFound(err, "job_not_found")
+	}
+	return &job, nil
+}
+
+// ClaimNextJob atomically takes the oldest queued job, or a running job
+// whose lease expired because its worker died, and leases it to the caller.
+// A queued job with a lease is waiting to be retried and is skipped until
+// the lease expires. It returns nil when there is nothing to do.
+func (r *gormRepository) ClaimNextJob(ctx context.Context, lease time.Duration) (*Job, error) {
+	var job Job
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		query := tx
+		// SQLite has no row locks; it serializes writers instead and the
+		// conditional update below catches a lost race.
+		if tx.Dialector.Name() == DriverPostgres {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		err := query.
+			Where(
+				"(status = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?)) OR (status IN ? AND lease_expires_at < ?)",
+				JobStatusQueued,
+				now,
+				[]string{JobStatusExtracting, JobStatusAnalyzing},
+				now,
+			).
+			Order("created_at").
+			First(&job).
+			Error
+		if err != nil {
+			return err
+		}
+
+		job.Status = JobStatusExtracting
+		job.Attempts++
+		job.StartedAt = &now
+		expires := now.Add(lease)
+		job.LeaseExpiresAt = &expires
+		res := tx.
+			Model(&Job{}).
+			Where("id = ?", job.ID).
+			Where("attempts = ?", job.Attempts-1).
+			Updates(map[string]any{
+				"status":           job.Status,
+				"attempts":         job.Attempts,
+				"started_at":       job.StartedAt,
+				"lease_expires_at": job.LeaseExpiresAt,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *gormRepository) UpdateJob(ctx context.Context, id uuid.UUID, updates map[string]any) error {
+	return r.db.
+		WithContext(ctx).
+		Model(&Job{}).
+		Where("id = ?", id).
 		Updates(updates).
 		Error
 }
+
+// CreateBatch stores a batch together with all of its queued jobs.
+func (r *gormRepository) CreateBatch(ctx context.Context, batch *Batch, jobs []Job) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Jobs").Create(batch).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(jobs, 50).Error
+	})
+}
+
+func (r *gormRepository) GetBatchByID(ctx context.Context, id uuid.UUID) (*Batch, error) {
+	var batch Batch
+
+	err := r.db.
+		WithContext(ctx).
+		Preload("Jobs", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at")
+		}).
+		Preload("Jobs.Document", omitText).
+		Preload("Jobs.Document.Results", currentResults).
+		Preload("Jobs.Document.Results.Policy", omitText).
+		First(&batch, "id = ?", id).
+		Error
+	if err != nil {
+		return nil, notFound(err, "batch_not_found")
+	}
+	return &batch, nil
+}
+
+// BlobInUse reports whether any policy, policy version, document or job
+// points to the blob. Identical uploads share a blob across workspaces, so
+// the check is deliberately not scoped to the caller's.
+func (r *gormRepository) BlobInUse(ctx context.Context, key string) (bool, error) {
+	for _, table := range []string{"policies", "policy_versions", "documents", "jobs"} {
+		var count int64
+		err := r.db.WithContext(ctx).Table(table).Where("blob_key = ?", key).Count(&count).Error
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MarkPolicyResultsStale flags the current results for policyID, and the
+// documents that own them, as out of date.
+func (r *gormRepository) MarkPolicyResultsStale(ctx context.Context, policyID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&Document{}).
+			Where("id IN (?)", tx.
+				Model(&ComplianceResult{}).
+				Select("document_id").
+				Where("policy_id = ?", policyID).
+				Where("superseded_at IS NULL")).
+			Update("is_stale", true).
+			Error
+		if err != nil {
+			return err
+		}
+		return tx.
+			Model(&ComplianceResult{}).
+			Where("policy_id = ?", policyID).
+			Where("superseded_at IS NULL").
+			Update("is_stale", true).
+			Error
+	})
+}
+
+// GetDocumentsByPolicy returns the documents with a current result for
+// policyID, without their text.
+func (r *gormRepository) GetDocumentsByPolicy(ctx context.Context, policyID uuid.UUID, staleOnly bool) ([]Document, error) {
+	var documents []Document
+
+	query := r.db.
+		WithContext(ctx).
+		Model(&ComplianceResult{}).
+		Select("document_id").
+		Where("policy_id = ?", policyID).
+		Where("superseded_at IS NULL")
+	if staleOnly {
+		query = query.Where("is_stale = ?", true)
+	}
+
+	err := r.db.
+		WithContext(ctx).
+		Scopes(omitText).
+		Where("id IN (?)", query).
+		Order("created_at").
+		Find(&documents).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// ReplaceResults supersedes the current results of document for the
+// policies in results, stores results and saves the document's rollup.
+func (r *gormRepository) ReplaceResults(ctx context.Context, document *Document, results []ComplianceResult) error {
+	policyIDs := make([]uuid.UUID, len(results))
+	for i, res := range results {
+		policyIDs[i] = res.PolicyID
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&ComplianceResult{}).
+			Where("document_id = ?", document.ID).
+			Where("policy_id IN ?", policyIDs).
+			Where("superseded_at IS NULL").
+			Update("superseded_at", time.Now()).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(&results).Error; err != nil {
+			return err
+		}
+
+		return tx.
+			Model(&Document{}).
+			Where("id = ?", document.ID).
+			Updates(map[string]any{
+				"violations":               document.Violations,
+				"is_compliant":             document.IsCompliant,
+				"is_human_review_required": document.IsHumanReviewRequired,
+				"compliance_percentage":    document.CompliancePercentage,
+				"violation_percentage":     document.ViolationPercentage,
+				"is_stale":                 document.IsStale,
+			}).
+			Error
+	})
+}
+
+// GetResultHistory returns every result of documentID, superseded ones
+// included, newest first.
+func (r *gormRepository) GetResultHistory(ctx context.Context, documentID uuid.UUID) ([]ComplianceResult, error) {
+	var results []ComplianceResult
+
+	err := r.db.
+		WithContext(ctx).
+		Preload("Policy", omitText).
+		Preload("RuleResults").
+		Where("document_id = ?", documentID).
+		Order("created_at DESC").
+		Find(&results).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// UpdateExtraction stores a fresh extraction for a document.
+func (r *gormRepository) UpdateExtraction(ctx context.Context, documentID uuid.UUID, extraction Extraction) error {
+	return r.db.
+		WithContext(ctx).
+		Model(&Document{}).
+		Where("id = ?", documentID).
+		Select("ExtractedText", "PageCount", "MimeType", "Author", "AuthoredAt", "Language").
+		Updates(&Document{Extraction: extraction}).
+		Error
+}
+
+// RuleChanges are the rule writes that turn one policy version into the
+// next.
+type RuleChanges struct {
+	Created []Rule
+	Updated []Rule
+	Deleted []uuid.UUID
+}
+
+// SavePolicyVersion stores policy as its new latest version: the policy
+// row, its rule changes and the version snapshot are written together.
+func (r *gormRepository) SavePolicyVersion(ctx context.Context, policy *Policy, changes RuleChanges, version *PolicyVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(policy).Error; err != nil {
+			return err
+		}
+
+		for _, rule := range changes.Updated {
+			err := tx.
+				Model(&Rule{}).
+				Where("id = ?", rule.ID).
+				Select("RuleID", "RuleText", "Severity", "Weight", "Section", "Category", "Obligation", "ParentRuleID", "Applicability").
+				Updates(&rule).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(changes.Deleted) > 0 {
+			if err := tx.Delete(&Rule{}, "id IN ?", changes.Deleted).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(changes.Created) > 0 {
+			if err := tx.Omit("Policy").Create(&changes.Created).Error; err != nil {
+				return err
+			}
+		}
+
+		return conflict(tx.Create(version).Error, "policy_version_conflict")
+	})
+}
+
+// CreatePolicyVersion stores a snapshot and makes it the policy's latest
+// version.
+func (r *gormRepository) CreatePolicyVersion(ctx context.Context, version *PolicyVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&Policy{}).
+			Where("id = ?", version.PolicyID).
+			Update("version", version.Version).
+			Error
+		if err != nil {
+			return err
+		}
+		return conflict(tx.Create(version).Error, "policy_version_conflict")
+	})
+}
+
+func (r *gormRepository) GetPolicyVersions(ctx context.Context, policyID uuid.UUID) ([]PolicyVersion, error) {
+	var versions []PolicyVersion
+
+	err := r.db.
+		WithContext(ctx).
+		Where("policy_id = ?", policyID).
+		Order("version").
+		Find(&versions).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *gormRepository) GetPolicyVersion(ctx context.Context, policyID uuid.UUID, version int) (*PolicyVersion, error) {
+	var policyVersion PolicyVersion
+
+	err := r.db.
+		WithContext(ctx).
+		Where("policy_id = ?", policyID).
+		Where("version = ?", version).
+		First(&policyVersion).
+		Error
+	if err != nil {
+		return nil, notFound(err, "policy_version_not_found")
+	}
+	return &policyVersion, nil
+}
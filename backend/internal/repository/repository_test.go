@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"policy-match/internal/apperr"
+	"policy-match/internal/config"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func newTestRepository(t *testing.T) Repository {
+	t.Helper()
+	repo, err := NewRepository(&config.Config{DBDriver: DriverSQLite, DBURL: ":memory:", DBAutoMigrate: true})
+	if err != nil {
+		t.Fatalf("newRepository: %v", err)
+	}
+	return repo
+}
+
+func createTestPolicy(t *testing.T, ctx context.Context, repo Repository, rules ...string) *Policy {
+	t.Helper()
+	policy := &Policy{
+		BaseModel: BaseModel{ID: uuid.New()},
+		Title:     "Security",
+		Category:  "security",
+		Path:      "security.pdf",
+		Extension: ".pdf",
+		Version:   1,
+	}
+	if err := repo.CreatePolicy(ctx, policy); err != nil {
+		t.Fatalf("createPolicy: %v", err)
+	}
+	var models []Rule
+	for i, text := range rules {
+		models = append(models, Rule{
+			BaseModel: BaseModel{ID: uuid.New()},
+			PolicyID:  policy.ID,
+			RuleID:    string(rune('1' + i)),
+			RuleText:  text,
+			Severity:  SeverityMajor,
+			Weight:    1,
+		})
+	}
+	if len(models) > 0 {
+		if err := repo.CreateRules(ctx, models); err != nil {
+			t.Fatalf("createRules: %v", err)
+		}
+	}
+	return policy
+}
+
+func TestPolicyRules(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	policy := createTestPolicy(t, ctx, repo, "Staff must wear badges.", "Visitors must sign in.")
+
+	stored, err := repo.GetPolicyByID(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("getPolicyByID: %v", err)
+	}
+	if len(stored.Rules) != 2 || stored.WorkspaceID != DefaultWorkspaceID {
+		t.Fatalf("policy = %+v, want 2 rules in the default workspace", stored)
+	}
+
+	if err := repo.UpdateRule(ctx, policy.ID, "1", map[string]any{"severity": SeverityCritical}); err != nil {
+		t.Fatalf("updateRule: %v", err)
+	}
+	if err := repo.DeleteRule(ctx, policy.ID, "2"); err != nil {
+		t.Fatalf("deleteRule: %v", err)
+	}
+	stored, _ = repo.GetPolicyByID(ctx, policy.ID)
+	if len(stored.Rules) != 1 || stored.Rules[0].Severity != SeverityCritical {
+		t.Errorf("rules = %+v, want only rule 1, now critical", stored.Rules)
+	}
+
+	if err := repo.UpdateRule(ctx, policy.ID, "2", map[string]any{"severity": SeverityMinor}); !apperr.Is(err, apperr.KindNotFound) {
+		t.Errorf("update deleted rule: %v, want not found", err)
+	}
+	if err := repo.DeleteRule(ctx, policy.ID, "2"); !apperr.Is(err, apperr.KindNotFound) {
+		t.Errorf("delete deleted rule: %v, want not found", err)
+	}
+
+	if _, err := repo.GetPolicyByID(ctx, uuid.New()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("missing policy: %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestWorkspaceScoping(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	policy := createTestPolicy(t, ctx, repo, "Staff must wear badges.")
+
+	other := &Workspace{BaseModel: BaseModel{ID: uuid.New()}, Name: "Other"}
+	if err := repo.CreateWorkspace(ctx, other); err != nil {
+		t.Fatalf("createWorkspace: %v", err)
+	}
+
+	if _, err := repo.GetPolicyByID(WithWorkspace(ctx, DefaultWorkspaceID), policy.ID); err != nil {
+		t.Errorf("own workspace: %v", err)
+	}
+	if _, err := repo.GetPolicyByID(WithWorkspace(ctx, other.ID), policy.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("other workspace: %v, want ErrRecordNotFound", err)
+	}
+	err := repo.UpdatePolicy(WithWorkspace(ctx, other.ID), policy.ID, map[string]any{"title": "Taken"})
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("other workspace update: %v, want ErrRecordNotFound", err)
+	}
+	policies, total, err := repo.GetAllPolicies(WithWorkspace(ctx, other.ID), PolicyFilter{}, 0, 10)
+	if err != nil || total != 0 || len(policies) != 0 {
+		t.Errorf("other workspace lists %d policies (%d total), err %v, want none", len(policies), total, err)
+	}
+}
+
+func TestLockPolicy(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	policy := createTestPolicy(t, ctx, repo, "Staff must wear badges.")
+
+	err := repo.LockPolicy(ctx, policy.ID, func(tx Repository) error {
+		return tx.UpdatePolicy(ctx, policy.ID, map[string]any{"version": 2})
+	})
+	if err != nil {
+		t.Fatalf("lockPolicy: %v", err)
+	}
+
+	failed := errors.New("edit failed")
+	err = repo.LockPolicy(ctx, policy.ID, func(tx Repository) error {
+		if err := tx.UpdatePolicy(ctx, policy.ID, map[string]any{"version": 3}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("lockPolicy: %v, want the edit's error", err)
+	}
+
+	stored, _ := repo.GetPolicyByID(ctx, policy.ID)
+	if stored.Version != 2 {
+		t.Errorf("version = %d, want 2 with the failed edit rolled back", stored.Version)
+	}
+
+	if err := repo.LockPolicy(ctx, uuid.New(), func(Repository) error { return nil }); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("missing policy: %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestClaimNextJob(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	newJob := func(name string) *Job {
+		job := &Job{BaseModel: BaseModel{ID: uuid.New()}, Kind: JobKindCheck, Status: JobStatusQueued, FileName: name, BlobKey: "key"}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("createJob: %v", err)
+		}
+		return job
+	}
+	first := newJob("a.pdf")
+	time.Sleep(time.Millisecond)
+	second := newJob("b.pdf")
+
+	claimed, err := repo.ClaimNextJob(ctx, time.Minute)
+	if err != nil || claimed == nil || claimed.ID != first.ID {
+		t.Fatalf("first claim = %+v, %v, want the oldest job", claimed, err)
+	}
+	if claimed.Status != JobStatusExtracting || claimed.Attempts != 1 {
+		t.Errorf("claimed = %+v, want it extracting on its first attempt", claimed)
+	}
+
+	// A job queued for a retry waits until its lease expires.
+	retryAt := time.Now().Add(time.Hour)
+	if err := repo.UpdateJob(ctx, second.ID, map[string]any{"lease_expires_at": retryAt}); err != nil {
+		t.Fatalf("updateJob: %v", err)
+	}
+	if job, err := repo.ClaimNextJob(ctx, time.Minute); err != nil || job != nil {
+		t.Fatalf("claim with every job leased = %+v, %v, want none", job, err)
+	}
+
+	// A running job whose lease expired is picked up again.
+	if err := repo.UpdateJob(ctx, first.ID, map[string]any{"lease_expires_at": time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("updateJob: %v", err)
+	}
+	claimed, err = repo.ClaimNextJob(ctx, time.Minute)
+	if err != nil || claimed == nil || claimed.ID != first.ID || claimed.Attempts != 2 {
+		t.Fatalf("reclaim = %+v, %v, want the expired job on attempt 2", claimed, err)
+	}
+}
+
+func TestSearchRulesSQLite(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	policy := createTestPolicy(t, ctx, repo, "Staff must wear badges.", "Discounts up to 100% need approval.", "Visitors must sign in.")
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"badges", 1},
+		{"MUST", 2},
+		{"staff badges", 1},
+		{"staff visitors", 0},
+		{"100%", 1},
+		{"%", 1},
+		{"_", 0},
+	}
+	for _, tt := range tests {
+		hits, err := repo.SearchRules(ctx, SearchQuery{Text: tt.query, PolicyID: &policy.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("searchRules(%q): %v", tt.query, err)
+		}
+		if len(hits) != tt.want {
+			t.Errorf("searchRules(%q) = %d hits, want %d", tt.query, len(hits), tt.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"html"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The expressions below must match the indexes in migration 000004.
+const (
+	ruleVector     = "to_tsvector('english', rules.rule_text)"
+	documentVector = "to_tsvector('english', left(documents.extracted_text, 1000000))"
+	searchQuery    = "websearch_to_tsquery('english', ?)"
+
+	// ts_headline wraps matches in these control characters rather than in
+	// <mark>, so the text around them can be escaped before they are
+	// replaced by tags.
+	markStart       = "\x02"
+	markStop        = "\x03"
+	headlineOptions = "StartSel=" + markStart + ", StopSel=" + markStop + ", MaxWords=35, MinWords=15, MaxFragments=2"
+)
+
+var marks = strings.NewReplacer(markStart, "<mark>", markStop, "</mark>")
+
+// SearchQuery is a full-text query in web search syntax ("quoted phrases",
+// -excluded, or). PolicyID and Category scope rules by their policy and
+// documents by the policies they were checked against.
+type SearchQuery struct {
+	Text     string
+	PolicyID *uuid.UUID
+	Category string
+	Limit    int
+}
+
+type RuleHit struct {
+	RuleUUID    uuid.UUID
+	RuleID      string
+	RuleText    string
+	PolicyID    uuid.UUID
+	PolicyTitle string
+	Category    string
+	Rank        float64
+	Snippet     string
+}
+
+type DocumentHit struct {
+	DocumentID uuid.UUID
+	Title      string
+	CreatedAt  time.Time
+	Rank       float64
+	Snippet    string
+}
+
+func (r *gormRepository) SearchRules(ctx context.Context, q SearchQuery) ([]RuleHit, error) {
+	var hits []RuleHit
+
+	query := r.db.
+		WithContext(ctx).
+		Table("rules").
+		Joins("JOIN policies ON policies.id = rules.policy_id").
+		Scopes(inWorkspace(ctx, "rules")).
+		Where("rules.deleted_at IS NULL").
+		Where("policies.deleted_at IS NULL")
+	if q.PolicyID != nil {
+		query = query.Where("rules.policy_id = ?", *q.PolicyID)
+	}
+	if q.Category != "" {
+		query = query.Where("policies.category = ?", q.Category)
+	}
+
+	if r.db.Dialector.Name() != DriverPostgres {
+		err := query.
+			Select("rules.id AS rule_uuid, rules.rule_id, rules.rule_text, rules.policy_id, policies.title AS policy_title, policies.category").
+			Scopes(containsAll("rules.rule_text", q.Text)).
+			Order("rules.created_at DESC").
+			Limit(q.Limit).
+			Scan(&hits).
+			Error
+		if err != nil {
+			return nil, err
+		}
+		for i := range hits {
+			hits[i].Snippet = snippet(hits[i].RuleText, q.Text)
+		}
+		return hits, nil
+	}
+
+	err := query.
+		Select(
+			"rules.id AS rule_uuid, rules.rule_id, rules.rule_text, rules.policy_id, policies.title AS policy_title, policies.category, "+
+				"ts_rank("+ruleVector+", query) AS rank, "+
+				"ts_headline('english', rules.rule_text, query, '"+headlineOptions+"') AS snippet",
+		).
+		Joins("CROSS JOIN "+searchQuery+" AS query", q.Text).
+		Where(ruleVector + " @@ query").
+		Order("rank DESC").
+		Order("rules.id").
+		Limit(q.Limit).
+		Scan(&hits).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].Snippet = highlight(hits[i].Snippet)
+	}
+	return hits, nil
+}
+
+func (r *gormRepository) SearchDocuments(ctx context.Context, q SearchQuery) ([]DocumentHit, error) {
+	var hits []DocumentHit
+
+	query := r.db.
+		WithContext(ctx).
+		Table("documents").
+		Scopes(inWorkspace(ctx, "documents"), ownedBy(ctx, "documents")).
+		Where("documents.deleted_at IS NULL").
+		Scopes(DocumentFilter{PolicyID: q.PolicyID, Category: q.Category}.scope)
+
+	if r.db.Dialector.Name() != DriverPostgres {
+		var rows []struct {
+			ID            uuid.UUID
+			Title         string
+			CreatedAt     time.Time
+			ExtractedText string
+		}
+		err := query.
+			Select("documents.id, documents.title, documents.created_at, documents.extracted_text").
+			Scopes(containsAll("documents.extracted_text", q.Text)).
+			Order("documents.created_at DESC").
+			Limit(q.Limit).
+			Scan(&rows).
+			Error
+		if err != nil {
+			return nil, err
+		}
+		hits = make([]DocumentHit, len(rows))
+		for i, row := range rows {
+			hits[i] = DocumentHit{
+				DocumentID: row.ID,
+				Title:      row.Title,
+				CreatedAt:  row.CreatedAt,
+				Snippet:    snippet(row.ExtractedText, q.Text),
+			}
+		}
+		return hits, nil
+	}
+
+	// ts_headline re-parses the whole text, so it only runs on the ranked
+	// page rather than on every match.
+	ranked := query.
+		Select("documents.id, documents.title, documents.created_at, documents.extracted_text, ts_rank("+documentVector+", query) AS rank").
+		Joins("CROSS JOIN "+searchQuery+" AS query", q.Text).
+		Where(documentVector + " @@ query").
+		Order("rank DESC").
+		Order("documents.id").
+		Limit(q.Limit)
+
+	err := r.db.
+		WithContext(ctx).
+		Table("(?) AS hits", ranked).
+		Select(
+			"hits.id AS document_id, hits.title, hits.created_at, hits.rank, "+
+				"ts_headline('english', left(hits.extracted_text, 1000000), "+searchQuery+", '"+headlineOptions+"') AS snippet",
+			q.Text,
+		).
+		Order("hits.rank DESC").
+		Order("hits.id").
+		Scan(&hits).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].Snippet = highlight(hits[i].Snippet)
+	}
+	return hits, nil
+}
+
+// containsAll is the SQLite fallback for full-text search: every word of
+// text must appear in column, ignoring case. Results are not ranked.
+func containsAll(column, text string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		escape := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+		for _, word := range searchWords(text) {
+			db = db.Where("LOWER("+column+`) LIKE ? ESCAPE '\'`, "%"+escape.Replace(word)+"%")
+		}
+		return db
+	}
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '"'
+	})
+}
+
+// highlight HTML-escapes a snippet, then turns the markers around matched
+// terms into <mark> tags. Stored text comes from uploaded files and must not
+// reach clients as markup.
+func highlight(snippet string) string {
+	return marks.Replace(html.EscapeString(snippet))
+}
+
+// snippet returns the text around the first word of query found in text,
+// with that word wrapped in <mark> like ts_headline does. The text is
+// HTML-escaped.
+func snippet(text, query string) string {
+	const radius = 120
+
+	lower := strings.ToLower(text)
+	for _, word := range searchWords(query) {
+		i := strings.Index(lower, word)
+		if i < 0 || len(lower) != len(text) {
+			continue
+		}
+		start, end := max(i-radius, 0), min(i+len(word)+radius, len(text))
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+		return strings.TrimSpace(html.EscapeString(text[start:i]) + "<mark>" + html.EscapeString(text[i:i+len(word)]) + "</mark>" + html.EscapeString(text[i+len(word):end]))
+	}
+
+	if len(text) > 2*radius {
+		end := 2 * radius
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+		return html.EscapeString(text[:end])
+	}
+	return html.EscapeString(text)
+}
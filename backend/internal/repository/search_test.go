@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{"match", "Staff must wear badges.", "badges", "Staff must wear <mark>badges</mark>."},
+		{"case insensitive", "Badges are required.", "badges", "<mark>Badges</mark> are required."},
+		{"escapes around match", `<img src=x onerror="alert(1)"> badges`, "badges", `&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>badges</mark>`},
+		{"escapes without match", "<script>alert(1)</script>", "badges", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snippet(tt.text, tt.query); got != tt.want {
+				t.Errorf("snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := highlight("a <b> " + markStart + "match" + markStop + " & c")
+	want := "a &lt;b&gt; <mark>match</mark> &amp; c"
+	if got != want {
+		t.Errorf("highlight() = %q, want %q", got, want)
+	}
+}
+
+func TestSearchScoping(t *testing.T) {
+	owner, stranger := uuid.New(), uuid.New()
+	ctx := WithOwner(WithWorkspace(context.Background(), DefaultWorkspaceID), owner)
+	repo := newTestRepository(t)
+	policy := createTestPolicy(t, ctx, repo, "Staff must wear badges.")
+	document := &Document{
+		BaseModel:  BaseModel{ID: uuid.New()},
+		Title:      "Staff Handbook",
+		Path:       "handbook.pdf",
+		Extension:  ".pdf",
+		PolicyID:   policy.ID,
+		Extraction: Extraction{ExtractedText: "Staff wear badges at all times."},
+	}
+	if err := repo.CreateDocument(ctx, document); err != nil {
+		t.Fatalf("createDocument: %v", err)
+	}
+
+	other := &Workspace{BaseModel: BaseModel{ID: uuid.New()}, Name: "Other"}
+	if err := repo.CreateWorkspace(ctx, other); err != nil {
+		t.Fatalf("createWorkspace: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		rules     int
+		documents int
+	}{
+		{"own workspace", ctx, 1, 1},
+		{"other workspace", WithWorkspace(ctx, other.ID), 0, 0},
+		{"other workspace, same owner", OwnedOnly(WithWorkspace(ctx, other.ID)), 0, 0},
+		{"owned only", OwnedOnly(ctx), 1, 1},
+		{"owned by another caller", OwnedOnly(WithOwner(ctx, stranger)), 1, 0},
+		{"owned only without an owner", OwnedOnly(WithWorkspace(context.Background(), DefaultWorkspaceID)), 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := SearchQuery{Text: "badges", Limit: 10}
+			rules, err := repo.SearchRules(tt.ctx, q)
+			if err != nil {
+				t.Fatalf("searchRules: %v", err)
+			}
+			if len(rules) != tt.rules {
+				t.Errorf("searchRules = %d hits, want %d", len(rules), tt.rules)
+			}
+			documents, err := repo.SearchDocuments(tt.ctx, q)
+			if err != nil {
+				t.Fatalf("searchDocuments: %v", err)
+			}
+			if len(documents) != tt.documents {
+				t.Errorf("searchDocuments = %d hits, want %d", len(documents), tt.documents)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqlitePragmas are applied to every connection. WAL and a busy timeout
+// let the worker pool and the API write to the same file.
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+
+// openSQLite opens a database file using a pure-Go driver, so no cgo or
+// server is needed. path may be ":memory:" for throwaway databases.
+func openSQLite(path string) (*gorm.DB, error) {
+	dsn := path
+	if path != ":memory:" && !strings.HasPrefix(path, "file:") {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + sqlitePragmas
+	} else {
+		dsn += "?" + sqlitePragmas
+	}
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	// A single connection keeps ":memory:" databases shared and avoids
+	// SQLITE_BUSY when two transactions upgrade to writers.
+	sqlDB.SetMaxOpenConns(1)
+	return db, nil
+}
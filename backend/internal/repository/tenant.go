@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"policy-match/internal/dto"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultWorkspaceID (00000000-0000-0000-0000-000000000001) holds the rows
+// that existed before workspaces, and rows created outside any workspace.
+var DefaultWorkspaceID = uuid.UUID{15: 1}
+
+// Tenant marks a model as owned by a workspace. Queries on such models are
+// scoped to the workspace in the context, and created rows are stamped
+// with it, by the callbacks registered in registerTenantCallbacks.
+type Tenant struct {
+	WorkspaceID uuid.UUID `gorm:"not null;type:uuid;index"`
+}
+
+// Owner marks a model as owned by the caller that created it, an API key
+// or a user. Rows created before ownership was tracked have no owner.
+type Owner struct {
+	OwnerID *uuid.UUID `gorm:"type:uuid;index"`
+}
+
+// WithWorkspace returns a context whose queries only see workspaceID.
+func WithWorkspace(ctx context.Context, workspaceID uuid.UUID) context.Context {
+	return context.WithValue(ctx, dto.WorkspaceIDContext, workspaceID)
+}
+
+// WorkspaceFromContext returns the workspace queries are scoped to. The
+// worker and CLI run without one and see every workspace.
+func WorkspaceFromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(dto.WorkspaceIDContext).(uuid.UUID)
+	return id, ok
+}
+
+// WithOwner returns a context whose created rows are owned by ownerID.
+func WithOwner(ctx context.Context, ownerID uuid.UUID) context.Context {
+	return context.WithValue(ctx, dto.OwnerIDContext, ownerID)
+}
+
+// OwnedOnly returns a context whose queries only see rows owned by the
+// context's owner, which must be set with WithOwner.
+func OwnedOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dto.OwnedOnlyContext, true)
+}
+
+func ownerFromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(dto.OwnerIDContext).(uuid.UUID)
+	return id, ok
+}
+
+func ownedOnly(ctx context.Context) bool {
+	only, _ := ctx.Value(dto.OwnedOnlyContext).(bool)
+	return only
+}
+
+func registerTenantCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("tenant:create", stampTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register("tenant:query", scopeTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tenant:update", scopeTenant); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", scopeTenant)
+}
+
+func scopeTenant(db *gorm.DB) {
+	ctx, schema := db.Statement.Context, db.Statement.Schema
+	if schema == nil {
+		return
+	}
+	if id, ok := WorkspaceFromContext(ctx); ok && schema.LookUpField("WorkspaceID") != nil {
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: db.Statement.Table, Name: "workspace_id"}, Value: id},
+		}})
+	}
+	if ownedOnly(ctx) && schema.LookUpField("OwnerID") != nil {
+		// Without an owner in ctx this matches nothing, which is the safe
+		// outcome for a caller restricted to its own rows.
+		id, _ := ownerFromContext(ctx)
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: db.Statement.Table, Name: "owner_id"}, Value: id},
+		}})
+	}
+}
+
+func stampTenant(db *gorm.DB) {
+	ctx, schema := db.Statement.Context, db.Statement.Schema
+	if schema == nil {
+		return
+	}
+	if schema.LookUpField("WorkspaceID") != nil {
+		id, ok := WorkspaceFromContext(ctx)
+		if !ok {
+			id = DefaultWorkspaceID
+		}
+		db.Statement.SetColumn("WorkspaceID", id, true)
+	}
+	if id, ok := ownerFromContext(ctx); ok && schema.LookUpField("OwnerID") != nil {
+		db.Statement.SetColumn("OwnerID", &id, true)
+	}
+}
+
+// inWorkspace scopes a query gorm cannot scope from a model, such as one
+// built with Table, to the workspace in ctx.
+func inWorkspace(ctx context.Context, table string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if id, ok := WorkspaceFromContext(ctx); ok {
+			return db.Where(table+".workspace_id = ?", id)
+		}
+		return db
+	}
+}
+
+// ownedBy is inWorkspace for Owner models.
+func ownedBy(ctx context.Context, table string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !ownedOnly(ctx) {
+			return db
+		}
+		id, _ := ownerFromContext(ctx)
+		return db.Where(table+".owner_id = ?", id)
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// JSON list columns are stored as jsonb on Postgres and as text on SQLite.
+// They implement driver.Valuer so they also work in map updates, which
+// skip gorm serializers.
+
+type StringList []string
+
+type UUIDList []uuid.UUID
+
+type RuleSnapshots []RuleSnapshot
+
+func (StringList) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDataType(db)
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	return jsonValue(l)
+}
+
+func (l *StringList) Scan(src any) error {
+	return jsonScan(l, src)
+}
+
+func (UUIDList) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDataType(db)
+}
+
+func (l UUIDList) Value() (driver.Value, error) {
+	return jsonValue(l)
+}
+
+func (l *UUIDList) Scan(src any) error {
+	return jsonScan(l, src)
+}
+
+func (RuleSnapshots) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDataType(db)
+}
+
+func (l RuleSnapshots) Value() (driver.Value, error) {
+	return jsonValue(l)
+}
+
+func (l *RuleSnapshots) Scan(src any) error {
+	return jsonScan(l, src)
+}
+
+func jsonDataType(db *gorm.DB) string {
+	if db.Dialector.Name() == DriverPostgres {
+		return "jsonb"
+	}
+	return "text"
+}
+
+func jsonValue(v any) (driver.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func jsonScan(dst any, src any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", src, dst)
+	}
+}
+
+// Vector is an embedding stored as little-endian float32s in a bytea (blob
+// on SQLite) column.
+type Vector []float32
+
+func (Vector) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == DriverPostgres {
+		return "bytea"
+	}
+	return "blob"
+}
+
+func (v Vector) Value() (driver.Value, error) {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b, nil
+}
+
+func (v *Vector) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into %T", src, v)
+	}
+	if len(b)%4 != 0 {
+		return fmt.Errorf("cannot scan %d bytes into %T", len(b), v)
+	}
+	out := make(Vector, len(b)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	*v = out
+	return nil
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"policy-match/internal/apperr"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *gormRepository) CreateWorkspace(ctx context.Context, workspace *Workspace) error {
+	return r.db.
+		WithContext(ctx).
+		Create(workspace).
+		Error
+}
+
+// GetWorkspaces returns the caller's workspace, or every workspace when
+// ctx is not scoped to one.
+func (r *gormRepository) GetWorkspaces(ctx context.Context) ([]Workspace, error) {
+	var workspaces []Workspace
+
+	query := r.db.
+		WithContext(ctx).
+		Order("created_at")
+	if id, ok := WorkspaceFromContext(ctx); ok {
+		query = query.Where("id = ?", id)
+	}
+	if err := query.Find(&workspaces).Error; err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+func (r *gormRepository) GetWorkspaceByID(ctx context.Context, id uuid.UUID) (*Workspace, error) {
+	var workspace Workspace
+
+	err := r.db.
+		WithContext(ctx).
+		First(&workspace, "id = ?", id).
+		Error
+	if err != nil {
+		return nil, notFound(err, "workspace_not_found")
+	}
+	return &workspace, nil
+}
+
+func (r *gormRepository) UpdateWorkspace(ctx context.Context, id uuid.UUID, updates map[string]any) error {
+	return r.db.
+		WithContext(ctx).
+		Model(&Workspace{}).
+		Where("id = ?", id).
+		Updates(updates).
+		Error
+}
+
+// GetOrCreateUser finds a user by email, ignoring case, or creates one.
+// An existing user's name is only filled in if it was empty.
+func (r *gormRepository) GetOrCreateUser(ctx context.Context, email string, name string) (*User, error) {
+	user := User{
+		BaseModel: BaseModel{
+			ID: uuid.New(),
+		},
+		Email: strings.ToLower(strings.TrimSpace(email)),
+		Name:  name,
+	}
+
+	err := r.db.
+		WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&user).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	var stored User
+	err = r.db.
+		WithContext(ctx).
+		First(&stored, "email = ?", user.Email).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if stored.Name == "" && name != "" {
+		stored.Name = name
+		if err := r.db.WithContext(ctx).Model(&stored).Update("name", name).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &stored, nil
+}
+
+func (r *gormRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	var user User
+
+	err := r.db.
+		WithContext(ctx).
+		First(&user, "email = ?", strings.ToLower(strings.TrimSpace(email))).
+		Error
+	if err != nil {
+		return nil, notFound(err, "user_not_found")
+	}
+	return &user, nil
+}
+
+// AddWorkspaceMember updates the role if the user is already a member.
+// member is reloaded so it reflects the stored row.
+func (r *gormRepository) AddWorkspaceMember(ctx context.Context, member *WorkspaceMember) error {
+	err := r.db.
+		WithContext(ctx).
+		Omit("User").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "workspace_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+		}).
+		Create(member).
+		Error
+	if err != nil {
+		return err
+	}
+
+	stored, err := r.GetWorkspaceMember(ctx, member.WorkspaceID, member.UserID)
+	if err != nil {
+		return err
+	}
+	*member = *stored
+	return nil
+}
+
+func (r *gormRepository) GetWorkspaceMembers(ctx context.Context, workspaceID uuid.UUID) ([]WorkspaceMember, error) {
+	var members []WorkspaceMember
+
+	err := r.db.
+		WithContext(ctx).
+		Preload("User").
+		Where("workspace_id = ?", workspaceID).
+		Order("created_at").
+		Find(&members).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *gormRepository) GetWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) (*WorkspaceMember, error) {
+	var member WorkspaceMember
+
+	err := r.db.
+		WithContext(ctx).
+		Preload("User").
+		Where("workspace_id = ?", workspaceID).
+		Where("user_id = ?", userID).
+		First(&member).
+		Error
+	if err != nil {
+		return nil, notFound(err, "workspace_member_not_found")
+	}
+	return &member, nil
+}
+
+// RemoveWorkspaceMember deletes the membership outright, so the user can
+// be added again later.
+func (r *gormRepository) RemoveWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) error {
+	res := r.db.
+		WithContext(ctx).
+		Unscoped().
+		Where("workspace_id = ?", workspaceID).
+		Where("user_id = ?", userID).
+		Delete(&WorkspaceMember{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apperr.NotFound("workspace_member_not_found", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"policy-match/internal/dto"
+	"policy-match/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every service key, which tells them apart from
+// caller-supplied LLM keys and bearer tokens.
+const APIKeyPrefix = "pm_"
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid or revoked api key")
+	ErrQuotaExceeded = errors.New("api key quota exceeded")
+)
+
+// CreateAPIKey stores a new key and returns it with its plaintext, which
+// is not kept and cannot be shown again.
+func (s *Service) CreateAPIKey(ctx context.Context, name string, role string, requestQuota int, tokenQuota int64) (*repository.APIKey, string, error) {
+	key, plaintext, err := NewAPIKey(name, role, requestQuota, tokenQuota)
+	if err != nil {
+		return nil, "", fmt.Errorf("createAPIKey :: %w", err)
+	}
+	if err := s.repository.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("createAPIKey :: %w", err)
+	}
+	return key, plaintext, nil
+}
+
+// NewAPIKey generates a key without storing it. The apikey subcommand uses
+// it to create the first key, before any key exists to call the API with.
+func NewAPIKey(name string, role string, requestQuota int, tokenQuota int64) (*repository.APIKey, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("newAPIKey :: generate: %w", err)
+	}
+	plaintext := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	key := &repository.APIKey{
+		BaseModel: repository.BaseModel{
+			ID: uuid.New(),
+		},
+		Name:         name,
+		Prefix:       plaintext[:len(APIKeyPrefix)+8],
+		KeyHash:      hashAPIKey(plaintext),
+		Role:         role,
+		RequestQuota: requestQuota,
+		TokenQuota:   tokenQuota,
+		UsageDay:     time.Now().UTC().Truncate(24 * time.Hour),
+	}
+	return key, plaintext, nil
+}
+
+func (s *Service) GetAPIKeys(ctx context.Context) ([]repository.APIKey, error) {
+	keys, err := s.repository.GetAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getAPIKeys :: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	if err := s.repository.RevokeAPIKey(ctx, id, time.Now().UTC()); err != nil {
+		return fmt.Errorf("revokeAPIKey :: %w", err)
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves a plaintext key and counts the request
+// against its quota.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, plaintext string) (*repository.APIKey, error) {
+	if !strings.HasPrefix(plaintext, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repository.GetAPIKeyByHash(ctx, hashAPIKey(plaintext))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("authenticateAPIKey :: getAPIKeyByHash: %w", err)
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	ok, err := s.repository.UseAPIKey(ctx, key.ID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("authenticateAPIKey :: useAPIKey: %w", err)
+	}
+	if !ok {
+		return nil, ErrQuotaExceeded
+	}
+	return key, nil
+}
+
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyID returns the service key the request was made with, if any, so
+// queued jobs keep charging it.
+func apiKeyID(ctx context.Context) *uuid.UUID {
+	if id, ok := ctx.Value(dto.APIKeyIDContext).(uuid.UUID); ok {
+		return &id
+	}
+	return nil
+}
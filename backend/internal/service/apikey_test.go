@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"policy-match/internal/apperr"
+	"policy-match/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	key, plaintext, err := NewAPIKey("ci", repository.RoleSubmitter, 10, 1000)
+	if err != nil {
+		t.Fatalf("newAPIKey: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, APIKeyPrefix) || len(plaintext) != len(APIKeyPrefix)+43 {
+		t.Errorf("plaintext = %q, want %s and 32 random bytes", plaintext, APIKeyPrefix)
+	}
+	if key.Prefix != plaintext[:len(APIKeyPrefix)+8] {
+		t.Errorf("prefix = %q, want the first 8 characters after %s", key.Prefix, APIKeyPrefix)
+	}
+	if key.KeyHash != hashAPIKey(plaintext) || strings.Contains(key.KeyHash, plaintext[len(APIKeyPrefix):]) {
+		t.Errorf("hash = %q, want the SHA-256 of the key and not the key", key.KeyHash)
+	}
+	if len(key.KeyHash) != 64 {
+		t.Errorf("hash has %d characters, want 64 hex digits", len(key.KeyHash))
+	}
+	if key.Role != repository.RoleSubmitter || key.RequestQuota != 10 || key.TokenQuota != 1000 {
+		t.Errorf("key = %+v, want the given role and quotas", key)
+	}
+
+	_, other, err := NewAPIKey("ci", repository.RoleSubmitter, 0, 0)
+	if err != nil {
+		t.Fatalf("newAPIKey: %v", err)
+	}
+	if other == plaintext {
+		t.Error("two keys are equal")
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	s := &Service{repository: newTestRepository(t)}
+	key, plaintext, err := s.CreateAPIKey(ctx, "ci", repository.RoleReviewer, 0, 0)
+	if err != nil {
+		t.Fatalf("createAPIKey: %v", err)
+	}
+
+	got, err := s.AuthenticateAPIKey(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("authenticateAPIKey: %v", err)
+	}
+	if got.ID != key.ID || got.Role != repository.RoleReviewer || got.WorkspaceID != repository.DefaultWorkspaceID {
+		t.Errorf("key = %+v, want %s", got, key.ID)
+	}
+
+	for _, token := range []string{
+		"",
+		strings.TrimPrefix(plaintext, APIKeyPrefix),
+		plaintext + "x",
+		key.KeyHash,
+		APIKeyPrefix + "unknown",
+	} {
+		if _, err := s.AuthenticateAPIKey(ctx, token); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("authenticateAPIKey(%q): err = %v, want ErrInvalidAPIKey", token, err)
+		}
+	}
+
+	keys, err := s.GetAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("getAPIKeys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].RequestCount != 1 || keys[0].LastUsedAt == nil {
+		t.Errorf("keys = %+v, want one key used once", keys)
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	ctx := context.Background()
+	s := &Service{repository: newTestRepository(t)}
+	key, plaintext, err := s.CreateAPIKey(ctx, "ci", repository.RoleAdmin, 0, 0)
+	if err != nil {
+		t.Fatalf("createAPIKey: %v", err)
+	}
+
+	if err := s.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("revokeAPIKey: %v", err)
+	}
+	if _, err := s.AuthenticateAPIKey(ctx, plaintext); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("revoked key: err = %v, want ErrInvalidAPIKey", err)
+	}
+	ok, err := s.repository.UseAPIKey(ctx, key.ID, time.Now().Add(48*time.Hour))
+	if err != nil || ok {
+		t.Errorf("useAPIKey on a revoked key = %v, %v, want false", ok, err)
+	}
+
+	keys, err := s.GetAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("getAPIKeys: %v", err)
+	}
+	revokedAt := keys[0].RevokedAt
+	if revokedAt == nil {
+		t.Fatal("key not marked revoked")
+	}
+
+	// Revoking again keeps the first time.
+	time.Sleep(10 * time.Millisecond)
+	if err := s.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("revokeAPIKey again: %v", err)
+	}
+	keys, err = s.GetAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("getAPIKeys: %v", err)
+	}
+	if !keys[0].RevokedAt.Equal(*revokedAt) {
+		t.Errorf("revoked at = %v, want the first revocation %v", keys[0].RevokedAt, revokedAt)
+	}
+
+	err = s.RevokeAPIKey(ctx, uuid.New())
+	if appErr, ok := apperr.As(err); !ok || appErr.Kind != apperr.KindNotFound {
+		t.Errorf("revoke unknown key: err = %v, want not found", err)
+	}
+}
+
+func TestAPIKeyRequestQuota(t *testing.T) {
+	ctx := context.Background()
+	s := &Service{repository: newTestRepository(t)}
+	key, plaintext, err := s.CreateAPIKey(ctx, "ci", repository.RoleAdmin, 2, 0)
+	if err != nil {
+		t.Fatalf("createAPIKey: %v", err)
+	}
+
+	for i := range 2 {
+		if _, err := s.AuthenticateAPIKey(ctx, plaintext); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	for range 2 {
+		if _, err := s.AuthenticateAPIKey(ctx, plaintext); !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("request over quota: err = %v, want ErrQuotaExceeded", err)
+		}
+	}
+	if n := requestCount(t, s); n != 2 {
+		t.Errorf("request count = %d, want 2; refused requests must not count", n)
+	}
+
+	// The quota is per UTC day; the first request of the next day starts
+	// a new count.
+	tomorrow := time.Now().UTC().Add(24 * time.Hour)
+	ok, err := s.repository.UseAPIKey(ctx, key.ID, tomorrow)
+	if err != nil || !ok {
+		t.Fatalf("useAPIKey the next day = %v, %v, want true", ok, err)
+	}
+	if n := requestCount(t, s); n != 1 {
+		t.Errorf("request count after the day changed = %d, want 1", n)
+	}
+	ok, err = s.repository.UseAPIKey(ctx, key.ID, tomorrow.Add(time.Minute))
+	if err != nil || !ok {
+		t.Fatalf("second request the next day = %v, %v, want true", ok, err)
+	}
+	ok, err = s.repository.UseAPIKey(ctx, key.ID, tomorrow.Add(2*time.Minute))
+	if err != nil || ok {
+		t.Errorf("third request the next day = %v, %v, want false", ok, err)
+	}
+}
+
+func TestAPIKeyTokenQuota(t *testing.T) {
+	ctx := context.Background()
+	s := &Service{repository: newTestRepository(t)}
+	key, plaintext, err := s.CreateAPIKey(ctx, "ci", repository.RoleAdmin, 0, 100)
+	if err != nil {
+		t.Fatalf("createAPIKey: %v", err)
+	}
+
+	if err := s.repository.AddAPIKeyTokens(ctx, key.ID, 99); err != nil {
+		t.Fatalf("addAPIKeyTokens: %v", err)
+	}
+	if _, err := s.AuthenticateAPIKey(ctx, plaintext); err != nil {
+		t.Fatalf("under the token quota: %v", err)
+	}
+
+	// A request may overshoot the token quota; the next one is refused.
+	if err := s.repository.AddAPIKeyTokens(ctx, key.ID, 50); err != nil {
+		t.Fatalf("addAPIKeyTokens: %v", err)
+	}
+	if _, err := s.AuthenticateAPIKey(ctx, plaintext); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("over the token quota: err = %v, want ErrQuotaExceeded", err)
+	}
+
+	ok, err := s.repository.UseAPIKey(ctx, key.ID, time.Now().UTC().Add(24*time.Hour))
+	if err != nil || !ok {
+		t.Fatalf("useAPIKey the next day = %v, %v, want true", ok, err)
+	}
+	keys, err := s.GetAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("getAPIKeys: %v", err)
+	}
+	if keys[0].TokenCount != 0 {
+		t.Errorf("token count after the day changed = %d, want 0", keys[0].TokenCount)
+	}
+}
+
+func requestCount(t *testing.T, s *Service) int {
+	t.Helper()
+	keys, err := s.GetAPIKeys(context.Background())
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("getAPIKeys = %d keys, %v", len(keys), err)
+	}
+	return keys[0].RequestCount
+}
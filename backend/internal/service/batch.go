@@ -0,0 +1,222 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"policy-match/internal/apperr"
+	"policy-match/internal/blob"
+	"policy-match/internal/dto"
+	"policy-match/internal/repository"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrNoPolicies    = apperr.InvalidInput("policy_id_is_required", errors.New("no policies selected"))
+	ErrEmptyBatch    = apperr.InvalidInput("batch_is_empty", errors.New("batch contains no files"))
+	ErrBatchTooLarge = apperr.InvalidInput("batch_has_too_many_files", errors.New("batch exceeds the file limit"))
+	ErrFileTooLarge  = apperr.InvalidInput("file_is_too_large", errors.New("file exceeds the size limit"))
+	ErrBatchTooBig   = apperr.InvalidInput("batch_is_too_large", errors.New("batch exceeds the size limit"))
+	ErrNestedArchive = apperr.InvalidInput("batch_has_nested_archive", errors.New("zip archive contains another zip archive"))
+)
+
+// batchFile is an uploaded file, or a zip entry, already in the blob store.
+type batchFile struct {
+	name string
+	obj  blob.Object
+}
+
+// SubmitBatch queues one job per file, each checked against every selected
+// policy. Zip archives are expanded so a folder can be uploaded as a single
+// file. Jobs are run by the shared worker pool, which bounds how many are
+// processed at once.
+func (s *Service) SubmitBatch(ctx context.Context, req dto.UploadBatchRequestDTO, policyIDs []uuid.UUID) (_ *repository.Batch, err error) {
+	policyIDs, err = s.resolvePolicyIDs(ctx, policyIDs, req.Category)
+	if err != nil {
+		return nil, fmt.Errorf("submitBatch :: %w", err)
+	}
+
+	var files []batchFile
+	defer func() {
+		if err != nil {
+			s.discardBlobs(ctx, files)
+		}
+	}()
+	budget := s.cfg.BatchMaxTotalBytes
+	for _, fh := range req.Files {
+		read, err := s.readBatchUpload(ctx, fh, s.cfg.BatchMaxFiles-len(files), &budget)
+		files = append(files, read...)
+		if err != nil {
+			return nil, fmt.Errorf("submitBatch :: %s: %w", fh.Filename, err)
+		}
+		if len(files) > s.cfg.BatchMaxFiles {
+			return nil, fmt.Errorf("submitBatch :: %w (%d)", ErrBatchTooLarge, s.cfg.BatchMaxFiles)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("submitBatch :: %w", ErrEmptyBatch)
+	}
+
+	batch := &repository.Batch{
+		BaseModel: repository.BaseModel{
+			ID: uuid.New(),
+		},
+		PolicyIDs: policyIDs,
+		FileCount: len(files),
+	}
+
+	jobs := make([]repository.Job, len(files))
+	for i, f := range files {
+		jobs[i] = repository.Job{
+			BaseModel: repository.BaseModel{
+				ID: uuid.New(),
+			},
+			Kind:      repository.JobKindCheck,
+			Status:    repository.JobStatusQueued,
+			PolicyIDs: policyIDs,
+			FileName:  f.name,
+			BlobKey:   f.obj.Key,
+			Size:      f.obj.Size,
+			BatchID:   &batch.ID,
+			APIKeyID:  apiKeyID(ctx),
+		}
+	}
+
+	if err := s.repository.CreateBatch(ctx, batch, jobs); err != nil {
+		return nil, fmt.Errorf("submitBatch :: createBatch: %w", err)
+	}
+
+	s.queued(ctx, jobs...)
+
+	batch.Jobs = jobs
+	return batch, nil
+}
+
+func (s *Service) GetBatch(ctx context.Context, id uuid.UUID) (*repository.Batch, error) {
+	batch, err := s.repository.GetBatchByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getBatch :: getBatchByID: %w", err)
+	}
+	return batch, nil
+}
+
+// discardBlobs deletes the blobs stored for a batch that failed before its
+// jobs were saved. A blob is shared by every identical upload, so one that
+// a stored row already points to is kept.
+func (s *Service) discardBlobs(ctx context.Context, files []batchFile) {
+	ctx = context.WithoutCancel(ctx)
+	for _, f := range files {
+		used, err := s.repository.BlobInUse(ctx, f.obj.Key)
+		if err == nil && !used {
+			err = s.blobs.Delete(ctx, f.obj.Key)
+		}
+		if err != nil && !errors.Is(err, blob.ErrNotFound) {
+			log.Warn().Err(err).Str("blob_key", f.obj.Key).Msg("submitBatch :: discardBlob")
+		}
+	}
+}
+
+// readBatchUpload streams an upload, or each file in a zip upload, into the
+// blob store. At most maxFiles files are read, and budget is reduced by
+// every byte stored. On error it still returns the files already stored,
+// so the caller can discard them.
+func (s *Service) readBatchUpload(ctx context.Context, fh *multipart.FileHeader, maxFiles int, budget *int64) ([]batchFile, error) {
+	zipped := isZip(fh.Filename)
+	if zipped && fh.Size > s.cfg.BatchMaxZipBytes {
+		return nil, ErrFileTooLarge
+	}
+	if !zipped && fh.Size > s.cfg.BatchMaxFileBytes {
+		return nil, ErrFileTooLarge
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	if zipped {
+		return s.readZip(ctx, f, fh.Size, maxFiles, budget)
+	}
+	obj, err := s.putCapped(ctx, f, budget)
+	if err != nil {
+		return nil, fmt.Errorf("putBlob: %w", err)
+	}
+	return []batchFile{{name: fh.Filename, obj: obj}}, nil
+}
+
+func (s *Service) readZip(ctx context.Context, r io.ReaderAt, size int64, maxFiles int, budget *int64) ([]batchFile, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("open zip: %w", err)
+	}
+
+	var files []batchFile
+	for _, entry := range zr.File {
+		name := path.Base(entry.Name)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(entry.Name, "__MACOSX/") {
+			continue
+		}
+		if isZip(name) {
+			return files, fmt.Errorf("%w: %s", ErrNestedArchive, entry.Name)
+		}
+		if len(files) >= maxFiles {
+			return files, ErrBatchTooLarge
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return files, fmt.Errorf("open zip entry %s: %w", entry.Name, err)
+		}
+		obj, err := s.putCapped(ctx, rc, budget)
+		rc.Close()
+		if err != nil {
+			return files, fmt.Errorf("read zip entry %s: %w", entry.Name, err)
+		}
+		files = append(files, batchFile{name: name, obj: obj})
+	}
+	return files, nil
+}
+
+// putCapped stores r, failing as soon as it exceeds the per-file limit or
+// the remaining budget, so the declared sizes in a zip need not be
+// trusted.
+func (s *Service) putCapped(ctx context.Context, r io.Reader, budget *int64) (blob.Object, error) {
+	r = &cappedReader{r: r, left: s.cfg.BatchMaxFileBytes, err: ErrFileTooLarge}
+	r = &cappedReader{r: r, left: *budget, err: ErrBatchTooBig}
+	obj, err := s.blobs.Put(ctx, r)
+	if err != nil {
+		return blob.Object{}, err
+	}
+	*budget -= obj.Size
+	return obj, nil
+}
+
+// cappedReader fails with err once more than left bytes have been read. An
+// error from r other than io.EOF wins, so a per-file cap wrapped in the
+// batch budget is still reported as such.
+type cappedReader struct {
+	r    io.Reader
+	left int64
+	err  error
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.left -= int64(n)
+	if c.left < 0 && (err == nil || err == io.EOF) {
+		return n, c.err
+	}
+	return n, err
+}
+
+func isZip(filename string) bool {
+	return strings.EqualFold(path.Ext(filename), ".zip")
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"policy-match/internal/blob"
+	"policy-match/internal/config"
+	"policy-match/internal/dto"
+	"policy-match/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type uploadFile struct {
+	name    string
+	content []byte
+}
+
+// fileHeaders builds the multipart file headers a handler would bind.
+func fileHeaders(t *testing.T, files ...uploadFile) []*multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, f := range files {
+		part, err := w.CreateFormFile("files", f.name)
+		if err != nil {
+			t.Fatalf("createFormFile: %v", err)
+		}
+		part.Write(f.content)
+	}
+	w.Close()
+
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("readForm: %v", err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["files"]
+}
+
+func zipFile(t *testing.T, name string, entries ...uploadFile) uploadFile {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatalf("create zip entry: %v", err)
+		}
+		w.Write(e.content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return uploadFile{name: name, content: buf.Bytes()}
+}
+
+func newBatchTestService(t *testing.T) (*Service, uuid.UUID, string) {
+	t.Helper()
+	repo := newTestRepository(t)
+	dir := t.TempDir()
+	blobs, err := blob.NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("newLocalStore: %v", err)
+	}
+	policy := &repository.Policy{BaseModel: repository.BaseModel{ID: uuid.New()}, Title: "Security", Category: "security", Path: "security.pdf", Extension: ".pdf"}
+	if err := repo.CreatePolicy(context.Background(), policy); err != nil {
+		t.Fatalf("createPolicy: %v", err)
+	}
+	s := &Service{
+		cfg: &config.Config{
+			BatchMaxFiles:      3,
+			BatchMaxFileBytes:  100,
+			BatchMaxZipBytes:   1 << 20,
+			BatchMaxTotalBytes: 250,
+			JobAPIKeyTTL:       time.Hour,
+		},
+		repository: repo,
+		blobs:      blobs,
+		jobs:       nopQueue{},
+	}
+	return s, policy.ID, dir
+}
+
+// storedBlobs counts the blobs left in a local store.
+func storedBlobs(t *testing.T, dir string) int {
+	t.Helper()
+	n := 0
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+			n++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("walk blobs: %v", err)
+	}
+	return n
+}
+
+func TestSubmitBatch(t *testing.T) {
+	s, policyID, dir := newBatchTestService(t)
+	req := dto.UploadBatchRequestDTO{Files: fileHeaders(t,
+		uploadFile{"a.pdf", []byte("first")},
+		zipFile(t, "folder.zip",
+			uploadFile{"docs/../b.pdf", []byte("second")},
+			uploadFile{"docs/nested/c.pdf", []byte("third")},
+			uploadFile{"__MACOSX/._c.pdf", []byte("resource fork")},
+			uploadFile{".hidden", []byte("skipped")},
+		),
+	)}
+
+	batch, err := s.SubmitBatch(context.Background(), req, []uuid.UUID{policyID})
+	if err != nil {
+		t.Fatalf("submitBatch: %v", err)
+	}
+	var names []string
+	for _, job := range batch.Jobs {
+		names = append(names, job.FileName)
+	}
+	if strings.Join(names, ",") != "a.pdf,b.pdf,c.pdf" || batch.FileCount != 3 {
+		t.Errorf("jobs = %v, want a file per document with entry paths dropped", names)
+	}
+	if n := storedBlobs(t, dir); n != 3 {
+		t.Errorf("stored %d blobs, want 3", n)
+	}
+}
+
+func TestSubmitBatchLimits(t *testing.T) {
+	big := bytes.Repeat([]byte("x"), 101)
+	tests := []struct {
+		name  string
+		files func(t *testing.T) []uploadFile
+		want  error
+	}{
+		{
+			name:  "file over the per-file cap",
+			files: func(t *testing.T) []uploadFile { return []uploadFile{{"a.pdf", []byte("ok")}, {"big.pdf", big}} },
+			want:  ErrFileTooLarge,
+		},
+		{
+			// The entry is far over the cap once inflated, whatever size
+			// the archive declares for it.
+			name: "zip bomb entry",
+			files: func(t *testing.T) []uploadFile {
+				return []uploadFile{zipFile(t, "bomb.zip", uploadFile{"a.pdf", []byte("ok")}, uploadFile{"bomb.pdf", bytes.Repeat([]byte{0}, 1<<20)})}
+			},
+			want: ErrFileTooLarge,
+		},
+		{
+			name: "total over the batch cap",
+			files: func(t *testing.T) []uploadFile {
+				chunk := bytes.Repeat([]byte("y"), 90)
+				return []uploadFile{zipFile(t, "all.zip", uploadFile{"a.pdf", chunk}, uploadFile{"b.pdf", append(chunk, 'b')}, uploadFile{"c.pdf", append(chunk, 'c')})}
+			},
+			want: ErrBatchTooBig,
+		},
+		{
+			name: "too many files",
+			files: func(t *testing.T) []uploadFile {
+				return []uploadFile{{"a.pdf", []byte("a")}, zipFile(t, "more.zip", uploadFile{"b.pdf", []byte("b")}, uploadFile{"c.pdf", []byte("c")}, uploadFile{"d.pdf", []byte("d")})}
+			},
+			want: ErrBatchTooLarge,
+		},
+		{
+			name: "nested archive",
+			files: func(t *testing.T) []uploadFile {
+				inner := zipFile(t, "inner.zip", uploadFile{"b.pdf", []byte("b")})
+				return []uploadFile{zipFile(t, "outer.zip", uploadFile{"a.pdf", []byte("a")}, uploadFile{"sub/inner.zip", inner.content})}
+			},
+			want: ErrNestedArchive,
+		},
+		{
+			name:  "empty",
+			files: func(t *testing.T) []uploadFile { return []uploadFile{zipFile(t, "empty.zip")} },
+			want:  ErrEmptyBatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, policyID, dir := newBatchTestService(t)
+			req := dto.UploadBatchRequestDTO{Files: fileHeaders(t, tt.files(t)...)}
+			_, err := s.SubmitBatch(context.Background(), req, []uuid.UUID{policyID})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			// Files stored before the failure are removed again.
+			if n := storedBlobs(t, dir); n != 0 {
+				t.Errorf("%d blobs left behind", n)
+			}
+		})
+	}
+}
+
+func TestSubmitBatchKeepsSharedBlobs(t *testing.T) {
+	s, policyID, dir := newBatchTestService(t)
+	ctx := context.Background()
+	if _, err := s.SubmitBatch(ctx, dto.UploadBatchRequestDTO{Files: fileHeaders(t, uploadFile{"a.pdf", []byte("shared")})}, []uuid.UUID{policyID}); err != nil {
+		t.Fatalf("submitBatch: %v", err)
+	}
+
+	// The failed batch stored the same content, which an earlier job still
+	// points to.
+	req := dto.UploadBatchRequestDTO{Files: fileHeaders(t, uploadFile{"a.pdf", []byte("shared")}, uploadFile{"b.pdf", []byte("own")}, uploadFile{"big.pdf", bytes.Repeat([]byte("x"), 101)})}
+	if _, err := s.SubmitBatch(ctx, req, []uuid.UUID{policyID}); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("err = %v, want ErrFileTooLarge", err)
+	}
+	if n := storedBlobs(t, dir); n != 1 {
+		t.Errorf("%d blobs left, want only the shared one", n)
+	}
+}
+
+func TestCappedReader(t *testing.T) {
+	errCap := errors.New("over the cap")
+	tests := []struct {
+		name    string
+		input   string
+		left    int64
+		wantErr bool
+	}{
+		{"under", "abc", 4, false},
+		{"exactly at the cap", "abcd", 4, false},
+		{"over", "abcde", 4, true},
+		{"zero cap", "a", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := io.ReadAll(&cappedReader{r: strings.NewReader(tt.input), left: tt.left, err: errCap})
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, errCap)) {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The per-file cap inside the batch budget reports its own error.
+	inner := &cappedReader{r: strings.NewReader("abcdef"), left: 4, err: ErrFileTooLarge}
+	if _, err := io.ReadAll(&cappedReader{r: inner, left: 2, err: ErrBatchTooBig}); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("nested caps err = %v, want the inner cap's error", err)
+	}
+}
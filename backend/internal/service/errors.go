@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"policy-match/internal/apperr"
+	"policy-match/internal/client/llm"
+
+	"github.com/google/go-tika/tika"
+)
+
+// llmError tags provider failures so callers can answer 429 or 503
+// instead of 500.
+func llmError(err error) error {
+	switch {
+	case errors.Is(err, llm.ErrRateLimited):
+		return apperr.RateLimited("llm_rate_limited", llm.RetryAfter(err), err)
+	case errors.Is(err, llm.ErrProviderUnavailable):
+		return apperr.UpstreamFailure("llm_provider_unavailable", err)
+	case errors.Is(err, llm.ErrInvalidOutput), errors.Is(err, llm.ErrInvalidResponse):
+		return apperr.UpstreamFailure("llm_output_invalid", err)
+	default:
+		return err
+	}
+}
+
+// extractError tells a file Tika cannot parse apart from Tika itself
+// failing.
+func extractError(err error) error {
+	var tikaErr tika.ClientError
+	if errors.As(err, &tikaErr) {
+		switch tikaErr.StatusCode {
+		case http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+			return apperr.InvalidInput("file_could_not_be_read", err)
+		}
+	}
+	return apperr.UpstreamFailure("text_extraction_unavailable", err)
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"policy-match/internal/apperr"
+	"policy-match/internal/blob"
+	"policy-match/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// OpenPolicyFile returns the policy and a reader for its original upload.
+// The caller must close the reader.
+func (s *Service) OpenPolicyFile(ctx context.Context, id uuid.UUID) (*repository.Policy, io.ReadCloser, error) {
+	policy, err := s.repository.GetPolicyByID(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openPolicyFile :: getPolicyByID: %w", err)
+	}
+
+	rc, err := s.blobs.Get(ctx, policy.BlobKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openPolicyFile :: getBlob: %w", fileError(err))
+	}
+	return policy, rc, nil
+}
+
+// OpenDocumentFile returns the document and a reader for its original
+// upload. The caller must close the reader.
+func (s *Service) OpenDocumentFile(ctx context.Context, id uuid.UUID) (*repository.Document, io.ReadCloser, error) {
+	document, err := s.repository.GetDocumentByID(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openDocumentFile :: getDocumentByID: %w", err)
+	}
+
+	rc, err := s.blobs.Get(ctx, document.BlobKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openDocumentFile :: getBlob: %w", fileError(err))
+	}
+	return document, rc, nil
+}
+
+// fileError reports a row whose blob is gone as a missing file.
+func fileError(err error) error {
+	if errors.Is(err, blob.ErrNotFound) {
+		return apperr.NotFound("file_not_found", err)
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"policy-match/internal/dto"
+	"policy-match/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubmitDocument persists the upload as a queued job and returns
+// immediately; a worker runs CheckDocumentCompliance in the background.
+func (s *Service) SubmitDocument(ctx context.Context, req dto.UploadDocumentRequestDTO, policyIDs []uuid.UUID) (*repository.Job, error) {
+	policyIDs, err := s.resolvePolicyIDs(ctx, policyIDs, req.Category)
+	if err != nil {
+		return nil, fmt.Errorf("submitDocument :: %w", err)
+	}
+
+	f, err := req.File.Open()
+	if err != nil {
+		return nil, fmt.Errorf("submitDocument :: open file: %w", err)
+	}
+	defer f.Close()
+
+	obj, err := s.blobs.Put(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("submitDocument :: putBlob: %w", err)
+	}
+
+	job := &repository.Job{
+		BaseModel: repository.BaseModel{
+			ID: uuid.New(),
+		},
+		Kind:      repository.JobKindCheck,
+		Status:    repository.JobStatusQueued,
+		PolicyIDs: policyIDs,
+		FileName:  req.File.Filename,
+		BlobKey:   obj.Key,
+		Size:      obj.Size,
+		APIKeyID:  apiKeyID(ctx),
+	}
+	if err := s.repository.CreateJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("submitDocument :: createJob: %w", err)
+	}
+
+	s.queued(ctx, *job)
+	return job, nil
+}
+
+// resolvePolicyIDs merges explicit policy IDs with every policy in category
+// and checks that they all exist.
+func (s *Service) resolvePolicyIDs(ctx context.Context, policyIDs []uuid.UUID, category string) ([]uuid.UUID, error) {
+	seen := map[uuid.UUID]bool{}
+	resolved := make([]uuid.UUID, 0, len(policyIDs))
+	for _, id := range policyIDs {
+		if seen[id] {
+			continue
+		}
+		if _, err := s.repository.GetPolicyByID(ctx, id); err != nil {
+			return nil, fmt.Errorf("resolvePolicyIDs :: getPolicyByID %s: %w", id, err)
+		}
+		seen[id] = true
+		resolved = append(resolved, id)
+	}
+
+	if category != "" {
+		policies, err := s.repository.GetPoliciesByCategory(ctx, category)
+		if err != nil {
+			return nil, fmt.Errorf("resolvePolicyIDs :: getPoliciesByCategory: %w", err)
+		}
+		for _, policy := range policies {
+			if !seen[policy.ID] {
+				seen[policy.ID] = true
+				resolved = append(resolved, policy.ID)
+			}
+		}
+	}
+
+	if len(resolved) == 0 {
+		return nil, ErrNoPolicies
+	}
+	return resolved, nil
+}
+
+func (s *Service) GetJob(ctx context.Context, id uuid.UUID) (*repository.Job, error) {
+	job, err := s.repository.GetJobByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getJob :: getJobByID: %w", err)
+	}
+	return job, nil
+}
+
+// ProcessJob implements worker.Processor.
+func (s *Service) ProcessJob(ctx context.Context, job *repository.Job) error {
+	ctx = repository.WithWorkspace(ctx, job.WorkspaceID)
+	if job.OwnerID != nil {
+		ctx = repository.WithOwner(ctx, *job.OwnerID)
+	}
+	if key, ok := s.apiKey(job.ID); ok {
+		ctx = context.WithValue(ctx, dto.UserAPIKeyContext, key)
+	}
+	if job.APIKeyID != nil {
+		ctx = context.WithValue(ctx, dto.APIKeyIDContext, *job.APIKeyID)
+	}
+
+	if job.Kind == repository.JobKindRecheck {
+		_, err := s.RecheckDocumentCompliance(ctx, job)
+		return err
+	}
+
+	document, err := s.CheckDocumentCompliance(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	err = s.repository.UpdateJob(ctx, job.ID, map[string]any{"document_id": document.ID})
+	if err != nil {
+		return fmt.Errorf("processJob :: updateJob: %w", err)
+	}
+	return nil
+}
+
+// jobAPIKey is a caller-supplied LLM key held for a queued job until the
+// job finishes for good or the key expires.
+type jobAPIKey struct {
+	key     string
+	expires time.Time
+}
+
+// apiKey returns the caller's key for a job while it has not expired. It is
+// kept across attempts, so a retried job still runs with it.
+func (s *Service) apiKey(id uuid.UUID) (string, bool) {
+	v, ok := s.apiKeys.Load(id)
+	if !ok {
+		return "", false
+	}
+	key := v.(jobAPIKey)
+	if time.Now().After(key.expires) {
+		s.apiKeys.Delete(id)
+		return "", false
+	}
+	return key.key, true
+}
+
+// ReleaseJob implements worker.Processor. It drops the caller's key once
+// the job is done or has failed for good.
+func (s *Service) ReleaseJob(id uuid.UUID) {
+	s.apiKeys.Delete(id)
+}
+
+// ExpireAPIKeys drops caller keys older than JobAPIKeyTTL until ctx is
+// cancelled. This catches jobs that are never released here: those that
+// finished on another replica or never ran.
+func (s *Service) ExpireAPIKeys(ctx context.Context) {
+	ticker := time.NewTicker(max(s.cfg.JobAPIKeyTTL/4, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.expireAPIKeys(now)
+		}
+	}
+}
+
+func (s *Service) expireAPIKeys(now time.Time) {
+	s.apiKeys.Range(func(id, v any) bool {
+		if now.After(v.(jobAPIKey).expires) {
+			s.apiKeys.Delete(id)
+		}
+		return true
+	})
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"policy-match/internal/config"
+	"policy-match/internal/dto"
+	"policy-match/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type nopQueue struct{}
+
+func (nopQueue) Notify() {}
+
+func TestJobAPIKeys(t *testing.T) {
+	s := &Service{cfg: &config.Config{JobAPIKeyTTL: time.Hour}, jobs: nopQueue{}}
+	ctx := context.WithValue(context.Background(), dto.UserAPIKeyContext, "user-key")
+	first := repository.Job{BaseModel: repository.BaseModel{ID: uuid.New()}}
+	second := repository.Job{BaseModel: repository.BaseModel{ID: uuid.New()}}
+	s.queued(ctx, first, second)
+
+	// The key outlives an attempt, so a retried job still uses it.
+	for range 2 {
+		if key, ok := s.apiKey(first.ID); !ok || key != "user-key" {
+			t.Fatalf("apiKey = %q, %v, want the caller's key", key, ok)
+		}
+	}
+
+	s.ReleaseJob(first.ID)
+	if _, ok := s.apiKey(first.ID); ok {
+		t.Error("key kept after its job was released")
+	}
+	if _, ok := s.apiKey(second.ID); !ok {
+		t.Error("releasing one job dropped another job's key")
+	}
+
+	s.queued(context.Background(), repository.Job{BaseModel: repository.BaseModel{ID: uuid.New()}})
+	if n := countAPIKeys(s); n != 1 {
+		t.Errorf("held %d keys, want 1 without a caller key", n)
+	}
+}
+
+func TestExpireJobAPIKeys(t *testing.T) {
+	s := &Service{cfg: &config.Config{JobAPIKeyTTL: time.Hour}, jobs: nopQueue{}}
+	expired, live := uuid.New(), uuid.New()
+	s.apiKeys.Store(expired, jobAPIKey{key: "old", expires: time.Now().Add(-time.Second)})
+	s.apiKeys.Store(live, jobAPIKey{key: "new", expires: time.Now().Add(time.Hour)})
+
+	if _, ok := s.apiKey(expired); ok {
+		t.Error("expired key was returned")
+	}
+
+	s.apiKeys.Store(expired, jobAPIKey{key: "old", expires: time.Now().Add(-time.Second)})
+	s.expireAPIKeys(time.Now())
+	if n := countAPIKeys(s); n != 1 {
+		t.Errorf("held %d keys after the sweep, want 1", n)
+	}
+	if _, ok := s.apiKey(live); !ok {
+		t.Error("sweep dropped a live key")
+	}
+}
+
+func countAPIKeys(s *Service) int {
+	n := 0
+	s.apiKeys.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"policy-match/internal/apperr"
+	"policy-match/internal/dto"
+	"policy-match/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNoDocuments = apperr.InvalidInput("no_documents_to_recheck", errors.New("no documents to re-check"))
+
+// RecheckDocument queues a job that re-runs a stored document against the
+// current rules of every policy it has a result for.
+func (s *Service) RecheckDocument(ctx context.Context, documentID uuid.UUID) (*repository.Job, error) {
+	document, err := s.repository.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("recheckDocument :: getDocumentByID: %w", err)
+	}
+
+	policyIDs := make([]uuid.UUID, 0, len(document.Results))
+	for _, res := range document.Results {
+		policyIDs = append(policyIDs, res.PolicyID)
+	}
+	if len(policyIDs) == 0 {
+		policyIDs = append(policyIDs, document.PolicyID)
+	}
+
+	job := newRecheckJob(ctx, document, policyIDs, nil)
+	if err := s.repository.CreateJob(ctx, &job); err != nil {
+		return nil, fmt.Errorf("recheckDocument :: createJob: %w", err)
+	}
+
+	s.queued(ctx, job)
+	return &job, nil
+}
+
+// RecheckPolicy queues a batch with one re-check job per document that has
+// a current result for the policy. With staleOnly, documents whose result
+// is still up to date are skipped.
+func (s *Service) RecheckPolicy(ctx context.Context, policyID uuid.UUID, staleOnly bool) (*repository.Batch, error) {
+	if _, err := s.repository.GetPolicyByID(ctx, policyID); err != nil {
+		return nil, fmt.Errorf("recheckPolicy :: getPolicyByID: %w", err)
+	}
+
+	documents, err := s.repository.GetDocumentsByPolicy(ctx, policyID, staleOnly)
+	if err != nil {
+		return nil, fmt.Errorf("recheckPolicy :: getDocumentsByPolicy: %w", err)
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("recheckPolicy :: %w", ErrNoDocuments)
+	}
+
+	batch := &repository.Batch{
+		BaseModel: repository.BaseModel{
+			ID: uuid.New(),
+		},
+		PolicyIDs: []uuid.UUID{policyID},
+		FileCount: len(documents),
+	}
+
+	jobs := make([]repository.Job, len(documents))
+	for i := range documents {
+		jobs[i] = newRecheckJob(ctx, &documents[i], batch.PolicyIDs, &batch.ID)
+	}
+
+	if err := s.repository.CreateBatch(ctx, batch, jobs); err != nil {
+		return nil, fmt.Errorf("recheckPolicy :: createBatch: %w", err)
+	}
+
+	s.queued(ctx, jobs...)
+
+	batch.Jobs = jobs
+	return batch, nil
+}
+
+// GetResultHistory returns every result a document has had, newest first.
+func (s *Service) GetResultHistory(ctx context.Context, documentID uuid.UUID) ([]repository.ComplianceResult, error) {
+	if _, err := s.repository.GetDocumentByID(ctx, documentID); err != nil {
+		return nil, fmt.Errorf("getResultHistory :: getDocumentByID: %w", err)
+	}
+
+	results, err := s.repository.GetResultHistory(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("getResultHistory :: %w", err)
+	}
+	return results, nil
+}
+
+// RecheckDocumentCompliance runs a re-check job. New results replace the
+// current ones for the job's policies; the old ones stay as history.
+func (s *Service) RecheckDocumentCompliance(ctx context.Context, job *repository.Job) (*repository.Document, error) {
+	if job.DocumentID == nil {
+		return nil, fmt.Errorf("recheckDocumentCompliance :: job has no document")
+	}
+	if len(job.PolicyIDs) == 0 {
+		return nil, fmt.Errorf("recheckDocumentCompliance :: %w", ErrNoPolicies)
+	}
+
+	document, err := s.repository.GetDocumentByID(ctx, *job.DocumentID)
+	if err != nil {
+		return nil, fmt.Errorf("recheckDocumentCompliance :: getDocumentByID: %w", err)
+	}
+
+	// Documents checked before extracted text was stored are
+	// extracted again once and keep the text from then on.
+	if document.ExtractedText == "" {
+		if err := s.reextract(ctx, document); err != nil {
+			return nil, fmt.Errorf("recheckDocumentCompliance :: %w", err)
+		}
+	}
+
+	err = s.repository.UpdateJob(ctx, job.ID, map[string]any{"status": repository.JobStatusAnalyzing})
+	if err != nil {
+		return nil, fmt.Errorf("recheckDocumentCompliance :: updateJob: %w", err)
+	}
+
+	rechecked := map[uuid.UUID]bool{}
+	results := make([]repository.ComplianceResult, 0, len(job.PolicyIDs))
+	for _, policyID := range job.PolicyIDs {
+		policy, err := s.repository.GetPolicyByID(ctx, policyID)
+		if err != nil {
+			return nil, fmt.Errorf("recheckDocumentCompliance :: getPolicyByID: %w", err)
+		}
+
+		result, err := s.checkPolicy(ctx, document.ID, policy, document.ExtractedText)
+		if err != nil {
+			return nil, fmt.Errorf("recheckDocumentCompliance :: %w", err)
+		}
+		rechecked[policyID] = true
+		results = append(results, *result)
+	}
+
+	current := results
+	for _, res := range document.Results {
+		if !rechecked[res.PolicyID] {
+			current = append(current, res)
+		}
+	}
+	document.Results = current
+	rollUp(document)
+
+	err = s.repository.ReplaceResults(ctx, document, results)
+	if err != nil {
+		return nil, fmt.Errorf("recheckDocumentCompliance :: replaceResults: %w", err)
+	}
+	return document, nil
+}
+
+func (s *Service) reextract(ctx context.Context, document *repository.Document) error {
+	rc, err := s.blobs.Get(ctx, document.BlobKey)
+	if err != nil {
+		return fmt.Errorf("reextract :: getBlob: %w", err)
+	}
+	defer rc.Close()
+
+	extraction, err := s.tikaClient.Extract(ctx, rc)
+	if err != nil {
+		return fmt.Errorf("reextract :: extract: %w", extractError(err))
+	}
+
+	document.Extraction = newExtraction(extraction, cleanText(extraction.Text))
+	if err := s.repository.UpdateExtraction(ctx, document.ID, document.Extraction); err != nil {
+		return fmt.Errorf("reextract :: updateExtraction: %w", err)
+	}
+	return nil
+}
+
+// markStale flags the results of policyID as out of date after its rules
+// or scoring changed.
+func (s *Service) markStale(ctx context.Context, policyID uuid.UUID) error {
+	if err := s.repository.MarkPolicyResultsStale(ctx, policyID); err != nil {
+		return fmt.Errorf("markStale :: %w", err)
+	}
+	return nil
+}
+
+// queued stores the caller's LLM key for jobs and wakes the worker pool.
+func (s *Service) queued(ctx context.Context, jobs ...repository.Job) {
+	if key, ok := ctx.Value(dto.UserAPIKeyContext).(string); ok && key != "" {
+		expires := time.Now().Add(s.cfg.JobAPIKeyTTL)
+		for _, job := range jobs {
+			s.apiKeys.Store(job.ID, jobAPIKey{key: key, expires: expires})
+		}
+	}
+	s.jobs.Notify()
+}
+
+func newRecheckJob(ctx context.Context, document *repository.Document, policyIDs []uuid.UUID, batchID *uuid.UUID) repository.Job {
+	return repository.Job{
+		BaseModel: repository.BaseModel{
+			ID: uuid.New(),
+		},
+		Kind:       repository.JobKindRecheck,
+		Status:     repository.JobStatusQueued,
+		PolicyIDs:  policyIDs,
+		FileName:   document.Title + document.Extension,
+		BlobKey:    document.BlobKey,
+		Size:       document.Size,
+		BatchID:    batchID,
+		DocumentID: &document.ID,
+		APIKeyID:   apiKeyID(ctx),
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"policy-match/internal/blob"
+	"policy-match/internal/client/llm"
+	"policy-match/internal/client/tika"
+	"policy-match/internal/config"
+	"policy-match/internal/repository"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newRecheckTestService returns a service on a Tika stand-in that returns
+// uploads as their text, counting calls in extracts, and a fake LLM that
+// answers rules "1" and "2" with *verdict.
+func newRecheckTestService(t *testing.T, verdict *string, extracts *atomic.Int32) *Service {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		extracts.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode([]map[string]any{{"X-TIKA:content": string(body), "dc:language": "en"}})
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := &config.Config{
+		TikaURL:                 srv.URL,
+		LLMChunkTokens:          1000,
+		LLMSectionTokens:        1000,
+		LLMConcurrency:          1,
+		SeverityWeights:         map[string]float64{},
+		ObligationWeights:       map[string]float64{},
+		CompliancePassThreshold: 80,
+		ReviewConfidence:        0.5,
+	}
+	repo := newTestRepository(t)
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newLocalStore: %v", err)
+	}
+	provider := llm.NewFakeProvider(func(llm.ChatRequest) (string, error) {
+		return `{"results": [
+			{"rule_id": "1", "verdict": "` + *verdict + `", "confidence": 0.9, "evidence": "", "rationale": "stated"},
+			{"rule_id": "2", "verdict": "` + *verdict + `", "confidence": 0.9, "evidence": "", "rationale": "stated"}],
+			"is_human_review_required": false}`, nil
+	})
+	return NewService(cfg, llm.NewLLMClient(cfg, repo, provider, nil), tika.NewTikaClient(cfg), repo, blobs, nopQueue{})
+}
+
+func createRecheckPolicy(t *testing.T, s *Service, title string) *repository.Policy {
+	t.Helper()
+	ctx := context.Background()
+	policy := &repository.Policy{BaseModel: repository.BaseModel{ID: uuid.New()}, Title: title, Category: "security", Version: 1}
+	if err := s.repository.CreatePolicy(ctx, policy); err != nil {
+		t.Fatalf("createPolicy: %v", err)
+	}
+	rules := []repository.Rule{
+		{BaseModel: repository.BaseModel{ID: uuid.New()}, PolicyID: policy.ID, RuleID: "1", RuleText: "Staff must wear badges.", Weight: 1, Obligation: repository.ObligationMust},
+		{BaseModel: repository.BaseModel{ID: uuid.New()}, PolicyID: policy.ID, RuleID: "2", RuleText: "Visitors must sign in.", Weight: 1, Obligation: repository.ObligationMust},
+	}
+	if err := s.repository.CreateRules(ctx, rules); err != nil {
+		t.Fatalf("createRules: %v", err)
+	}
+	policy.Rules = rules
+	if err := s.repository.CreatePolicyVersion(ctx, newPolicyVersion(policy, repository.VersionSourceUpload)); err != nil {
+		t.Fatalf("createPolicyVersion: %v", err)
+	}
+	return policy
+}
+
+// checkRecheckDocument stores text and checks it against policies.
+func checkRecheckDocument(t *testing.T, s *Service, text string, policies ...*repository.Policy) *repository.Document {
+	t.Helper()
+	ctx := context.Background()
+	obj, err := s.blobs.Put(ctx, strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("putBlob: %v", err)
+	}
+	job := &repository.Job{
+		BaseModel: repository.BaseModel{ID: uuid.New()},
+		Kind:      repository.JobKindCheck,
+		Status:    repository.JobStatusQueued,
+		FileName:  "handbook.txt",
+		BlobKey:   obj.Key,
+		Size:      obj.Size,
+	}
+	for _, p := range policies {
+		job.PolicyIDs = append(job.PolicyIDs, p.ID)
+	}
+	if err := s.repository.CreateJob(ctx, job); err != nil {
+		t.Fatalf("createJob: %v", err)
+	}
+	if err := s.ProcessJob(ctx, job); err != nil {
+		t.Fatalf("processJob: %v", err)
+	}
+	document, err := s.repository.GetDocumentByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("getDocumentByID: %v", err)
+	}
+	return document
+}
+
+func resultFor(t *testing.T, document *repository.Document, policyID uuid.UUID) repository.ComplianceResult {
+	t.Helper()
+	for _, res := range document.Results {
+		if res.PolicyID == policyID {
+			return res
+		}
+	}
+	t.Fatalf("document has no result for policy %s", policyID)
+	return repository.ComplianceResult{}
+}
+
+func TestRecheckPolicyAfterEdit(t *testing.T) {
+	ctx := context.Background()
+	verdict := "fail"
+	var extracts atomic.Int32
+	s := newRecheckTestService(t, &verdict, &extracts)
+
+	edited := createRecheckPolicy(t, s, "security")
+	untouched := createRecheckPolicy(t, s, "privacy")
+	document := checkRecheckDocument(t, s, "Staff wear badges.", edited, untouched)
+	if resultFor(t, document, edited.ID).IsCompliant {
+		t.Fatal("first check passed, want it to fail")
+	}
+
+	if _, err := s.RecheckPolicy(ctx, edited.ID, true); !errors.Is(err, ErrNoDocuments) {
+		t.Fatalf("recheck before edit: err = %v, want ErrNoDocuments", err)
+	}
+
+	err := s.UpdateRule(ctx, edited.ID, "2", map[string]any{"rule_text": "Visitors must sign in at reception."})
+	if err != nil {
+		t.Fatalf("updateRule: %v", err)
+	}
+	document, err = s.repository.GetDocumentByID(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("getDocumentByID: %v", err)
+	}
+	if !resultFor(t, document, edited.ID).IsStale || resultFor(t, document, untouched.ID).IsStale {
+		t.Fatal("want only the edited policy's result stale")
+	}
+
+	batch, err := s.RecheckPolicy(ctx, edited.ID, true)
+	if err != nil {
+		t.Fatalf("recheckPolicy: %v", err)
+	}
+	if len(batch.Jobs) != 1 || *batch.Jobs[0].DocumentID != document.ID {
+		t.Fatalf("batch jobs = %+v, want one job for the document", batch.Jobs)
+	}
+	if got := batch.Jobs[0].PolicyIDs; len(got) != 1 || got[0] != edited.ID {
+		t.Errorf("job policies = %v, want only the edited policy", got)
+	}
+
+	verdict = "pass"
+	calls := extracts.Load()
+	if err := s.ProcessJob(ctx, &batch.Jobs[0]); err != nil {
+		t.Fatalf("processJob: %v", err)
+	}
+	if extracts.Load() != calls {
+		t.Error("re-check extracted the document again instead of using its stored text")
+	}
+
+	document, err = s.repository.GetDocumentByID(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("getDocumentByID: %v", err)
+	}
+	current := resultFor(t, document, edited.ID)
+	if current.IsStale || !current.IsCompliant || current.PolicyVersion != 2 {
+		t.Errorf("re-checked result = stale %v, compliant %v, version %d, want fresh, passing, version 2",
+			current.IsStale, current.IsCompliant, current.PolicyVersion)
+	}
+	// The untouched policy still fails, so the document does too.
+	if kept := resultFor(t, document, untouched.ID); kept.IsCompliant || kept.PolicyVersion != 1 {
+		t.Errorf("untouched result = compliant %v, version %d, want the original", kept.IsCompliant, kept.PolicyVersion)
+	}
+	if document.IsCompliant {
+		t.Error("document compliant while one of its policies fails")
+	}
+
+	history, err := s.GetResultHistory(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("getResultHistory: %v", err)
+	}
+	if len(history) != 3 {
+		t.Errorf("history has %d results, want 3", len(history))
+	}
+	superseded := 0
+	for _, res := range history {
+		if res.SupersededAt != nil {
+			superseded++
+		}
+	}
+	if superseded != 1 {
+		t.Errorf("%d results superseded, want 1", superseded)
+	}
+}
+
+func TestRecheckDocumentReextracts(t *testing.T) {
+	ctx := context.Background()
+	verdict := "pass"
+	var extracts atomic.Int32
+	s := newRecheckTestService(t, &verdict, &extracts)
+
+	policy := createRecheckPolicy(t, s, "security")
+	document := checkRecheckDocument(t, s, "Staff wear badges.", policy)
+
+	// Documents checked before their text was stored have none.
+	err := s.repository.UpdateExtraction(ctx, document.ID, repository.Extraction{})
+	if err != nil {
+		t.Fatalf("updateExtraction: %v", err)
+	}
+
+	job, err := s.RecheckDocument(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("recheckDocument: %v", err)
+	}
+	if len(job.PolicyIDs) != 1 || job.PolicyIDs[0] != policy.ID {
+		t.Fatalf("job policies = %v, want %s", job.PolicyIDs, policy.ID)
+	}
+
+	calls := extracts.Load()
+	if err := s.ProcessJob(ctx, job); err != nil {
+		t.Fatalf("processJob: %v", err)
+	}
+	if extracts.Load() != calls+1 {
+		t.Errorf("extracted %d times, want once", extracts.Load()-calls)
+	}
+	document, err = s.repository.GetDocumentByID(ctx, document.ID)
+	if err != nil {
+		t.Fatalf("getDocumentByID: %v", err)
+	}
+	if strings.TrimSpace(document.ExtractedText) != "Staff wear badges." {
+		t.Errorf("extracted text = %q, want it stored again", document.ExtractedText)
+	}
+}
+
+func TestRecheckDocumentWithoutPolicies(t *testing.T) {
+	s := &Service{}
+	id := uuid.New()
+	_, err := s.RecheckDocumentCompliance(context.Background(), &repository.Job{DocumentID: &id})
+	if !errors.Is(err, ErrNoPolicies) {
+		t.Errorf("err = %v, want ErrNoPolicies", err)
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"math"
+	"policy-match/internal/client/llm"
+	"policy-match/internal/repository"
+)
+
+type score struct {
+	CompliancePercentage  int
+	ViolationPercentage   int
+	IsCompliant           bool
+	IsHumanReviewRequired bool
+	Violations            []string
+}
+
+// scoreResults derives the compliance score from per-rule verdicts alone, so
+// identical verdicts always give identical scores. Each rule contributes its
+// own weight multiplied by its severity and obligation weights.
+// Not-applicable rules are left out. Uncertain rules, and required rules no
+// part of the document addressed, count against the score and request
+// review; optional rules that were not addressed are left out. A failed
+// critical rule fails the document regardless of the percentage.
+func (s *Service) scoreResults(policy *repository.Policy, results []llm.RuleResult) score {
+	rules := make(map[string]repository.Rule, len(policy.Rules))
+	for _, rule := range policy.Rules {
+		rules[rule.RuleID] = rule
+	}
+
+	sc := score{Violations: []string{}}
+	var earned, failed, total float64
+	criticalFailed := false
+	for _, res := range results {
+		rule, ok := rules[res.RuleID]
+		if !ok {
+			continue
+		}
+		w := s.ruleWeight(rule)
+
+		switch res.Verdict {
+		case llm.VerdictNotApplicable:
+			continue
+		case llm.VerdictNotAddressed:
+			if !rule.IsRequired() {
+				continue
+			}
+			sc.IsHumanReviewRequired = true
+		case llm.VerdictPass:
+			earned += w
+		case llm.VerdictFail:
+			failed += w
+			sc.Violations = append(sc.Violations, rule.RuleText)
+			criticalFailed = criticalFailed || rule.Severity == repository.SeverityCritical
+		default:
+			sc.IsHumanReviewRequired = true
+		}
+		total += w
+
+		if res.Confidence < s.cfg.ReviewConfidence {
+			sc.IsHumanReviewRequired = true
+		}
+	}
+
+	sc.CompliancePercentage = 100
+	if total > 0 {
+		sc.CompliancePercentage = int(math.Round(earned * 100 / total))
+		sc.ViolationPercentage = int(math.Round(failed * 100 / total))
+	}
+
+	threshold := s.cfg.CompliancePassThreshold
+	if policy.PassThreshold != nil {
+		threshold = *policy.PassThreshold
+	}
+	sc.IsCompliant = !criticalFailed && sc.CompliancePercentage >= threshold
+
+	return sc
+}
+
+func (s *Service) ruleWeight(rule repository.Rule) float64 {
+	w := rule.Weight
+	if w <= 0 {
+		w = 1
+	}
+	if sw, ok := s.cfg.SeverityWeights[rule.Severity]; ok {
+		w *= sw
+	}
+	if ow, ok := s.cfg.ObligationWeights[rule.Obligation]; ok {
+		w *= ow
+	}
+	return w
+}
+
+// rollUp summarizes a document's per-policy results: it is compliant only
+// if every policy passes, needs review if any policy does, and its
+// percentages are the average across policies.
+func rollUp(document *repository.Document) {
+	document.IsCompliant = len(document.Results) > 0
+	document.IsHumanReviewRequired = false
+	document.IsStale = false
+	document.Violations = []string{}
+
+	var compliance, violation int
+	seen := map[string]bool{}
+	for _, res := range document.Results {
+		document.IsCompliant = document.IsCompliant && res.IsCompliant
+		document.IsHumanReviewRequired = document.IsHumanReviewRequired || res.IsHumanReviewRequired
+		document.IsStale = document.IsStale || res.IsStale
+		compliance += res.CompliancePercentage
+		violation += res.ViolationPercentage
+		for _, v := range res.Violations {
+			if !seen[v] {
+				seen[v] = true
+				document.Violations = append(document.Violations, v)
+			}
+		}
+	}
+
+	if n := len(document.Results); n > 0 {
+		document.CompliancePercentage = int(math.Round(float64(compliance) / float64(n)))
+		document.ViolationPercentage = int(math.Round(float64(violation) / float64(n)))
+	}
+}
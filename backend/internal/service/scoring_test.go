@@ -0,0 +1,242 @@
+package service
+
+import (
+	"policy-match/internal/client/llm"
+	"policy-match/internal/config"
+	"policy-match/internal/repository"
+	"testing"
+)
+
+func TestScoreResults(t *testing.T) {
+	s := &Service{cfg: &config.Config{
+		SeverityWeights:         map[string]float64{repository.SeverityCritical: 3, repository.SeverityMajor: 2, repository.SeverityMinor: 1},
+		ObligationWeights:       map[string]float64{},
+		CompliancePassThreshold: 80,
+		ReviewConfidence:        0.5,
+	}}
+	policy := &repository.Policy{Rules: []repository.Rule{
+		{RuleID: "1", RuleText: "critical rule", Severity: repository.SeverityCritical, Obligation: repository.ObligationMust},
+		{RuleID: "2", RuleText: "major rule", Severity: repository.SeverityMajor, Obligation: repository.ObligationMust},
+		{RuleID: "3", RuleText: "optional rule", Severity: repository.SeverityMinor, Obligation: repository.ObligationMay},
+	}}
+	result := func(id string, verdict llm.Verdict) llm.RuleResult {
+		return llm.RuleResult{RuleID: id, Verdict: verdict, Confidence: 0.9}
+	}
+
+	tests := []struct {
+		name       string
+		results    []llm.RuleResult
+		compliance int
+		violation  int
+		compliant  bool
+		review     bool
+	}{
+		{
+			name:       "all pass",
+			results:    []llm.RuleResult{result("1", llm.VerdictPass), result("2", llm.VerdictPass), result("3", llm.VerdictPass)},
+			compliance: 100,
+			compliant:  true,
+		},
+		{
+			name:       "failed critical rule fails the document",
+			results:    []llm.RuleResult{result("1", llm.VerdictFail), result("2", llm.VerdictPass), result("3", llm.VerdictPass)},
+			compliance: 50,
+			violation:  50,
+		},
+		{
+			name:       "not applicable is left out",
+			results:    []llm.RuleResult{result("1", llm.VerdictPass), result("2", llm.VerdictNotApplicable), result("3", llm.VerdictNotApplicable)},
+			compliance: 100,
+			compliant:  true,
+		},
+		{
+			name:       "optional not addressed is left out",
+			results:    []llm.RuleResult{result("1", llm.VerdictPass), result("2", llm.VerdictPass), result("3", llm.VerdictNotAddressed)},
+			compliance: 100,
+			compliant:  true,
+		},
+		{
+			name:       "required not addressed counts and requests review",
+			results:    []llm.RuleResult{result("1", llm.VerdictPass), result("2", llm.VerdictNotAddressed), result("3", llm.VerdictPass)},
+			compliance: 67,
+			review:     true,
+		},
+		{
+			name:       "uncertain counts and requests review",
+			results:    []llm.RuleResult{result("1", llm.VerdictPass), result("2", llm.VerdictUncertain), result("3", llm.VerdictPass)},
+			compliance: 67,
+			review:     true,
+		},
+		{
+			name:       "low confidence requests review",
+			results:    []llm.RuleResult{result("1", llm.VerdictPass), result("2", llm.VerdictPass), {RuleID: "3", Verdict: llm.VerdictPass, Confidence: 0.2}},
+			compliance: 100,
+			compliant:  true,
+			review:     true,
+		},
+		{
+			name:       "unknown rules are ignored",
+			results:    []llm.RuleResult{result("1", llm.VerdictPass), result("9", llm.VerdictFail)},
+			compliance: 100,
+			compliant:  true,
+		},
+		{
+			name:       "no scored rules",
+			results:    nil,
+			compliance: 100,
+			compliant:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := s.scoreResults(policy, tt.results)
+			if sc.CompliancePercentage != tt.compliance || sc.ViolationPercentage != tt.violation {
+				t.Errorf("percentages = %d/%d, want %d/%d", sc.CompliancePercentage, sc.ViolationPercentage, tt.compliance, tt.violation)
+			}
+			if sc.IsCompliant != tt.compliant {
+				t.Errorf("IsCompliant = %v, want %v", sc.IsCompliant, tt.compliant)
+			}
+			if sc.IsHumanReviewRequired != tt.review {
+				t.Errorf("IsHumanReviewRequired = %v, want %v", sc.IsHumanReviewRequired, tt.review)
+			}
+		})
+	}
+}
+
+func TestScoreResultsPolicyThreshold(t *testing.T) {
+	s := &Service{cfg: &config.Config{CompliancePassThreshold: 80}}
+	threshold := 40
+	policy := &repository.Policy{
+		PassThreshold: &threshold,
+		Rules:         []repository.Rule{{RuleID: "1", Weight: 1}, {RuleID: "2", Weight: 1}},
+	}
+	sc := s.scoreResults(policy, []llm.RuleResult{
+		{RuleID: "1", Verdict: llm.VerdictPass, Confidence: 1},
+		{RuleID: "2", Verdict: llm.VerdictFail, Confidence: 1},
+	})
+	if sc.CompliancePercentage != 50 || !sc.IsCompliant {
+		t.Errorf("score = %d%%, compliant %v, want 50%% and compliant", sc.CompliancePercentage, sc.IsCompliant)
+	}
+}
+
+func TestScoreResultsSeverityWeighting(t *testing.T) {
+	s := &Service{cfg: &config.Config{
+		SeverityWeights:         map[string]float64{repository.SeverityMajor: 3, repository.SeverityMinor: 1},
+		ObligationWeights:       map[string]float64{repository.ObligationMust: 1, repository.ObligationShould: 0.5},
+		CompliancePassThreshold: 70,
+	}}
+	policy := &repository.Policy{Rules: []repository.Rule{
+		{RuleID: "1", Severity: repository.SeverityMajor, Obligation: repository.ObligationMust},
+		{RuleID: "2", Severity: repository.SeverityMinor, Obligation: repository.ObligationMust},
+		{RuleID: "3", Severity: repository.SeverityMinor, Obligation: repository.ObligationShould, Weight: 2},
+	}}
+	// Weights: 3*1, 1*1 and 2*1*0.5, so passing only the major rule earns
+	// 3 of 5.
+	sc := s.scoreResults(policy, []llm.RuleResult{
+		{RuleID: "1", Verdict: llm.VerdictPass, Confidence: 1},
+		{RuleID: "2", Verdict: llm.VerdictFail, Confidence: 1},
+		{RuleID: "3", Verdict: llm.VerdictFail, Confidence: 1},
+	})
+	if sc.CompliancePercentage != 60 || sc.ViolationPercentage != 40 || sc.IsCompliant {
+		t.Errorf("score = %+v, want 60%%/40%% and not compliant", sc)
+	}
+	if len(sc.Violations) != 2 {
+		t.Errorf("violations = %q, want both failed rules", sc.Violations)
+	}
+}
+
+func TestScoreResultsZeroWeights(t *testing.T) {
+	s := &Service{cfg: &config.Config{
+		SeverityWeights:         map[string]float64{repository.SeverityCritical: 0, repository.SeverityMinor: 0},
+		CompliancePassThreshold: 80,
+	}}
+	policy := &repository.Policy{Rules: []repository.Rule{
+		{RuleID: "1", Severity: repository.SeverityMinor},
+		{RuleID: "2", Severity: repository.SeverityMajor, Weight: 0},
+		{RuleID: "3", Severity: repository.SeverityCritical},
+	}}
+
+	// A zero severity weight drops the rule from the percentage; a zero rule
+	// weight falls back to 1.
+	sc := s.scoreResults(policy, []llm.RuleResult{
+		{RuleID: "1", Verdict: llm.VerdictFail, Confidence: 1},
+		{RuleID: "2", Verdict: llm.VerdictPass, Confidence: 1},
+	})
+	if sc.CompliancePercentage != 100 || !sc.IsCompliant {
+		t.Errorf("score = %+v, want 100%% and compliant", sc)
+	}
+
+	// Only zero-weight rules scored: nothing to divide by, so 100%, but a
+	// failed critical rule still fails the document.
+	sc = s.scoreResults(policy, []llm.RuleResult{
+		{RuleID: "1", Verdict: llm.VerdictPass, Confidence: 1},
+		{RuleID: "3", Verdict: llm.VerdictFail, Confidence: 1},
+	})
+	if sc.CompliancePercentage != 100 || sc.ViolationPercentage != 0 || sc.IsCompliant {
+		t.Errorf("score = %+v, want 100%% and not compliant", sc)
+	}
+}
+
+func TestScoreResultsEmptyPolicy(t *testing.T) {
+	s := &Service{cfg: &config.Config{CompliancePassThreshold: 80}}
+	sc := s.scoreResults(&repository.Policy{}, []llm.RuleResult{{RuleID: "1", Verdict: llm.VerdictFail}})
+	if sc.CompliancePercentage != 100 || !sc.IsCompliant || sc.IsHumanReviewRequired || len(sc.Violations) != 0 {
+		t.Errorf("score = %+v, want 100%% and compliant", sc)
+	}
+}
+
+func TestRollUp(t *testing.T) {
+	tests := []struct {
+		name       string
+		results    []repository.ComplianceResult
+		compliance int
+		violation  int
+		compliant  bool
+		review     bool
+		stale      bool
+		violations int
+	}{
+		{
+			name:      "no results",
+			compliant: false,
+		},
+		{
+			name: "averages across policies",
+			results: []repository.ComplianceResult{
+				{CompliancePercentage: 100, IsCompliant: true},
+				{CompliancePercentage: 67, ViolationPercentage: 33, IsCompliant: true},
+			},
+			compliance: 84,
+			violation:  17,
+			compliant:  true,
+		},
+		{
+			name: "one failing policy fails the document",
+			results: []repository.ComplianceResult{
+				{CompliancePercentage: 100, IsCompliant: true},
+				{CompliancePercentage: 40, ViolationPercentage: 60, Violations: []string{"a", "b"}, IsHumanReviewRequired: true, IsStale: true},
+				{CompliancePercentage: 90, ViolationPercentage: 10, Violations: []string{"a"}, IsCompliant: true},
+			},
+			compliance: 77,
+			violation:  23,
+			review:     true,
+			stale:      true,
+			violations: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &repository.Document{Results: tt.results, IsStale: true, IsCompliant: true}
+			rollUp(doc)
+			if doc.CompliancePercentage != tt.compliance || doc.ViolationPercentage != tt.violation {
+				t.Errorf("percentages = %d/%d, want %d/%d", doc.CompliancePercentage, doc.ViolationPercentage, tt.compliance, tt.violation)
+			}
+			if doc.IsCompliant != tt.compliant || doc.IsHumanReviewRequired != tt.review || doc.IsStale != tt.stale {
+				t.Errorf("flags = compliant %v, review %v, stale %v", doc.IsCompliant, doc.IsHumanReviewRequired, doc.IsStale)
+			}
+			if len(doc.Violations) != tt.violations {
+				t.Errorf("violations = %q, want %d distinct", doc.Violations, tt.violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"policy-match/internal/repository"
+)
+
+const (
+	SearchTypeAll       = "all"
+	SearchTypeRules     = "rules"
+	SearchTypeDocuments = "documents"
+)
+
+type SearchResults struct {
+	Rules     []repository.RuleHit
+	Documents []repository.DocumentHit
+}
+
+// Search runs a full-text query over rule text, stored document text, or
+// both, best matches first.
+func (s *Service) Search(ctx context.Context, q repository.SearchQuery, searchType string) (*SearchResults, error) {
+	results := &SearchResults{
+		Rules:     []repository.RuleHit{},
+		Documents: []repository.DocumentHit{},
+	}
+
+	if searchType != SearchTypeDocuments {
+		rules, err := s.repository.SearchRules(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("search :: searchRules: %w", err)
+		}
+		results.Rules = append(results.Rules, rules...)
+	}
+	if searchType != SearchTypeRules {
+		documents, err := s.repository.SearchDocuments(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("search :: searchDocuments: %w", err)
+		}
+		results.Documents = append(results.Documents, documents...)
+	}
+
+	return results, nil
+}
@@ -2,8 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"path/filepath"
+	"policy-match/internal/apperr"
+	"policy-match/internal/blob"
 	"policy-match/internal/client/llm"
 	"policy-match/internal/client/tika"
 	"policy-match/internal/config"
@@ -11,130 +16,259 @@ import (
 	"policy-match/internal/repository"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Service struct {
 	cfg        *config.Config
 	llmClient  *llm.LLMClient
 	tikaClient *tika.TikaClient
-	repository *repository.Repository
+	repository repository.Repository
+	blobs      blob.Store
+	jobs       JobQueue
+
+	// apiKeys holds caller-supplied LLM keys for queued jobs as jobAPIKey
+	// values. They are kept in memory only, so jobs resumed after a restart
+	// or on another replica use the server key.
+	apiKeys sync.Map
+}
+
+// JobQueue is notified whenever a new job is persisted.
+type JobQueue interface {
+	Notify()
 }
 
 func NewService(
 	cfg *config.Config,
 	llmClient *llm.LLMClient,
 	tikaClient *tika.TikaClient,
-	repository *repository.Repository,
+	repository repository.Repository,
+	blobs blob.Store,
+	jobs JobQueue,
 ) *Service {
 	return &Service{
 		cfg:        cfg,
 		llmClient:  llmClient,
 		tikaClient: tikaClient,
 		repository: repository,
+		blobs:      blobs,
+		jobs:       jobs,
 	}
 }
 
-func (s *Service) UploadPolicy(ctx context.Context, req dto.UploadPolicyRequestDTO) error {
+// UploadPolicy creates a policy, or with a non-nil policyID stores the
+// file as the next version of that policy.
+func (s *Service) UploadPolicy(ctx context.Context, req dto.UploadPolicyRequestDTO, policyID uuid.UUID) (*repository.Policy, error) {
+	var current *repository.Policy
+	if policyID != uuid.Nil {
+		policy, err := s.repository.GetPolicyByID(ctx, policyID)
+		if err != nil {
+			return nil, fmt.Errorf("uploadPolicy :: getPolicyByID: %w", err)
+		}
+		current = policy
+	}
+
 	f, err := req.File.Open()
 	if err != nil {
-		return fmt.Errorf("uploadPolicy :: open file: %w", err)
+		return nil, fmt.Errorf("uploadPolicy :: open file: %w", err)
 	}
 	defer f.Close()
 
-	extractedText, err := s.tikaClient.ExtractText(ctx, f)
+	obj, err := s.blobs.Put(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("uploadPolicy :: putBlob: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("uploadPolicy :: rewind file: %w", err)
+	}
+
+	extraction, err := s.tikaClient.Extract(ctx, f)
 	if err != nil {
-		return fmt.Errorf("uploadPolicy :: extractText: %w", err)
+		return nil, fmt.Errorf("uploadPolicy :: extract: %w", extractError(err))
 	}
 
-	cleanedText := cleanText(extractedText)
-	rules, err := s.llmClient.ExtractRules(ctx, cleanedText)
+	cleanedText := cleanText(extraction.Text)
+	extracted, err := s.llmClient.ExtractRules(ctx, cleanedText)
 	if err != nil {
-		return fmt.Errorf("uploadPolicy :: extractRules: %w", err)
+		return nil, fmt.Errorf("uploadPolicy :: extractRules: %w", llmError(err))
 	}
 
 	filename, ext := sanitizeFilename(req.File.Filename)
-	docId := uuid.New()
-	doc := &repository.Policy{
+	doc := repository.Policy{
 		BaseModel: repository.BaseModel{
-			ID: docId,
+			ID: uuid.New(),
 		},
 		Title:     req.Title,
 		Category:  req.Category,
 		Path:      filename,
 		Extension: ext,
-	}
 
-	err = s.repository.CreatePolicy(ctx, doc)
-	if err != nil {
-		return fmt.Errorf("uploadPolicy :: createDocument: %w", err)
+		BlobKey:     obj.Key,
+		ContentType: contentType(ext),
+		Size:        obj.Size,
+
+		Extraction:      newExtraction(extraction, cleanedText),
+		RulesOutputPath: string(extracted.OutputPath),
+		Version:         1,
 	}
 
-	rulesModel := make([]repository.Rule, len(rules))
-	for i, rule := range rules {
-		rulesModel[i] = repository.Rule{
-			BaseModel: repository.BaseModel{
-				ID: uuid.New(),
-			},
-			PolicyID: docId,
-			RuleID:   rule.RuleID,
-			RuleText: rule.RuleText,
+	if current != nil {
+		revised, err := s.revisePolicy(ctx, current.ID, doc, extracted.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("uploadPolicy :: %w", err)
 		}
+		return revised, nil
 	}
 
+	err = s.repository.CreatePolicy(ctx, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("uploadPolicy :: createDocument: %w", err)
+	}
+
+	_, rulesModel := matchRules(doc.ID, nil, extracted.Rules)
 	err = s.repository.CreateRules(ctx, rulesModel)
 	if err != nil {
-		return fmt.Errorf("uploadPolicy :: createRules: %w", err)
+		return nil, fmt.Errorf("uploadPolicy :: createRules: %w", err)
 	}
+	doc.Rules = rulesModel
 
-	return nil
+	err = s.repository.CreatePolicyVersion(ctx, newPolicyVersion(&doc, repository.VersionSourceUpload))
+	if err != nil {
+		return nil, fmt.Errorf("uploadPolicy :: createPolicyVersion: %w", err)
+	}
+
+	return &doc, nil
 }
 
-func (s *Service) CheckDocumentCompliance(ctx context.Context, req dto.UploadDocumentRequestDTO) (*llm.CheckComplianceResponse, error) {
-	f, err := req.File.Open()
+// CheckDocumentCompliance extracts the job's file once, checks it against
+// each of the job's policies and stores one document with a result per
+// policy.
+func (s *Service) CheckDocumentCompliance(ctx context.Context, job *repository.Job) (*repository.Document, error) {
+	if len(job.PolicyIDs) == 0 {
+		return nil, fmt.Errorf("checkDocumentCompliance :: %w", ErrNoPolicies)
+	}
+
+	// The document takes the job's ID, so a job retried after its worker
+	// died between saving the document and finishing the job returns the
+	// saved document instead of creating a second one.
+	documentID := job.ID
+	if document, err := s.repository.GetDocumentByID(ctx, documentID); err == nil {
+		return document, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("checkDocumentCompliance :: getDocumentByID: %w", err)
+	}
+
+	rc, err := s.blobs.Get(ctx, job.BlobKey)
 	if err != nil {
-		return nil, fmt.Errorf("checkDocumentCompliance :: open file: %w", err)
+		return nil, fmt.Errorf("checkDocumentCompliance :: getBlob: %w", err)
 	}
-	defer f.Close()
+	defer rc.Close()
 
-	docExtractedContent, err := s.tikaClient.ExtractText(ctx, f)
+	extraction, err := s.tikaClient.Extract(ctx, rc)
 	if err != nil {
-		return nil, fmt.Errorf("checkDocumentCompliance :: extractText: %w", err)
+		return nil, fmt.Errorf("checkDocumentCompliance :: extract: %w", extractError(err))
+	}
+	docExtractedContent := cleanText(extraction.Text)
+
+	err = s.repository.UpdateJob(ctx, job.ID, map[string]any{"status": repository.JobStatusAnalyzing})
+	if err != nil {
+		return nil, fmt.Errorf("checkDocumentCompliance :: updateJob: %w", err)
+	}
+
+	results := make([]repository.ComplianceResult, 0, len(job.PolicyIDs))
+	for _, policyID := range job.PolicyIDs {
+		policy, err := s.repository.GetPolicyByID(ctx, policyID)
+		if err != nil {
+			return nil, fmt.Errorf("checkDocumentCompliance :: getPolicyByID: %w", err)
+		}
+
+		result, err := s.checkPolicy(ctx, documentID, policy, docExtractedContent)
+		if err != nil {
+			return nil, fmt.Errorf("checkDocumentCompliance :: %w", err)
+		}
+		results = append(results, *result)
+	}
+
+	filename, ext := sanitizeFilename(job.FileName)
+	document := &repository.Document{
+		BaseModel: repository.BaseModel{
+			ID: documentID,
+		},
+		Title:     filename,
+		Path:      filename,
+		Extension: ext,
+
+		BlobKey:     job.BlobKey,
+		ContentType: contentType(ext),
+		Size:        job.Size,
+
+		Extraction: newExtraction(extraction, docExtractedContent),
+
+		PolicyID: job.PolicyIDs[0],
+		Results:  results,
 	}
+	rollUp(document)
 
-	policy, err := s.repository.GetPolicyByID(ctx, uuid.MustParse(req.PolicyID))
+	err = s.repository.CreateDocument(ctx, document)
 	if err != nil {
-		return nil, fmt.Errorf("checkDocumentCompliance :: getPolicyByID: %w", err)
+		return nil, fmt.Errorf("checkDocumentCompliance :: createDocument: %w", err)
 	}
+	return document, nil
+}
 
+func (s *Service) checkPolicy(ctx context.Context, documentID uuid.UUID, policy *repository.Policy, text string) (*repository.ComplianceResult, error) {
 	checkComplianceResponse, err := s.llmClient.
 		CheckCompliance(
 			ctx,
 			policy.Rules,
-			docExtractedContent,
+			text,
 		)
 	if err != nil {
-		return nil, fmt.Errorf("checkDocumentCompliance :: chat: %w", err)
+		return nil, fmt.Errorf("checkPolicy :: chat: %w", llmError(err))
 	}
 
-	filename, ext := sanitizeFilename(req.File.Filename)
-	s.repository.CreateDocument(ctx, &repository.Document{
-		BaseModel: repository.BaseModel{
-			ID: uuid.New(),
-		},
-		Title:     filename,
-		Path:      filename,
-		Extension: ext,
+	sc := s.scoreResults(policy, checkComplianceResponse.Results)
 
-		Violations:            checkComplianceResponse.Violations,
-		IsCompliant:           checkComplianceResponse.IsCompliant,
-		IsHumanReviewRequired: checkComplianceResponse.IsHumanReviewRequired,
-		CompliancePercentage:  checkComplianceResponse.CompliancePercentage,
+	resultID := uuid.New()
+	ruleResults := make([]repository.RuleResult, len(checkComplianceResponse.Results))
+	for i, res := range checkComplianceResponse.Results {
+		ruleResults[i] = repository.RuleResult{
+			BaseModel: repository.BaseModel{
+				ID: uuid.New(),
+			},
+			DocumentID:         documentID,
+			ComplianceResultID: &resultID,
+			RuleUUID:           res.RuleUUID,
+			RuleID:             res.RuleID,
+			Verdict:            string(res.Verdict),
+			Confidence:         res.Confidence,
+			Evidence:           res.Evidence,
+			EvidenceStart:      res.EvidenceStart,
+			EvidenceEnd:        res.EvidenceEnd,
+			Rationale:          res.Rationale,
+		}
+	}
 
-		PolicyID: policy.ID,
-	})
-	return checkComplianceResponse, nil
+	return &repository.ComplianceResult{
+		BaseModel: repository.BaseModel{
+			ID: resultID,
+		},
+		DocumentID: documentID,
+		PolicyID:   policy.ID,
+
+		Violations:            sc.Violations,
+		IsCompliant:           sc.IsCompliant,
+		IsHumanReviewRequired: checkComplianceResponse.IsHumanReviewRequired || sc.IsHumanReviewRequired,
+		CompliancePercentage:  sc.CompliancePercentage,
+		ViolationPercentage:   sc.ViolationPercentage,
+		PolicyVersion:         policy.Version,
+		OutputPath:            string(checkComplianceResponse.OutputPath),
+
+		RuleResults: ruleResults,
+	}, nil
 }
 
 func sanitizeFilename(filename string) (string, string) {
@@ -147,12 +281,31 @@ func sanitizeFilename(filename string) (string, string) {
 	return filename, ext
 }
 
-func (s *Service) GetDocuments(ctx context.Context, page int, pageSize int) ([]repository.Document, int, error) {
+func newExtraction(extraction *tika.Extraction, cleanedText string) repository.Extraction {
+	return repository.Extraction{
+		ExtractedText: cleanedText,
+		PageCount:     extraction.PageCount,
+		MimeType:      extraction.MimeType,
+		Author:        extraction.Author,
+		AuthoredAt:    extraction.AuthoredAt,
+		Language:      extraction.Language,
+	}
+}
+
+func contentType(ext string) string {
+	if t := mime.TypeByExtension(strings.ToLower(ext)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func (s *Service) GetDocuments(ctx context.Context, filter repository.DocumentFilter, page int, pageSize int) ([]repository.Document, int, error) {
 	offset := (page - 1) * pageSize
 
 	documents, total, err := s.repository.
 		GetAllDocuments(
 			ctx,
+			filter,
 			offset,
 			pageSize,
 		)
@@ -180,12 +333,48 @@ func cleanText(raw string) string {
 	return cleaned
 }
 
-func (s *Service) GetPolicies(ctx context.Context, page int, pageSize int) ([]repository.Policy, int, error) {
+func (s *Service) GetPolicy(ctx context.Context, id uuid.UUID) (*repository.Policy, error) {
+	policy, err := s.repository.GetPolicyByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getPolicy :: getPolicyByID: %w", err)
+	}
+	return policy, nil
+}
+
+// GetDocument returns the document with only the rule results whose rules
+// pass filter. Scores and violations are left as checked.
+func (s *Service) GetDocument(ctx context.Context, id uuid.UUID, filter repository.RuleFilter) (*repository.Document, error) {
+	document, err := s.repository.GetDocumentByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getDocument :: getDocumentByID: %w", err)
+	}
+
+	for i := range document.Results {
+		document.Results[i].RuleResults = filterRuleResults(document.Results[i].RuleResults, filter)
+	}
+	return document, nil
+}
+
+func filterRuleResults(results []repository.RuleResult, filter repository.RuleFilter) []repository.RuleResult {
+	if filter.IsZero() {
+		return results
+	}
+	filtered := make([]repository.RuleResult, 0, len(results))
+	for _, res := range results {
+		if filter.Match(res.Rule) {
+			filtered = append(filtered, res)
+		}
+	}
+	return filtered
+}
+
+func (s *Service) GetPolicies(ctx context.Context, filter repository.PolicyFilter, page int, pageSize int) ([]repository.Policy, int, error) {
 	offset := (page - 1) * pageSize
 
 	policies, total, err := s.repository.
 		GetAllPolicies(
 			ctx,
+			filter,
 			offset,
 			pageSize,
 		)
@@ -195,24 +384,72 @@ func (s *Service) GetPolicies(ctx context.Context, page int, pageSize int) ([]re
 	return policies, total, nil
 }
 
-func (s *Service) DeleteDocument(ctx context.Context, id string) error {
-	return s.repository.DeleteDocument(ctx, uuid.MustParse(id))
+func (s *Service) DeleteDocument(ctx context.Context, id uuid.UUID) error {
+	if err := s.repository.DeleteDocument(ctx, id); err != nil {
+		return fmt.Errorf("deleteDocument :: %w", err)
+	}
+	return nil
 }
 
-func (s *Service) DeletePolicy(ctx context.Context, id string) error {
-	return s.repository.DeletePolicy(ctx, uuid.MustParse(id))
+func (s *Service) DeletePolicy(ctx context.Context, id uuid.UUID) error {
+	if err := s.repository.DeletePolicy(ctx, id); err != nil {
+		return fmt.Errorf("deletePolicy :: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) DeleteRule(ctx context.Context, policyID uuid.UUID, ruleID string) error {
+	return s.editPolicy(ctx, policyID, func(repo repository.Repository, _ *repository.Policy) error {
+		return repo.DeleteRule(ctx, policyID, ruleID)
+	})
 }
 
-func (s *Service) DeleteRule(ctx context.Context, id string) error {
-	return s.repository.DeleteRule(ctx, uuid.MustParse(id))
+func (s *Service) UpdatePolicy(ctx context.Context, id uuid.UUID, updates map[string]any) error {
+	if err := s.repository.UpdatePolicy(ctx, id, updates); err != nil {
+		return fmt.Errorf("updatePolicy :: %w", err)
+	}
+
+	// Only the pass threshold changes how results are scored.
+	if _, ok := updates["pass_threshold"]; ok {
+		return s.markStale(ctx, id)
+	}
+	return nil
 }
 
+// ErrInvalidParentRule rejects a parent that is not a rule of the same
+// policy, or that is nested under the rule being edited.
+var ErrInvalidParentRule = apperr.InvalidInput("parent_rule_is_invalid", errors.New("parent rule is missing or nested under the rule"))
+
 func (s *Service) UpdateRule(ctx context.Context, policyID uuid.UUID, ruleID string, updates map[string]any) error {
-	return s.repository.
-		UpdateRule(
-			ctx,
-			policyID,
-			ruleID,
-			updates,
-		)
+	return s.editPolicy(ctx, policyID, func(repo repository.Repository, policy *repository.Policy) error {
+		if parent, ok := updates["parent_rule_id"].(string); ok && parent != "" && !validParent(policy.Rules, ruleID, parent) {
+			return fmt.Errorf("updateRule :: %w", ErrInvalidParentRule)
+		}
+		return repo.
+			UpdateRule(
+				ctx,
+				policyID,
+				ruleID,
+				updates,
+			)
+	})
+}
+
+// validParent reports whether parent can be made the parent of ruleID:
+// it must exist, and following its own parents must not lead back to
+// ruleID.
+func validParent(rules []repository.Rule, ruleID string, parent string) bool {
+	parents := make(map[string]string, len(rules))
+	for _, rule := range rules {
+		parents[rule.RuleID] = rule.ParentRuleID
+	}
+	if _, ok := parents[parent]; !ok {
+		return false
+	}
+	for id, steps := parent, 0; id != "" && steps <= len(rules); id, steps = parents[id], steps+1 {
+		if id == ruleID {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"policy-match/internal/client/oidc"
+	"policy-match/internal/repository"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoWorkspaceAccess means a signed-in user has no role in the workspace
+// their token points at.
+var ErrNoWorkspaceAccess = errors.New("no access to workspace")
+
+// AuthenticateUser maps a verified token onto a user and their membership
+// in the token's workspace, the default one if it names none. Roles in
+// the token win and are synced to the membership, so the members list
+// shows SSO users; without any, the stored membership decides. It runs on
+// every request, so it only writes when the user is new or the token's
+// role differs from the stored one.
+func (s *Service) AuthenticateUser(ctx context.Context, identity oidc.Identity) (*repository.WorkspaceMember, error) {
+	workspaceID := repository.DefaultWorkspaceID
+	if identity.WorkspaceID != nil {
+		workspaceID = *identity.WorkspaceID
+	}
+	if _, err := s.repository.GetWorkspaceByID(ctx, workspaceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoWorkspaceAccess
+		}
+		return nil, fmt.Errorf("authenticateUser :: getWorkspaceByID: %w", err)
+	}
+
+	var member *repository.WorkspaceMember
+	user, err := s.repository.GetUserByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		member, err = s.repository.GetWorkspaceMember(ctx, workspaceID, user.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("authenticateUser :: getWorkspaceMember: %w", err)
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("authenticateUser :: getUserByEmail: %w", err)
+	}
+
+	role := highestRole(identity.Roles)
+	if role == "" {
+		if member == nil {
+			return nil, ErrNoWorkspaceAccess
+		}
+		return member, nil
+	}
+	if member != nil && member.Role == role {
+		return member, nil
+	}
+
+	member, err = s.AddWorkspaceMember(repository.WithWorkspace(ctx, workspaceID), workspaceID, identity.Email, identity.Name, role)
+	if err != nil {
+		return nil, fmt.Errorf("authenticateUser :: %w", err)
+	}
+	return member, nil
+}
+
+// highestRole returns the most privileged known role in roles, or "" if
+// none is known. Unknown roles, e.g. the issuer's own, are ignored.
+func highestRole(roles []string) string {
+	for _, role := range repository.Roles {
+		if slices.Contains(roles, role) {
+			return role
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"policy-match/internal/client/oidc"
+	"policy-match/internal/config"
+	"policy-match/internal/repository"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newTestRepository(t *testing.T) repository.Repository {
+	t.Helper()
+	repo, err := repository.NewRepository(&config.Config{
+		DBDriver:      repository.DriverSQLite,
+		DBURL:         ":memory:",
+		DBAutoMigrate: true,
+	})
+	if err != nil {
+		t.Fatalf("newRepository: %v", err)
+	}
+	return repo
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	s := &Service{cfg: &config.Config{}, repository: repo}
+
+	if _, err := s.AuthenticateUser(ctx, oidc.Identity{Email: "ada@example.com"}); !errors.Is(err, ErrNoWorkspaceAccess) {
+		t.Fatalf("without a role or membership: %v, want ErrNoWorkspaceAccess", err)
+	}
+	if _, err := repo.GetUserByEmail(ctx, "ada@example.com"); err == nil {
+		t.Fatal("a user without access was created")
+	}
+
+	identity := oidc.Identity{Email: "Ada@Example.com", Name: "Ada", Roles: []string{"offline_access", repository.RoleReviewer}}
+	member, err := s.AuthenticateUser(ctx, identity)
+	if err != nil {
+		t.Fatalf("first sign-in: %v", err)
+	}
+	if member.Role != repository.RoleReviewer || member.WorkspaceID != repository.DefaultWorkspaceID {
+		t.Fatalf("member = %+v", member)
+	}
+
+	again, err := s.AuthenticateUser(ctx, identity)
+	if err != nil {
+		t.Fatalf("second sign-in: %v", err)
+	}
+	if !again.UpdatedAt.Equal(member.UpdatedAt) {
+		t.Errorf("membership was written again with an unchanged role")
+	}
+
+	stored, err := s.AuthenticateUser(ctx, oidc.Identity{Email: "ada@example.com"})
+	if err != nil || stored.Role != repository.RoleReviewer {
+		t.Fatalf("without a role: %+v, %v, want the stored membership", stored, err)
+	}
+
+	identity.Roles = []string{repository.RoleAdmin}
+	promoted, err := s.AuthenticateUser(ctx, identity)
+	if err != nil || promoted.Role != repository.RoleAdmin {
+		t.Fatalf("role change: %+v, %v", promoted, err)
+	}
+
+	missing := uuid.New()
+	if _, err := s.AuthenticateUser(ctx, oidc.Identity{Email: "ada@example.com", WorkspaceID: &missing, Roles: []string{repository.RoleAdmin}}); !errors.Is(err, ErrNoWorkspaceAccess) {
+		t.Fatalf("unknown workspace: %v, want ErrNoWorkspaceAccess", err)
+	}
+}
@@ -0,0 +1,469 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"policy-match/internal/client/llm"
+	"policy-match/internal/repository"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ruleMatchThreshold is the minimum word overlap for a re-extracted rule to
+// be treated as a revision of an existing rule rather than a new one.
+const ruleMatchThreshold = 0.5
+
+type RuleDiff struct {
+	Before repository.RuleSnapshot
+	After  repository.RuleSnapshot
+}
+
+type PolicyDiff struct {
+	PolicyID  uuid.UUID
+	From      int
+	To        int
+	Added     []repository.RuleSnapshot
+	Removed   []repository.RuleSnapshot
+	Modified  []RuleDiff
+	Unchanged int
+}
+
+// revisePolicy stores a re-uploaded file as the next version of a policy.
+// Extracted rules are matched against the current ones so unchanged and
+// revised rules keep their identity. The policy is re-read under its lock,
+// so an edit made while the file was being extracted is not lost.
+func (s *Service) revisePolicy(ctx context.Context, policyID uuid.UUID, revision repository.Policy, extracted []llm.Rule) (*repository.Policy, error) {
+	var policy *repository.Policy
+	err := s.repository.LockPolicy(ctx, policyID, func(repo repository.Repository) error {
+		var err error
+		policy, err = repo.GetPolicyByID(ctx, policyID)
+		if err != nil {
+			return fmt.Errorf("getPolicyByID: %w", err)
+		}
+		if err := ensureVersion(ctx, repo, policy); err != nil {
+			return err
+		}
+
+		changes, rules := matchRules(policy.ID, policy.Rules, extracted)
+
+		if revision.Title != "" {
+			policy.Title = revision.Title
+		}
+		if revision.Category != "" {
+			policy.Category = revision.Category
+		}
+		policy.Path = revision.Path
+		policy.Extension = revision.Extension
+		policy.BlobKey = revision.BlobKey
+		policy.ContentType = revision.ContentType
+		policy.Size = revision.Size
+		policy.Extraction = revision.Extraction
+		policy.RulesOutputPath = revision.RulesOutputPath
+		policy.Version++
+		policy.Rules = rules
+
+		version := newPolicyVersion(policy, repository.VersionSourceUpload)
+		if err := repo.SavePolicyVersion(ctx, policy, changes, version); err != nil {
+			return fmt.Errorf("savePolicyVersion: %w", err)
+		}
+		if err := repo.MarkPolicyResultsStale(ctx, policy.ID); err != nil {
+			return fmt.Errorf("markPolicyResultsStale: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("revisePolicy :: %w", err)
+	}
+	return policy, nil
+}
+
+// ensureVersion snapshots the policy's current state if it has none yet,
+// which is the case for policies created before versioning. It runs under
+// the policy's lock.
+func ensureVersion(ctx context.Context, repo repository.Repository, policy *repository.Policy) error {
+	_, err := repo.GetPolicyVersion(ctx, policy.ID, policy.Version)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("ensureVersion :: getPolicyVersion: %w", err)
+	}
+
+	err = repo.CreatePolicyVersion(ctx, newPolicyVersion(policy, repository.VersionSourceUpload))
+	if err != nil {
+		return fmt.Errorf("ensureVersion :: createPolicyVersion: %w", err)
+	}
+	return nil
+}
+
+// ensureVersionLocked loads the policy and runs ensureVersion under its
+// lock, for reads that need the current version to exist.
+func (s *Service) ensureVersionLocked(ctx context.Context, policyID uuid.UUID) error {
+	return s.repository.LockPolicy(ctx, policyID, func(repo repository.Repository) error {
+		policy, err := repo.GetPolicyByID(ctx, policyID)
+		if err != nil {
+			return fmt.Errorf("getPolicyByID: %w", err)
+		}
+		return ensureVersion(ctx, repo, policy)
+	})
+}
+
+// editPolicy applies a rule edit and records the result as a new version.
+// The read, the edit and the snapshot run in one transaction under the
+// policy's lock; edit is given the policy as it was before and must only
+// use repo.
+func (s *Service) editPolicy(ctx context.Context, policyID uuid.UUID, edit func(repo repository.Repository, policy *repository.Policy) error) error {
+	err := s.repository.LockPolicy(ctx, policyID, func(repo repository.Repository) error {
+		policy, err := repo.GetPolicyByID(ctx, policyID)
+		if err != nil {
+			return fmt.Errorf("getPolicyByID: %w", err)
+		}
+		if err := ensureVersion(ctx, repo, policy); err != nil {
+			return err
+		}
+		previous := newPolicyVersion(policy, repository.VersionSourceEdit)
+
+		if err := edit(repo, policy); err != nil {
+			return err
+		}
+
+		policy, err = repo.GetPolicyByID(ctx, policyID)
+		if err != nil {
+			return fmt.Errorf("getPolicyByID: %w", err)
+		}
+		policy.Version++
+		version := newPolicyVersion(policy, repository.VersionSourceEdit)
+
+		// An edit that changed nothing does not create a version.
+		if reflect.DeepEqual(previous.Rules, version.Rules) {
+			return nil
+		}
+
+		if err := repo.CreatePolicyVersion(ctx, version); err != nil {
+			return fmt.Errorf("createPolicyVersion: %w", err)
+		}
+		if err := repo.MarkPolicyResultsStale(ctx, policyID); err != nil {
+			return fmt.Errorf("markPolicyResultsStale: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("editPolicy :: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) GetPolicyVersions(ctx context.Context, policyID uuid.UUID) ([]repository.PolicyVersion, error) {
+	if err := s.ensureVersionLocked(ctx, policyID); err != nil {
+		return nil, fmt.Errorf("getPolicyVersions :: %w", err)
+	}
+
+	versions, err := s.repository.GetPolicyVersions(ctx, policyID)
+	if err != nil {
+		return nil, fmt.Errorf("getPolicyVersions :: %w", err)
+	}
+	return versions, nil
+}
+
+// DiffPolicyVersions compares the rules of two versions of a policy. Rules
+// are paired by UUID, which survives re-uploads and edits.
+func (s *Service) DiffPolicyVersions(ctx context.Context, policyID uuid.UUID, from, to int) (*PolicyDiff, error) {
+	if err := s.ensureVersionLocked(ctx, policyID); err != nil {
+		return nil, fmt.Errorf("diffPolicyVersions :: %w", err)
+	}
+
+	before, err := s.repository.GetPolicyVersion(ctx, policyID, from)
+	if err != nil {
+		return nil, fmt.Errorf("diffPolicyVersions :: getPolicyVersion %d: %w", from, err)
+	}
+	after, err := s.repository.GetPolicyVersion(ctx, policyID, to)
+	if err != nil {
+		return nil, fmt.Errorf("diffPolicyVersions :: getPolicyVersion %d: %w", to, err)
+	}
+
+	diff := &PolicyDiff{
+		PolicyID: policyID,
+		From:     from,
+		To:       to,
+		Added:    []repository.RuleSnapshot{},
+		Removed:  []repository.RuleSnapshot{},
+		Modified: []RuleDiff{},
+	}
+
+	old := make(map[uuid.UUID]repository.RuleSnapshot, len(before.Rules))
+	for _, rule := range before.Rules {
+		old[rule.RuleUUID] = rule
+	}
+	for _, rule := range after.Rules {
+		prev, ok := old[rule.RuleUUID]
+		if !ok {
+			diff.Added = append(diff.Added, rule)
+			continue
+		}
+		delete(old, rule.RuleUUID)
+		if prev == rule {
+			diff.Unchanged++
+			continue
+		}
+		diff.Modified = append(diff.Modified, RuleDiff{Before: prev, After: rule})
+	}
+	for _, rule := range before.Rules {
+		if _, ok := old[rule.RuleUUID]; ok {
+			diff.Removed = append(diff.Removed, rule)
+		}
+	}
+
+	return diff, nil
+}
+
+func newPolicyVersion(policy *repository.Policy, source string) *repository.PolicyVersion {
+	rules := make([]repository.RuleSnapshot, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		rules[i] = repository.RuleSnapshot{
+			RuleUUID: rule.ID,
+			RuleID:   rule.RuleID,
+			RuleText: rule.RuleText,
+			Severity: rule.Severity,
+			Weight:   rule.Weight,
+
+			Category:      rule.Category,
+			Obligation:    rule.Obligation,
+			ParentRuleID:  rule.ParentRuleID,
+			Applicability: rule.Applicability,
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].RuleID < rules[j].RuleID
+	})
+
+	return &repository.PolicyVersion{
+		BaseModel: repository.BaseModel{
+			ID: uuid.New(),
+		},
+		PolicyID:  policy.ID,
+		Version:   policy.Version,
+		Source:    source,
+		Path:      policy.Path,
+		Extension: policy.Extension,
+		BlobKey:   policy.BlobKey,
+		Size:      policy.Size,
+		Rules:     rules,
+	}
+}
+
+// matchRules pairs re-extracted rules with the current ones. A matched rule
+// keeps its UUID, RuleID, severity, weight and any parent it already has,
+// and takes the new text and section. Identical text is paired first, then
+// the most similar remaining pairs.
+func matchRules(policyID uuid.UUID, current []repository.Rule, extracted []llm.Rule) (repository.RuleChanges, []repository.Rule) {
+	pair := make([]int, len(extracted))
+	used := make([]bool, len(current))
+
+	byText := map[string][]int{}
+	for i, rule := range current {
+		key := strings.Join(ruleWords(rule.RuleText), " ")
+		byText[key] = append(byText[key], i)
+	}
+	for j, rule := range extracted {
+		pair[j] = -1
+		key := strings.Join(ruleWords(rule.RuleText), " ")
+		for _, i := range byText[key] {
+			if !used[i] {
+				used[i] = true
+				pair[j] = i
+				break
+			}
+		}
+	}
+
+	type candidate struct {
+		i, j   int
+		score  float64
+		sameID bool
+	}
+	var candidates []candidate
+	for j, rule := range extracted {
+		if pair[j] >= 0 {
+			continue
+		}
+		for i := range current {
+			if used[i] {
+				continue
+			}
+			score := ruleSimilarity(current[i].RuleText, rule.RuleText)
+			if score >= ruleMatchThreshold {
+				candidates = append(candidates, candidate{i, j, score, current[i].RuleID == rule.RuleID})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].score != candidates[b].score {
+			return candidates[a].score > candidates[b].score
+		}
+		return candidates[a].sameID && !candidates[b].sameID
+	})
+	for _, c := range candidates {
+		if used[c.i] || pair[c.j] >= 0 {
+			continue
+		}
+		used[c.i] = true
+		pair[c.j] = c.i
+	}
+
+	taken := map[string]bool{}
+	for _, rule := range current {
+		taken[rule.RuleID] = true
+	}
+
+	rules := make([]repository.Rule, len(extracted))
+	for j, ex := range extracted {
+		if i := pair[j]; i >= 0 {
+			rules[j] = current[i]
+			continue
+		}
+		severity := ex.Severity
+		if severity == "" {
+			severity = repository.SeverityMajor
+		}
+		rules[j] = repository.Rule{
+			BaseModel: repository.BaseModel{
+				ID: uuid.New(),
+			},
+			PolicyID: policyID,
+			RuleID:   uniqueRuleID(ex.RuleID, taken),
+			Severity: severity,
+			Weight:   1,
+		}
+	}
+
+	// The model names parents by its own rule_id, which a new rule does
+	// not keep when it clashes with an existing one, so parents are mapped
+	// through the IDs the rules were stored under.
+	ids := map[string]map[string]string{}
+	for j, ex := range extracted {
+		crumb := strings.Join(ex.Section, " > ")
+		if ids[ex.RuleID] == nil {
+			ids[ex.RuleID] = map[string]string{}
+		}
+		if _, ok := ids[ex.RuleID][crumb]; !ok {
+			ids[ex.RuleID][crumb] = rules[j].RuleID
+		}
+	}
+
+	var changes repository.RuleChanges
+	for j, ex := range extracted {
+		parent := parentRuleID(ids[ex.ParentRuleID], ex.Section)
+		if parent == rules[j].RuleID {
+			parent = ""
+		}
+		changed := applyExtracted(&rules[j], ex, parent)
+		switch {
+		case pair[j] < 0:
+			changes.Created = append(changes.Created, rules[j])
+		case changed:
+			changes.Updated = append(changes.Updated, rules[j])
+		}
+	}
+	for i, rule := range current {
+		if !used[i] {
+			changes.Deleted = append(changes.Deleted, rule.ID)
+		}
+	}
+
+	return changes, rules
+}
+
+// parentRuleID picks the stored ID of a parent the model named, given the
+// stored IDs of every rule with that name keyed by breadcrumb. A rule under
+// the child's own headings wins, then one under the nearest enclosing
+// heading; elsewhere the name only resolves if it is unambiguous.
+func parentRuleID(candidates map[string]string, section []string) string {
+	for n := len(section); n >= 0; n-- {
+		if id, ok := candidates[strings.Join(section[:n], " > ")]; ok {
+			return id
+		}
+	}
+	if len(candidates) == 1 {
+		for _, id := range candidates {
+			return id
+		}
+	}
+	return ""
+}
+
+// applyExtracted copies the extracted text and structure onto rule and
+// reports whether anything changed. Attributes a reviewer may have edited,
+// the parent among them, are only filled in where the rule has none yet.
+func applyExtracted(rule *repository.Rule, ex llm.Rule, parentRuleID string) bool {
+	changed := rule.RuleText != ex.RuleText ||
+		!slices.Equal(rule.Section, ex.Section)
+	rule.RuleText = ex.RuleText
+	rule.Section = ex.Section
+
+	fill := func(field *string, value string) {
+		if value = strings.TrimSpace(value); *field == "" && value != "" {
+			*field = value
+			changed = true
+		}
+	}
+	fill(&rule.ParentRuleID, parentRuleID)
+	fill(&rule.Category, strings.ToLower(ex.Category))
+	fill(&rule.Obligation, ex.Obligation)
+	fill(&rule.Applicability, ex.Applicability)
+	return changed
+}
+
+// uniqueRuleID keeps id unless an existing rule already uses it.
+func uniqueRuleID(id string, taken map[string]bool) string {
+	if id == "" {
+		id = "R"
+	}
+	candidate := id
+	for n := 2; taken[candidate]; n++ {
+		candidate = id + "-" + strconv.Itoa(n)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// ruleSimilarity is the Jaccard overlap of the two rules' word sets.
+func ruleSimilarity(a, b string) float64 {
+	wordsA, wordsB := ruleWords(a), ruleWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	set := make(map[string]bool, len(wordsA))
+	for _, w := range wordsA {
+		set[w] = true
+	}
+	union := len(set)
+	shared := 0
+	seen := map[string]bool{}
+	for _, w := range wordsB {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if set[w] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	return float64(shared) / float64(union)
+}
+
+func ruleWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"policy-match/internal/client/llm"
+	"policy-match/internal/repository"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func storedRule(ruleID, text string) repository.Rule {
+	return repository.Rule{
+		BaseModel: repository.BaseModel{ID: uuid.New()},
+		RuleID:    ruleID,
+		RuleText:  text,
+		Severity:  repository.SeverityMajor,
+		Weight:    1,
+	}
+}
+
+func TestMatchRules(t *testing.T) {
+	policyID := uuid.New()
+	badges := storedRule("1", "Staff must wear badges at all times.")
+	badges.Severity = repository.SeverityCritical
+	visitors := storedRule("2", "Visitors must sign in at reception.")
+	laptops := storedRule("3", "Laptops must be encrypted.")
+	current := []repository.Rule{badges, visitors, laptops}
+
+	extracted := []llm.Rule{
+		{RuleID: "1", RuleText: "Staff must wear badges at all times."},
+		{RuleID: "2", RuleText: "Visitors must sign in at the reception desk."},
+		{RuleID: "3", RuleText: "Contractors must sign an NDA before starting."},
+	}
+	changes, rules := matchRules(policyID, current, extracted)
+
+	if rules[0].ID != badges.ID || rules[0].Severity != repository.SeverityCritical {
+		t.Errorf("identical rule = %+v, want the stored rule kept", rules[0])
+	}
+	if rules[1].ID != visitors.ID || rules[1].RuleText != extracted[1].RuleText {
+		t.Errorf("revised rule = %+v, want the stored rule with the new text", rules[1])
+	}
+	if rules[2].ID == laptops.ID || rules[2].RuleID != "3-2" || rules[2].PolicyID != policyID {
+		t.Errorf("new rule = %+v, want a new rule with an unused ID", rules[2])
+	}
+
+	if len(changes.Created) != 1 || changes.Created[0].ID != rules[2].ID {
+		t.Errorf("created = %+v", changes.Created)
+	}
+	if len(changes.Updated) != 1 || changes.Updated[0].ID != visitors.ID {
+		t.Errorf("updated = %+v", changes.Updated)
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0] != laptops.ID {
+		t.Errorf("deleted = %v", changes.Deleted)
+	}
+}
+
+func TestMatchRulesParents(t *testing.T) {
+	extracted := []llm.Rule{
+		{RuleID: "1", RuleText: "Staff must wear badges.", Section: []string{"Part I"}},
+		{RuleID: "1", RuleText: "Contractors must sign an NDA.", Section: []string{"Part II"}},
+		{RuleID: "1.1", RuleText: "The NDA must be renewed yearly.", Section: []string{"Part II", "Renewal"}, ParentRuleID: "1"},
+		{RuleID: "2", RuleText: "Visitors must sign in.", Section: []string{"Part II"}, ParentRuleID: "2"},
+		{RuleID: "3", RuleText: "Laptops must be encrypted.", Section: []string{"Part III"}, ParentRuleID: "1"},
+	}
+	_, rules := matchRules(uuid.New(), nil, extracted)
+
+	if rules[1].RuleID != "1-2" {
+		t.Fatalf("second rule 1 = %q, want 1-2", rules[1].RuleID)
+	}
+	if rules[2].ParentRuleID != "1-2" {
+		t.Errorf("parent = %q, want the rule 1 of the enclosing part", rules[2].ParentRuleID)
+	}
+	if rules[3].ParentRuleID != "" {
+		t.Errorf("self parent = %q, want none", rules[3].ParentRuleID)
+	}
+	if rules[4].ParentRuleID != "" {
+		t.Errorf("ambiguous parent = %q, want none", rules[4].ParentRuleID)
+	}
+}
+
+func TestMatchRulesKeepsEditedAttributes(t *testing.T) {
+	parent := storedRule("1", "Staff must wear badges.")
+	child := storedRule("2", "Badges must show a photo.")
+	child.ParentRuleID = "1"
+	child.Category = "physical security"
+
+	extracted := []llm.Rule{
+		{RuleID: "1", RuleText: "Staff must wear badges."},
+		{RuleID: "2", RuleText: "Badges must show a photo.", Category: "Identity", Obligation: repository.ObligationMust},
+	}
+	changes, rules := matchRules(uuid.New(), []repository.Rule{parent, child}, extracted)
+
+	if rules[1].ParentRuleID != "1" || rules[1].Category != "physical security" {
+		t.Errorf("child = %+v, want the edited parent and category kept", rules[1])
+	}
+	if rules[1].Obligation != repository.ObligationMust {
+		t.Errorf("obligation = %q, want it filled in", rules[1].Obligation)
+	}
+	if len(changes.Updated) != 1 || len(changes.Created) != 0 || len(changes.Deleted) != 0 {
+		t.Errorf("changes = %+v, want only the filled-in obligation", changes)
+	}
+}
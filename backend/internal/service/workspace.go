@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"policy-match/internal/apperr"
+	"policy-match/internal/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateWorkspace creates a workspace and its first API key, an admin key
+// that is the only way to call the API inside it.
+func (s *Service) CreateWorkspace(ctx context.Context, name string) (*repository.Workspace, *repository.APIKey, string, error) {
+	workspace := &repository.Workspace{
+		BaseModel: repository.BaseModel{
+			ID: uuid.New(),
+		},
+		Name: name,
+	}
+	if err := s.repository.CreateWorkspace(ctx, workspace); err != nil {
+		return nil, nil, "", fmt.Errorf("createWorkspace :: %w", err)
+	}
+
+	key, plaintext, err := s.CreateAPIKey(repository.WithWorkspace(ctx, workspace.ID), name, repository.RoleAdmin, 0, 0)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("createWorkspace :: %w", err)
+	}
+	return workspace, key, plaintext, nil
+}
+
+func (s *Service) GetWorkspaces(ctx context.Context) ([]repository.Workspace, error) {
+	workspaces, err := s.repository.GetWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getWorkspaces :: %w", err)
+	}
+	return workspaces, nil
+}
+
+func (s *Service) GetWorkspace(ctx context.Context, id uuid.UUID) (*repository.Workspace, error) {
+	if err := canAccessWorkspace(ctx, id); err != nil {
+		return nil, fmt.Errorf("getWorkspace :: %w", err)
+	}
+	workspace, err := s.repository.GetWorkspaceByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getWorkspace :: %w", err)
+	}
+	return workspace, nil
+}
+
+func (s *Service) RenameWorkspace(ctx context.Context, id uuid.UUID, name string) (*repository.Workspace, error) {
+	if err := canAccessWorkspace(ctx, id); err != nil {
+		return nil, fmt.Errorf("renameWorkspace :: %w", err)
+	}
+	if err := s.repository.UpdateWorkspace(ctx, id, map[string]any{"name": name}); err != nil {
+		return nil, fmt.Errorf("renameWorkspace :: %w", err)
+	}
+	return s.GetWorkspace(ctx, id)
+}
+
+func (s *Service) GetWorkspaceMembers(ctx context.Context, workspaceID uuid.UUID) ([]repository.WorkspaceMember, error) {
+	if _, err := s.GetWorkspace(ctx, workspaceID); err != nil {
+		return nil, fmt.Errorf("getWorkspaceMembers :: %w", err)
+	}
+	members, err := s.repository.GetWorkspaceMembers(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("getWorkspaceMembers :: %w", err)
+	}
+	return members, nil
+}
+
+// AddWorkspaceMember adds the user with email to the workspace with role,
+// creating the user if needed. An existing member gets the new role.
+func (s *Service) AddWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, email string, name string, role string) (*repository.WorkspaceMember, error) {
+	if _, err := s.GetWorkspace(ctx, workspaceID); err != nil {
+		return nil, fmt.Errorf("addWorkspaceMember :: %w", err)
+	}
+
+	user, err := s.repository.GetOrCreateUser(ctx, email, name)
+	if err != nil {
+		return nil, fmt.Errorf("addWorkspaceMember :: getOrCreateUser: %w", err)
+	}
+
+	member := &repository.WorkspaceMember{
+		BaseModel: repository.BaseModel{
+			ID: uuid.New(),
+		},
+		WorkspaceID: workspaceID,
+		UserID:      user.ID,
+		Role:        role,
+		User:        *user,
+	}
+	if err := s.repository.AddWorkspaceMember(ctx, member); err != nil {
+		return nil, fmt.Errorf("addWorkspaceMember :: %w", err)
+	}
+	return member, nil
+}
+
+func (s *Service) RemoveWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) error {
+	if err := canAccessWorkspace(ctx, workspaceID); err != nil {
+		return fmt.Errorf("removeWorkspaceMember :: %w", err)
+	}
+	if err := s.repository.RemoveWorkspaceMember(ctx, workspaceID, userID); err != nil {
+		return fmt.Errorf("removeWorkspaceMember :: %w", err)
+	}
+	return nil
+}
+
+// canAccessWorkspace reports other workspaces as not found, so callers
+// cannot probe which IDs exist.
+func canAccessWorkspace(ctx context.Context, id uuid.UUID) error {
+	if current, ok := repository.WorkspaceFromContext(ctx); ok && current != id {
+		return apperr.NotFound("workspace_not_found", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
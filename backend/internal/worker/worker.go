@@ -0,0 +1,208 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"policy-match/internal/apperr"
+	"policy-match/internal/config"
+	"policy-match/internal/repository"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Processor runs a single claimed job. Returning an error marks the job as
+// failed, unless it is an upstream failure and the job has attempts left,
+// in which case it is queued again. ReleaseJob is called once the job is
+// done or has failed for good.
+type Processor interface {
+	ProcessJob(ctx context.Context, job *repository.Job) error
+	ReleaseJob(id uuid.UUID)
+}
+
+// Pool runs queued jobs with bounded concurrency. Jobs live in the database
+// and are leased to a worker while running, so a job whose worker died is
+// picked up again once its lease expires, on this replica or another.
+type Pool struct {
+	cfg  *config.Config
+	repo repository.Repository
+	wake chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewPool(cfg *config.Config, repo repository.Repository) *Pool {
+	return &Pool{
+		cfg:  cfg,
+		repo: repo,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes an idle worker so new jobs start without waiting for the next
+// poll.
+func (p *Pool) Notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the workers until ctx is cancelled. Jobs still running then
+// stay leased and are picked up again once their lease expires.
+func (p *Pool) Start(ctx context.Context, processor Processor) {
+	for range p.cfg.JobWorkers {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.run(ctx, processor)
+		}()
+	}
+	log.Info().Msgf("worker :: started %d workers", p.cfg.JobWorkers)
+}
+
+// Wait blocks until every worker has returned after its context was
+// cancelled.
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Pool) run(ctx context.Context, processor Processor) {
+	ticker := time.NewTicker(p.cfg.JobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := p.repo.ClaimNextJob(ctx, p.cfg.JobLease)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Msg("worker :: claimNextJob: " + err.Error())
+		}
+		if job != nil {
+			p.process(ctx, processor, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) process(ctx context.Context, processor Processor, job *repository.Job) {
+	if job.Attempts > p.cfg.JobMaxAttempts {
+		p.finish(ctx, job, fmt.Errorf("job abandoned after %d attempts", job.Attempts-1))
+		processor.ReleaseJob(job.ID)
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, p.cfg.JobTimeout)
+	defer cancel()
+
+	stop := p.heartbeat(jobCtx, job.ID)
+	err := safeProcess(jobCtx, processor, job)
+	stop()
+
+	// On shutdown leave the job leased; it is retried once the lease
+	// expires instead of being reported as failed.
+	if ctx.Err() != nil {
+		return
+	}
+	if delay, ok := p.retryDelay(job, err); ok {
+		p.requeue(ctx, job, err, delay)
+		return
+	}
+	p.finish(ctx, job, err)
+	processor.ReleaseJob(job.ID)
+}
+
+// retryDelay reports whether a failed job should run again and after how
+// long. Only failures of the LLM provider or Tika are retried, since the
+// same input will fail the same way otherwise.
+func (p *Pool) retryDelay(job *repository.Job, err error) (time.Duration, bool) {
+	appErr, ok := apperr.As(err)
+	if !ok || job.Attempts >= p.cfg.JobMaxAttempts {
+		return 0, false
+	}
+	if appErr.Kind != apperr.KindUpstreamFailure && appErr.Kind != apperr.KindRateLimited {
+		return 0, false
+	}
+	delay := p.cfg.JobRetryDelay << (job.Attempts - 1)
+	return max(delay, appErr.RetryAfter), true
+}
+
+// requeue puts the job back in the queue. It is not claimed again before
+// its lease, reused as the retry time, expires.
+func (p *Pool) requeue(ctx context.Context, job *repository.Job, err error, delay time.Duration) {
+	appErr, _ := apperr.As(err)
+	log.Warn().Str("job_id", job.ID.String()).Msgf("worker :: retrying in %s after attempt %d: %s", delay, job.Attempts, err)
+
+	updates := map[string]any{
+		"status":           repository.JobStatusQueued,
+		"error":            appErr.Code,
+		"lease_expires_at": time.Now().Add(delay),
+	}
+	if err := p.repo.UpdateJob(ctx, job.ID, updates); err != nil {
+		log.Error().Str("job_id", job.ID.String()).Msg("worker :: updateJob: " + err.Error())
+	}
+}
+
+func (p *Pool) finish(ctx context.Context, job *repository.Job, err error) {
+	now := time.Now()
+	updates := map[string]any{
+		"status":           repository.JobStatusDone,
+		"finished_at":      now,
+		"lease_expires_at": nil,
+		"error":            "",
+	}
+	if err != nil {
+		log.Error().Str("job_id", job.ID.String()).Msg("worker :: job failed: " + err.Error())
+		updates["status"] = repository.JobStatusFailed
+		// Only the error code is stored; the job is visible to clients and
+		// the cause may include provider or database details.
+		updates["error"] = apperr.CodeInternal
+		if appErr, ok := apperr.As(err); ok {
+			updates["error"] = appErr.Code
+		}
+	}
+
+	if err := p.repo.UpdateJob(ctx, job.ID, updates); err != nil {
+		log.Error().Str("job_id", job.ID.String()).Msg("worker :: updateJob: " + err.Error())
+	}
+}
+
+// heartbeat keeps extending the job lease while it is being processed.
+func (p *Pool) heartbeat(ctx context.Context, id uuid.UUID) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(max(p.cfg.JobLease/3, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := p.repo.UpdateJob(ctx, id, map[string]any{
+					"lease_expires_at": time.Now().Add(p.cfg.JobLease),
+				})
+				if err != nil && ctx.Err() == nil {
+					log.Error().Str("job_id", id.String()).Msg("worker :: heartbeat: " + err.Error())
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func safeProcess(ctx context.Context, processor Processor, job *repository.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return processor.ProcessJob(ctx, job)
+}
@@ -0,0 +1,298 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"policy-match/internal/apperr"
+	"policy-match/internal/client/llm"
+	"policy-match/internal/config"
+	"policy-match/internal/repository"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// processorFunc runs fn for every job and records released jobs.
+type processorFunc struct {
+	fn func(ctx context.Context, job *repository.Job) error
+
+	mu       sync.Mutex
+	released []uuid.UUID
+}
+
+func (p *processorFunc) ProcessJob(ctx context.Context, job *repository.Job) error {
+	return p.fn(ctx, job)
+}
+
+func (p *processorFunc) ReleaseJob(id uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.released = append(p.released, id)
+}
+
+func (p *processorFunc) releasedJobs() []uuid.UUID {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]uuid.UUID(nil), p.released...)
+}
+
+func newTestPool(t *testing.T) (*Pool, repository.Repository) {
+	t.Helper()
+	repo, err := repository.NewRepository(&config.Config{
+		DBDriver:      repository.DriverSQLite,
+		DBURL:         ":memory:",
+		DBAutoMigrate: true,
+	})
+	if err != nil {
+		t.Fatalf("newRepository: %v", err)
+	}
+	return NewPool(&config.Config{
+		JobWorkers:      1,
+		JobPollInterval: 10 * time.Millisecond,
+		JobLease:        time.Minute,
+		JobTimeout:      time.Minute,
+		JobMaxAttempts:  3,
+		JobRetryDelay:   time.Minute,
+	}, repo), repo
+}
+
+func createJob(t *testing.T, repo repository.Repository) *repository.Job {
+	t.Helper()
+	job := &repository.Job{
+		BaseModel: repository.BaseModel{ID: uuid.New()},
+		Kind:      repository.JobKindCheck,
+		Status:    repository.JobStatusQueued,
+		FileName:  "a.pdf",
+		BlobKey:   "key",
+	}
+	if err := repo.CreateJob(context.Background(), job); err != nil {
+		t.Fatalf("createJob: %v", err)
+	}
+	return job
+}
+
+// runOnce claims the next job and processes it as a worker would.
+func runOnce(t *testing.T, p *Pool, processor Processor) *repository.Job {
+	t.Helper()
+	ctx := context.Background()
+	job, err := p.repo.ClaimNextJob(ctx, p.cfg.JobLease)
+	if err != nil || job == nil {
+		t.Fatalf("claimNextJob = %+v, %v, want a job", job, err)
+	}
+	p.process(ctx, processor, job)
+	stored, err := p.repo.GetJobByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("getJobByID: %v", err)
+	}
+	return stored
+}
+
+func TestPoolRunsJobs(t *testing.T) {
+	pool, repo := newTestPool(t)
+	job := createJob(t, repo)
+
+	var leased *repository.Job
+	processor := &processorFunc{fn: func(ctx context.Context, j *repository.Job) error {
+		leased, _ = repo.GetJobByID(ctx, j.ID)
+		return nil
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx, processor)
+	defer func() {
+		cancel()
+		pool.Wait()
+	}()
+	pool.Notify()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(processor.releasedJobs()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("job was not processed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if leased.Status != repository.JobStatusExtracting || leased.Attempts != 1 || leased.LeaseExpiresAt == nil {
+		t.Errorf("while running, job = %+v, want it leased on attempt 1", leased)
+	}
+	if until := time.Until(*leased.LeaseExpiresAt); until <= 0 || until > time.Minute {
+		t.Errorf("lease expires in %s, want within JobLease", until)
+	}
+
+	stored, _ := repo.GetJobByID(context.Background(), job.ID)
+	if stored.Status != repository.JobStatusDone || stored.LeaseExpiresAt != nil || stored.FinishedAt == nil || stored.Error != "" {
+		t.Errorf("finished job = %+v, want it done without a lease", stored)
+	}
+	if released := processor.releasedJobs(); len(released) != 1 || released[0] != job.ID {
+		t.Errorf("released = %v, want the job", released)
+	}
+}
+
+func TestHeartbeatExtendsLease(t *testing.T) {
+	pool, repo := newTestPool(t)
+	pool.cfg.JobLease = 1500 * time.Millisecond
+	createJob(t, repo)
+
+	var initial, extended time.Time
+	processor := &processorFunc{fn: func(ctx context.Context, j *repository.Job) error {
+		initial = *j.LeaseExpiresAt
+		// The heartbeat ticks every second at the least.
+		time.Sleep(1200 * time.Millisecond)
+		stored, err := repo.GetJobByID(ctx, j.ID)
+		if err != nil {
+			return err
+		}
+		extended = *stored.LeaseExpiresAt
+		return nil
+	}}
+	runOnce(t, pool, processor)
+
+	if !extended.After(initial) {
+		t.Errorf("lease %s was not extended past %s", extended, initial)
+	}
+}
+
+func TestExpiredLeaseIsReleased(t *testing.T) {
+	pool, repo := newTestPool(t)
+	job := createJob(t, repo)
+	ctx := context.Background()
+
+	// A worker claims the job and dies without heartbeats.
+	if _, err := repo.ClaimNextJob(ctx, time.Millisecond); err != nil {
+		t.Fatalf("claimNextJob: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	stored := runOnce(t, pool, &processorFunc{fn: func(ctx context.Context, j *repository.Job) error {
+		return nil
+	}})
+	if stored.ID != job.ID || stored.Status != repository.JobStatusDone || stored.Attempts != 2 {
+		t.Errorf("job = %+v, want it done on attempt 2", stored)
+	}
+}
+
+func TestAbandonedJob(t *testing.T) {
+	pool, repo := newTestPool(t)
+	job := createJob(t, repo)
+	if err := repo.UpdateJob(context.Background(), job.ID, map[string]any{"attempts": pool.cfg.JobMaxAttempts}); err != nil {
+		t.Fatalf("updateJob: %v", err)
+	}
+
+	processor := &processorFunc{fn: func(ctx context.Context, j *repository.Job) error {
+		t.Error("an abandoned job was processed")
+		return nil
+	}}
+	stored := runOnce(t, pool, processor)
+	if stored.Status != repository.JobStatusFailed || stored.Error != apperr.CodeInternal {
+		t.Errorf("job = %+v, want it failed", stored)
+	}
+	if len(processor.releasedJobs()) != 1 {
+		t.Error("abandoned job was not released")
+	}
+}
+
+func TestRetryOnProviderUnavailable(t *testing.T) {
+	pool, repo := newTestPool(t)
+	job := createJob(t, repo)
+	unavailable := apperr.UpstreamFailure("llm_provider_unavailable", fmt.Errorf("groq :: 503 from 10.0.0.7: %w", llm.ErrProviderUnavailable))
+	processor := &processorFunc{fn: func(ctx context.Context, j *repository.Job) error {
+		return unavailable
+	}}
+
+	for attempt := 1; attempt < pool.cfg.JobMaxAttempts; attempt++ {
+		before := time.Now()
+		stored := runOnce(t, pool, processor)
+		if stored.Status != repository.JobStatusQueued || stored.Attempts != attempt || stored.Error != "llm_provider_unavailable" {
+			t.Fatalf("attempt %d: job = %+v, want it queued again", attempt, stored)
+		}
+		// The delay doubles with each attempt.
+		want := pool.cfg.JobRetryDelay << (attempt - 1)
+		if wait := stored.LeaseExpiresAt.Sub(before); wait < want || wait > want+time.Second {
+			t.Errorf("attempt %d: retried after %s, want %s", attempt, wait, want)
+		}
+		if len(processor.releasedJobs()) != 0 {
+			t.Fatalf("attempt %d: a job to retry was released", attempt)
+		}
+		if err := repo.UpdateJob(context.Background(), job.ID, map[string]any{"lease_expires_at": time.Now().Add(-time.Second)}); err != nil {
+			t.Fatalf("updateJob: %v", err)
+		}
+	}
+
+	stored := runOnce(t, pool, processor)
+	if stored.Status != repository.JobStatusFailed || stored.Attempts != pool.cfg.JobMaxAttempts {
+		t.Errorf("last attempt: job = %+v, want it failed", stored)
+	}
+	if len(processor.releasedJobs()) != 1 {
+		t.Error("failed job was not released")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	pool, _ := newTestPool(t)
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		want     time.Duration
+		ok       bool
+	}{
+		{"first attempt", 1, apperr.UpstreamFailure("text_extraction_unavailable", nil), time.Minute, true},
+		{"backs off", 2, apperr.UpstreamFailure("text_extraction_unavailable", nil), 2 * time.Minute, true},
+		{"honors retry after", 1, apperr.RateLimited("llm_rate_limited", 5*time.Minute, nil), 5 * time.Minute, true},
+		{"short retry after", 2, apperr.RateLimited("llm_rate_limited", time.Second, nil), 2 * time.Minute, true},
+		{"no attempts left", 3, apperr.UpstreamFailure("text_extraction_unavailable", nil), 0, false},
+		{"client error", 1, apperr.InvalidInput("file_could_not_be_read", nil), 0, false},
+		{"plain error", 1, errors.New("boom"), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := pool.retryDelay(&repository.Job{Attempts: tt.attempts}, tt.err)
+			if delay != tt.want || ok != tt.ok {
+				t.Errorf("retryDelay = %s, %v, want %s, %v", delay, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFailedJobStoresOnlyTheCode(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(ctx context.Context, job *repository.Job) error
+		want string
+	}{
+		{
+			name: "app error",
+			fn: func(ctx context.Context, job *repository.Job) error {
+				return fmt.Errorf("checkDocumentCompliance :: %w", apperr.InvalidInput("file_could_not_be_read", errors.New("tika: /tmp/upload-81 is encrypted")))
+			},
+			want: "file_could_not_be_read",
+		},
+		{
+			name: "plain error",
+			fn: func(ctx context.Context, job *repository.Job) error {
+				return errors.New("pq: password authentication failed for user admin")
+			},
+			want: apperr.CodeInternal,
+		},
+		{
+			name: "panic",
+			fn: func(ctx context.Context, job *repository.Job) error {
+				panic("index out of range")
+			},
+			want: apperr.CodeInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, repo := newTestPool(t)
+			createJob(t, repo)
+			stored := runOnce(t, pool, &processorFunc{fn: tt.fn})
+			if stored.Status != repository.JobStatusFailed || stored.Error != tt.want {
+				t.Errorf("job = %+v, want it failed with %q", stored, tt.want)
+			}
+		})
+	}
+}